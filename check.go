@@ -0,0 +1,34 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/components/entity/verify"
+	"github.com/go-bread/models"
+)
+
+// checkEntities verifies the entity groups against the database, in strict mode any error stops the server
+func checkEntities(strict bool) {
+	tables, err := schema.Inspect(models.GetDb())
+	if err != nil {
+		if strict {
+			log.Fatalf("entity check: %v", err)
+		}
+		log.Printf("[warning] entity check: %v", err)
+		return
+	}
+
+	report := verify.Verify(entity.FieldsMap, tables)
+	if !report.HasErrors() {
+		log.Printf("[info] %s", report)
+		return
+	}
+
+	log.Printf("[error] %s", report)
+	if strict {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,290 @@
+package codegen
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/go-bread/components/database/condition"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+	"github.com/go-bread/consts"
+)
+
+type groupModel struct {
+	Name   string
+	Type   string
+	Fields []fieldModel
+	// Rows[0] 为行类型, 其余为命名空间对应的嵌套类型
+	Rows []rowModel
+	// 支持 _search 全文搜索
+	Searchable   bool
+	SearchFields []string
+}
+
+type rowModel struct {
+	Type   string
+	Fields []rowFieldModel
+}
+
+// rowFieldModel is a field or a nested namespace of a row type
+type rowFieldModel struct {
+	Name   string
+	Ident  string
+	GoType string
+	TSType string
+}
+
+type fieldModel struct {
+	Name      string
+	Ident     string
+	GoType    string
+	TSType    string
+	GoInput   string
+	TSInput   string
+	Orderable bool
+	Filters   []filterModel
+}
+
+// filterModel is one builder method of a queryable field
+type filterModel struct {
+	Method string
+	// Key is empty for plain values, otherwise the key of the object form
+	Key   string
+	Slice bool
+}
+
+var opSuffix = map[string]string{
+	condition.OpEqual: "Eq",
+	condition.OpIn:    "In",
+	">":               "Gt",
+	">=":              "Gte",
+	"<":               "Lt",
+	"<=":              "Lte",
+	"!=":              "Ne",
+	"like":            "Like",
+}
+
+var funcs = template.FuncMap{
+	"join":      strings.Join,
+	"relevance": func() string { return group.RelevanceField },
+	"lowerFirst": func(s string) string {
+		if s == "" {
+			return s
+		}
+		r := []rune(s)
+		r[0] = unicode.ToLower(r[0])
+		return string(r)
+	},
+}
+
+func buildModels(fieldsMap group.FieldsMap) []groupModel {
+	names := make([]string, 0, len(fieldsMap))
+	for gn := range fieldsMap {
+		names = append(names, string(gn))
+	}
+	sort.Strings(names)
+
+	var gms []groupModel
+	for _, gn := range names {
+		g := fieldsMap[consts.EntityGroupName(gn)]
+		g = g.Default() // 客户端只包含默认版本的字段
+		gm := groupModel{Name: gn, Type: ident(gn)}
+		rows := newRowBuilder(gm.Type + "Row")
+		var fieldNames []string
+		g.Walk(func(name string, f field.Field) {
+			fieldNames = append(fieldNames, name)
+		})
+		idents := fieldIdents(fieldNames)
+		g.Walk(func(name string, f field.Field) {
+			rows.add(name, goType(f), tsType(f))
+			fm := fieldModel{
+				Name:      name,
+				Ident:     idents[name],
+				GoType:    goType(f),
+				TSType:    tsType(f),
+				GoInput:   goKind(inputKind(f.TableField)),
+				TSInput:   tsKind(inputKind(f.TableField)),
+				Orderable: f.CanOrder,
+			}
+			for _, op := range f.Operators() {
+				suffix, ok := opSuffix[op]
+				if !ok {
+					continue
+				}
+				fl := filterModel{Method: fm.Ident + suffix}
+				switch op {
+				case condition.OpEqual:
+				case condition.OpIn:
+					fl.Slice = true
+				default:
+					fl.Key = condition.OperatorKeys[op]
+				}
+				fm.Filters = append(fm.Filters, fl)
+			}
+			gm.Fields = append(gm.Fields, fm)
+		})
+		gm.Rows = rows.rows
+		if _, ok := g.SearchFields(); ok {
+			gm.Searchable, gm.SearchFields = true, g.Search.Fields
+		}
+		gms = append(gms, gm)
+	}
+
+	return gms
+}
+
+// rowBuilder builds one row type per namespace, class.name -> Row.Class.Name
+type rowBuilder struct {
+	rows  []rowModel
+	index map[string]int
+}
+
+func newRowBuilder(typ string) *rowBuilder {
+	return &rowBuilder{rows: []rowModel{{Type: typ}}, index: map[string]int{"": 0}}
+}
+
+func (b *rowBuilder) add(name, goType, tsType string) {
+	parts := strings.Split(name, group.Separator)
+	parent := b.row("", parts[:len(parts)-1])
+	key := parts[len(parts)-1]
+	b.rows[parent].Fields = append(b.rows[parent].Fields, rowFieldModel{
+		Name: key, Ident: ident(key), GoType: goType, TSType: tsType,
+	})
+}
+
+// row returns the index of the row type of the namespace path, creating it in its parent when missing
+func (b *rowBuilder) row(prefix string, path []string) int {
+	i := b.index[prefix]
+	for _, ns := range path {
+		if prefix == "" {
+			prefix = ns
+		} else {
+			prefix += group.Separator + ns
+		}
+		j, ok := b.index[prefix]
+		if !ok {
+			j = len(b.rows)
+			typ := b.rows[0].Type + ident(prefix)
+			b.rows = append(b.rows, rowModel{Type: typ})
+			b.index[prefix] = j
+			b.rows[i].Fields = append(b.rows[i].Fields, rowFieldModel{
+				Name: ns, Ident: ident(ns), GoType: "*" + typ, TSType: typ,
+			})
+		}
+		i = j
+	}
+	return i
+}
+
+func render(t *template.Template, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 回调函数可以改变输出值的类型, 输出值可能为空
+func goType(f field.Field) string {
+	if f.Callback != nil {
+		return "interface{}"
+	}
+	if k := goKind(f.OutputKind()); k != "interface{}" {
+		return "*" + k
+	}
+	return "interface{}"
+}
+
+// 时间条件的值为字符串
+func inputKind(tf models.TableField) reflect.Kind {
+	if tf.IsTime {
+		return reflect.String
+	}
+	return tf.Type
+}
+
+func goKind(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int64"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint64"
+	case reflect.Float32, reflect.Float64:
+		return "float64"
+	case reflect.String:
+		return "string"
+	default:
+		return "interface{}"
+	}
+}
+
+func tsType(f field.Field) string {
+	if f.Callback != nil {
+		return "unknown"
+	}
+	return tsKind(f.OutputKind())
+}
+
+func tsKind(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// class_name -> ClassName
+func ident(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		r := []rune(part)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+	return b.String()
+}
+
+// class.id 与 class_id 都对应 ClassId, 冲突时嵌套字段的各段以Dot连接: ClassDotId,
+// 方法名如 ClassDotIdEq (TypeScript中为 classDotIdEq)
+func fieldIdents(names []string) map[string]string {
+	idents := make(map[string]string, len(names))
+	used := map[string]bool{}
+	var nested []string
+	for _, name := range names {
+		if strings.Contains(name, group.Separator) {
+			nested = append(nested, name)
+			continue
+		}
+		idents[name] = ident(name)
+		used[idents[name]] = true
+	}
+	for _, name := range nested {
+		id := ident(name)
+		if used[id] {
+			parts := strings.Split(name, group.Separator)
+			for i, p := range parts {
+				parts[i] = ident(p)
+			}
+			id = strings.Join(parts, "Dot")
+		}
+		idents[name] = id
+		used[id] = true
+	}
+	return idents
+}
@@ -0,0 +1,74 @@
+package codegen
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata")
+
+// testFields has a nested class.id beside class_id, both would be ClassId
+func testFields() group.FieldsMap {
+	g := group.EntityGroup{
+		JoinDriveTable: models.Student,
+		Entities: map[string]interface{}{
+			"id":          field.Field{Table: models.Student, TableField: models.Student.ID, CanQuery: true, CanOrder: true},
+			"class_id":    field.Field{Table: models.Student, TableField: models.Student.ClassId, CanQuery: true},
+			"create_time": field.Field{Table: models.Student, TableField: models.Student.CreateTime, CanQuery: true, CanOrder: true},
+			"class": map[string]interface{}{
+				"id":   field.Field{Table: models.Class, TableField: models.Class.Id, CanQuery: true},
+				"name": field.Field{Table: models.Class, TableField: models.Class.ClassName, CanQuery: true, Rule: "string,max=20"},
+			},
+		},
+	}
+	g.Init()
+	return group.FieldsMap{"student": g}
+}
+
+func TestGolden(t *testing.T) {
+	cases := []struct {
+		file   string
+		render func() ([]byte, error)
+	}{
+		{"client.go.golden", func() ([]byte, error) { return Go(testFields(), "client") }},
+		{"client.ts.golden", func() ([]byte, error) { return TypeScript(testFields()) }},
+	}
+	for _, c := range cases {
+		got, err := c.render()
+		if err != nil {
+			t.Errorf("%s: %v", c.file, err)
+			continue
+		}
+		path := filepath.Join("testdata", c.file)
+		if *update {
+			if err := ioutil.WriteFile(path, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is outdated, run go test -update", c.file)
+		}
+	}
+}
+
+func TestFieldIdents(t *testing.T) {
+	idents := fieldIdents([]string{"class_id", "class.id", "class.name", "teacher.id"})
+	want := map[string]string{"class_id": "ClassId", "class.id": "ClassDotId", "class.name": "ClassName", "teacher.id": "TeacherId"}
+	for name, id := range want {
+		if idents[name] != id {
+			t.Errorf("%s: %s, want %s", name, idents[name], id)
+		}
+	}
+}
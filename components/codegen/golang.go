@@ -0,0 +1,251 @@
+package codegen
+
+import (
+	"go/format"
+	"text/template"
+
+	"github.com/go-bread/components/entity/group"
+)
+
+// Go renders a client package with one query builder and row type per entity group
+func Go(fieldsMap group.FieldsMap, pkg string) ([]byte, error) {
+	src, err := render(goTemplate, struct {
+		Package string
+		Groups  []groupModel
+	}{pkg, buildModels(fieldsMap)})
+	if err != nil {
+		return nil, err
+	}
+
+	return format.Source(src)
+}
+
+var goTemplate = template.Must(template.New("go").Funcs(funcs).Parse(`// Code generated by bread gen client. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Direction string
+
+const (
+	Asc            Direction = "asc"
+	Desc           Direction = "desc"
+	AscNullsFirst  Direction = "asc nulls first"
+	AscNullsLast   Direction = "asc nulls last"
+	DescNullsFirst Direction = "desc nulls first"
+	DescNullsLast  Direction = "desc nulls last"
+)
+
+type PageInfo struct {
+	Page       uint32 ` + "`json:\"page\"`" + `
+	PageSize   uint32 ` + "`json:\"page_size\"`" + `
+	TotalCount uint32 ` + "`json:\"total_number\"`" + `
+}
+
+// Error is returned when the server answers with a non 2xx status
+type Error struct {
+	Status  int
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+	Field   string ` + "`json:\"field\"`" + `
+	Body    string ` + "`json:\"-\"`" + `
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("bread: status %d: %s", e.Status, e.Body)
+	}
+	return fmt.Sprintf("bread: status %d: code %d: %s", e.Status, e.Code, e.Message)
+}
+
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Header     http.Header
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     make(http.Header),
+	}
+}
+
+// SetToken authenticates the requests with the access token of /auth/login
+func (c *Client) SetToken(token string) {
+	c.Header.Set("Authorization", "Bearer "+token)
+}
+
+// SetAPIKey authenticates the requests with an API key of /auth/keys, e.g. for internal jobs
+func (c *Client) SetAPIKey(key string) {
+	c.Header.Set("X-Api-Key", key)
+}
+
+func (c *Client) list(ctx context.Context, form string, q *query, out interface{}) error {
+	doc, err := json.Marshal(q.document())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+"/list/"+form+"?query="+url.QueryEscape(string(doc)), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := &Error{Status: resp.StatusCode, Body: string(body)}
+		_ = json.Unmarshal(body, e)
+		return e
+	}
+
+	return json.Unmarshal(body, out)
+}
+
+type query struct {
+	conditions map[string]interface{}
+	fields     []string
+	orders     [][2]string
+	page       uint32
+	pageSize   uint32
+}
+
+func newQuery() query {
+	return query{conditions: make(map[string]interface{})}
+}
+
+func (q *query) set(name string, v interface{}) {
+	q.conditions[name] = v
+}
+
+func (q *query) setOperator(name, key string, v interface{}) {
+	m, ok := q.conditions[name].(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+		q.conditions[name] = m
+	}
+	m[key] = v
+}
+
+func (q *query) document() map[string]interface{} {
+	doc := make(map[string]interface{}, len(q.conditions)+4)
+	for k, v := range q.conditions {
+		doc[k] = v
+	}
+	doc["fields"] = q.fields
+	if q.page != 0 {
+		doc["_page"] = q.page
+		doc["_page_size"] = q.pageSize
+	}
+	if len(q.orders) > 0 {
+		doc["_order_by"] = q.orders
+	}
+	return doc
+}
+{{range $g := .Groups}}
+{{- range $i, $r := $g.Rows}}
+{{if $i}}// {{$r.Type}} is a nested namespace of {{$g.Type}}Row{{else}}// {{$r.Type}} is one row of the {{$g.Name}} entity group{{end}}
+type {{$r.Type}} struct {
+{{- range $r.Fields}}
+	{{.Ident}} {{.GoType}} ` + "`json:\"{{.Name}},omitempty\"`" + `
+{{- end}}
+}
+{{end}}
+type {{$g.Type}}List struct {
+	List     []{{$g.Type}}Row ` + "`json:\"list\"`" + `
+	PageInfo PageInfo ` + "`json:\"page_info\"`" + `
+}
+
+type {{$g.Type}}Field string
+
+const (
+{{- range $g.Fields}}
+	{{$g.Type}}Field{{.Ident}} {{$g.Type}}Field = "{{.Name}}"
+{{- end}}
+)
+
+type {{$g.Type}}OrderField string
+{{- $orderable := $g.Searchable}}{{range $g.Fields}}{{if .Orderable}}{{$orderable = true}}{{end}}{{end}}
+{{- if $orderable}}
+
+const (
+{{- range $g.Fields}}{{if .Orderable}}
+	{{$g.Type}}Order{{.Ident}} {{$g.Type}}OrderField = "{{.Name}}"
+{{- end}}{{end}}
+{{- if $g.Searchable}}
+	// {{$g.Type}}OrderRelevance orders the results of Search by relevance
+	{{$g.Type}}OrderRelevance {{$g.Type}}OrderField = "{{relevance}}"
+{{- end}}
+)
+{{- end}}
+
+type {{$g.Type}}Query struct {
+	query
+}
+
+func New{{$g.Type}}Query(fields ...{{$g.Type}}Field) *{{$g.Type}}Query {
+	q := &{{$g.Type}}Query{newQuery()}
+	return q.Fields(fields...)
+}
+
+func (q *{{$g.Type}}Query) Fields(fields ...{{$g.Type}}Field) *{{$g.Type}}Query {
+	for _, f := range fields {
+		q.fields = append(q.fields, string(f))
+	}
+	return q
+}
+
+func (q *{{$g.Type}}Query) Page(page, pageSize uint32) *{{$g.Type}}Query {
+	q.page, q.pageSize = page, pageSize
+	return q
+}
+
+func (q *{{$g.Type}}Query) OrderBy(f {{$g.Type}}OrderField, d Direction) *{{$g.Type}}Query {
+	q.orders = append(q.orders, [2]string{string(f), string(d)})
+	return q
+}
+{{- if $g.Searchable}}
+
+// Search matches text against {{join $g.SearchFields ", "}}
+func (q *{{$g.Type}}Query) Search(text string) *{{$g.Type}}Query {
+	q.set("_search", text)
+	return q
+}
+{{- end}}
+{{range $f := $g.Fields}}{{range $f.Filters}}
+func (q *{{$g.Type}}Query) {{.Method}}(v {{if .Slice}}...{{end}}{{$f.GoInput}}) *{{$g.Type}}Query {
+	{{if .Key}}q.setOperator("{{$f.Name}}", "{{.Key}}", v){{else}}q.set("{{$f.Name}}", v){{end}}
+	return q
+}
+{{end}}{{end}}
+func (c *Client) List{{$g.Type}}(ctx context.Context, q *{{$g.Type}}Query) (*{{$g.Type}}List, error) {
+	var out {{$g.Type}}List
+	if err := c.list(ctx, "{{$g.Name}}", &q.query, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+{{end}}`))
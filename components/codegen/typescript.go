@@ -0,0 +1,150 @@
+package codegen
+
+import (
+	"text/template"
+
+	"github.com/go-bread/components/entity/group"
+)
+
+// TypeScript renders a client module with one query builder and row type per entity group
+func TypeScript(fieldsMap group.FieldsMap) ([]byte, error) {
+	return render(tsTemplate, buildModels(fieldsMap))
+}
+
+var tsTemplate = template.Must(template.New("ts").Funcs(funcs).Parse(`// Code generated by bread gen client. DO NOT EDIT.
+
+export type Direction = 'asc' | 'desc' | 'asc nulls first' | 'asc nulls last' | 'desc nulls first' | 'desc nulls last';
+
+export interface PageInfo {
+  page: number;
+  page_size: number;
+  total_number: number;
+}
+
+export interface ListResult<Row> {
+  list: Row[];
+  page_info: PageInfo;
+}
+
+export class BreadError extends Error {
+  code?: number;
+  field?: string;
+
+  constructor(public status: number, public body: string) {
+    super(` + "`bread: status ${status}: ${body}`" + `);
+    try {
+      const e = JSON.parse(body) as { code?: number; message?: string; field?: string };
+      this.code = e.code;
+      this.field = e.field;
+      if (e.message) {
+        this.message = ` + "`bread: status ${status}: code ${e.code}: ${e.message}`" + `;
+      }
+    } catch (_) {
+      // 非json响应保留原始内容
+    }
+  }
+}
+
+abstract class Query<Field extends string, OrderField extends string> {
+  protected conditions: Record<string, unknown> = {};
+  private fieldList: Field[] = [];
+  private orders: [OrderField, Direction][] = [];
+  private pageInfo?: { page: number; pageSize: number };
+
+  fields(...fields: Field[]): this {
+    this.fieldList.push(...fields);
+    return this;
+  }
+
+  page(page: number, pageSize: number): this {
+    this.pageInfo = { page, pageSize };
+    return this;
+  }
+
+  orderBy(field: OrderField, direction: Direction = 'asc'): this {
+    this.orders.push([field, direction]);
+    return this;
+  }
+
+  protected setOperator(name: string, key: string, v: unknown): this {
+    const cur = this.conditions[name];
+    const m = (typeof cur === 'object' && cur !== null && !Array.isArray(cur) ? cur : {}) as Record<string, unknown>;
+    m[key] = v;
+    this.conditions[name] = m;
+    return this;
+  }
+
+  toJSON(): Record<string, unknown> {
+    const doc: Record<string, unknown> = { ...this.conditions, fields: this.fieldList };
+    if (this.pageInfo) {
+      doc._page = this.pageInfo.page;
+      doc._page_size = this.pageInfo.pageSize;
+    }
+    if (this.orders.length > 0) {
+      doc._order_by = this.orders;
+    }
+    return doc;
+  }
+}
+
+export class Client {
+  constructor(private baseURL: string, private init: RequestInit = {}) {
+    this.baseURL = baseURL.replace(/\/+$/, '');
+  }
+
+  // access token of /auth/login
+  setToken(token: string): void {
+    const headers = new Headers(this.init.headers);
+    headers.set('Authorization', ` + "`Bearer ${token}`" + `);
+    this.init = { ...this.init, headers };
+  }
+
+  // API key of /auth/keys, e.g. for internal jobs
+  setAPIKey(key: string): void {
+    const headers = new Headers(this.init.headers);
+    headers.set('X-Api-Key', key);
+    this.init = { ...this.init, headers };
+  }
+
+  private async list<Row>(form: string, q: { toJSON(): Record<string, unknown> }): Promise<ListResult<Row>> {
+    const query = encodeURIComponent(JSON.stringify(q.toJSON()));
+    const resp = await fetch(` + "`${this.baseURL}/list/${form}?query=${query}`" + `, this.init);
+    const body = await resp.text();
+    if (!resp.ok) {
+      throw new BreadError(resp.status, body);
+    }
+    return JSON.parse(body) as ListResult<Row>;
+  }
+{{range .}}
+  list{{.Type}}(q: {{.Type}}Query): Promise<ListResult<{{.Type}}Row>> {
+    return this.list<{{.Type}}Row>('{{.Name}}', q);
+  }
+{{end -}}
+}
+{{range $g := .}}
+{{- range $g.Rows}}
+export interface {{.Type}} {
+{{- range .Fields}}
+  {{.Name}}?: {{.TSType}} | null;
+{{- end}}
+}
+{{end}}
+export type {{$g.Type}}Field ={{range $i, $f := $g.Fields}}{{if $i}} |{{end}} '{{$f.Name}}'{{end}};
+
+export type {{$g.Type}}OrderField ={{$n := 0}}{{range $g.Fields}}{{if .Orderable}}{{if $n}} |{{end}} '{{.Name}}'{{$n = 1}}{{end}}{{end}}{{if $g.Searchable}}{{if $n}} |{{end}} '{{relevance}}'{{$n = 1}}{{end}}{{if not $n}} never{{end}};
+
+export class {{$g.Type}}Query extends Query<{{$g.Type}}Field, {{$g.Type}}OrderField> {
+{{- if $g.Searchable}}
+  search(text: string): this {
+    this.conditions._search = text;
+    return this;
+  }
+{{end}}
+{{- range $f := $g.Fields}}{{range $f.Filters}}
+  {{lowerFirst .Method}}(v: {{$f.TSInput}}{{if .Slice}}[]{{end}}): this {
+    {{if .Key}}return this.setOperator('{{$f.Name}}', '{{.Key}}', v);{{else}}this.conditions['{{$f.Name}}'] = v;
+    return this;{{end}}
+  }
+{{end}}{{end -}}
+}
+{{end}}`))
@@ -2,6 +2,32 @@ package condition
 
 import "reflect"
 
+// operators produced by the default query param
+const (
+	OpEqual = "="
+	OpIn    = "in"
+)
+
+// OperatorKeys maps the other operators to their key in the object form of a query value, e.g. {"gte": 1}
+var OperatorKeys = map[string]string{
+	">":    "gt",
+	">=":   "gte",
+	"<":    "lt",
+	"<=":   "lte",
+	"!=":   "ne",
+	"like": "like",
+}
+
+// OperatorByKey is the reverse of OperatorKeys
+func OperatorByKey(key string) (string, bool) {
+	for op, k := range OperatorKeys {
+		if k == key {
+			return op, true
+		}
+	}
+	return "", false
+}
+
 type QueryParam struct {
 	*DBParam
 	operator       string
@@ -20,6 +46,7 @@ func (q *QueryParam) Operator() string {
 	return q.operator
 }
 
+// GetSql returns the sql of conditions built from a Template
 func (q *QueryParam) GetSql() string {
 	return q.sql
 }
@@ -36,22 +63,14 @@ func NewQueryParam(table, field, operator string, value interface{}) *QueryParam
 	}
 }
 
-func NewQueryParamWithSql(table, field, sql string, value []interface{}) *QueryParam {
-	return &QueryParam{
-		DBParam:        newDatabaseParam(table, field),
-		sql:            sql,
-		conditionValue: value,
-	}
-}
-
 func NewDefaultQueryParam(table, field string, value interface{}) *QueryParam {
 	rt := reflect.ValueOf(value)
 	var operator string
 	switch rt.Kind() {
 	case reflect.Slice, reflect.Array:
-		operator = "in"
+		operator = OpIn
 	default:
-		operator = "="
+		operator = OpEqual
 	}
 	return NewQueryParam(table, field, operator, value)
 }
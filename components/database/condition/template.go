@@ -0,0 +1,240 @@
+package condition
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// placeholder types of templates, e.g. {day:datetime}, {ids:ints}
+const (
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeString   = "string"
+	TypeBool     = "bool"
+	TypeDatetime = "datetime"
+	TypeInts     = "ints"
+	TypeStrings  = "strings"
+)
+
+// Template is a vetted sql fragment of a condition, registered by name when the entity groups are defined.
+// {field} 为条件所在的 表名.字段名, 值只能通过带类型的占位符传入, 如 DATE({field}) = {day:datetime}, 列表如 {field} IN ({ids:ints});
+// 不允许出现引号不匹配, 括号不匹配, 分号, 注释, 反斜杠及 ? 占位符
+type Template struct {
+	Name     string
+	segments []segment
+}
+
+type segment struct {
+	literal string
+	field   bool   // {field}
+	param   string // 占位符名
+	typ     string
+}
+
+var (
+	templatesMu sync.RWMutex
+	templates   = make(map[string]*Template)
+)
+
+// RegisterTemplate parses and registers a template, it panics on invalid templates and duplicate names
+func RegisterTemplate(name, sql string) *Template {
+	t, err := ParseTemplate(name, sql)
+	if err != nil {
+		panic(err)
+	}
+	templatesMu.Lock()
+	defer templatesMu.Unlock()
+	if _, ok := templates[name]; ok {
+		panic("condition: template " + name + " is registered twice")
+	}
+	templates[name] = t
+	return t
+}
+
+// LookupTemplate returns the registered template of name
+func LookupTemplate(name string) (*Template, bool) {
+	templatesMu.RLock()
+	defer templatesMu.RUnlock()
+	t, ok := templates[name]
+	return t, ok
+}
+
+// ParseTemplate checks sql and splits it into literals and placeholders
+func ParseTemplate(name, sql string) (*Template, error) {
+	fail := func(format string, args ...interface{}) (*Template, error) {
+		return nil, fmt.Errorf("template %s: %s", name, fmt.Sprintf(format, args...))
+	}
+
+	t := &Template{Name: name}
+	var lit strings.Builder
+	var quote rune
+	depth := 0
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c == '\\' {
+			return fail("backslashes are not allowed")
+		}
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			lit.WriteRune(c)
+			continue
+		}
+
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case ';':
+			return fail("statement separators are not allowed")
+		case '?':
+			return fail("use named placeholders instead of ?")
+		case '#':
+			return fail("comments are not allowed")
+		case '-', '/':
+			if i+1 < len(runes) && (c == '-' && runes[i+1] == '-' || c == '/' && runes[i+1] == '*') {
+				return fail("comments are not allowed")
+			}
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return fail("unbalanced parentheses")
+			}
+		case '{':
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end == len(runes) {
+				return fail("unclosed placeholder")
+			}
+			seg, err := parsePlaceholder(string(runes[i+1 : end]))
+			if err != nil {
+				return fail("%v", err)
+			}
+			t.segments = append(t.segments, segment{literal: lit.String()}, seg)
+			lit.Reset()
+			i = end
+			continue
+		}
+		lit.WriteRune(c)
+	}
+	if quote != 0 {
+		return fail("unbalanced quote %c", quote)
+	}
+	if depth != 0 {
+		return fail("unbalanced parentheses")
+	}
+	t.segments = append(t.segments, segment{literal: lit.String()})
+	return t, nil
+}
+
+func parsePlaceholder(s string) (segment, error) {
+	if s == "field" {
+		return segment{field: true}, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || !isIdentifier(parts[0]) {
+		return segment{}, fmt.Errorf("invalid placeholder {%s}, expected {name:type}", s)
+	}
+	switch parts[1] {
+	case TypeInt, TypeFloat, TypeString, TypeBool, TypeDatetime, TypeInts, TypeStrings:
+	default:
+		return segment{}, fmt.Errorf("unknown type %s of placeholder %s", parts[1], parts[0])
+	}
+	return segment{param: parts[0], typ: parts[1]}, nil
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Params lists the placeholder names of the template
+func (t *Template) Params() []string {
+	var names []string
+	for _, s := range t.segments {
+		if s.param != "" {
+			names = append(names, s.param)
+		}
+	}
+	return names
+}
+
+// Param builds the condition of the template on table.field, args are keyed by placeholder name and checked against their types
+func (t *Template) Param(table, field string, args map[string]interface{}) (*QueryParam, error) {
+	var sql strings.Builder
+	var values []interface{}
+	for _, s := range t.segments {
+		switch {
+		case s.field:
+			sql.WriteString(table + "." + field)
+		case s.param != "":
+			v, ok := args[s.param]
+			if !ok {
+				return nil, fmt.Errorf("template %s: missing value of %s", t.Name, s.param)
+			}
+			if !matchType(s.typ, v) {
+				return nil, fmt.Errorf("template %s: %s must be of type %s, got %T", t.Name, s.param, s.typ, v)
+			}
+			sql.WriteString("?")
+			values = append(values, v)
+		default:
+			sql.WriteString(s.literal)
+		}
+	}
+	return &QueryParam{
+		DBParam:        newDatabaseParam(table, field),
+		sql:            sql.String(),
+		conditionValue: values,
+	}, nil
+}
+
+func matchType(typ string, v interface{}) bool {
+	switch typ {
+	case TypeInts, TypeStrings:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice || rv.Len() == 0 {
+			return false
+		}
+		elem := TypeInt
+		if typ == TypeStrings {
+			elem = TypeString
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if !matchType(elem, rv.Index(i).Interface()) {
+				return false
+			}
+		}
+		return true
+	case TypeDatetime:
+		_, ok := v.(time.Time)
+		return ok
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return typ == TypeInt || typ == TypeFloat
+	case reflect.Float32, reflect.Float64:
+		return typ == TypeFloat
+	case reflect.String:
+		return typ == TypeString
+	case reflect.Bool:
+		return typ == TypeBool
+	default:
+		return false
+	}
+}
@@ -0,0 +1,154 @@
+package migrate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity/models"
+)
+
+type dialect struct {
+	transactionalDDL bool
+	quoteChar        string
+	primaryKey       string // 自增主键的字段定义
+	datetime         string
+	types            func(k reflect.Kind) string
+}
+
+var dialects = map[string]dialect{
+	schema.MySQL: {
+		quoteChar:  "`",
+		primaryKey: "BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY",
+		datetime:   "DATETIME",
+		types: func(k reflect.Kind) string {
+			switch k {
+			case reflect.Bool:
+				return "TINYINT(1)"
+			case reflect.Int8:
+				return "TINYINT"
+			case reflect.Uint8:
+				return "TINYINT UNSIGNED"
+			case reflect.Int16:
+				return "SMALLINT"
+			case reflect.Uint16:
+				return "SMALLINT UNSIGNED"
+			case reflect.Int32:
+				return "INT"
+			case reflect.Uint32:
+				return "INT UNSIGNED"
+			case reflect.Int, reflect.Int64:
+				return "BIGINT"
+			case reflect.Uint, reflect.Uint64:
+				return "BIGINT UNSIGNED"
+			case reflect.Float32:
+				return "FLOAT"
+			case reflect.Float64:
+				return "DOUBLE"
+			case reflect.String:
+				return "VARCHAR(255)"
+			default:
+				return "TEXT"
+			}
+		},
+	},
+	schema.Postgres: {
+		transactionalDDL: true,
+		quoteChar:        `"`,
+		primaryKey:       "BIGSERIAL PRIMARY KEY",
+		datetime:         "TIMESTAMP",
+		types: func(k reflect.Kind) string {
+			switch k {
+			case reflect.Bool:
+				return "BOOLEAN"
+			case reflect.Int8, reflect.Uint8, reflect.Int16:
+				return "SMALLINT"
+			case reflect.Uint16, reflect.Int32:
+				return "INTEGER"
+			case reflect.Uint32, reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+				return "BIGINT"
+			case reflect.Float32:
+				return "REAL"
+			case reflect.Float64:
+				return "DOUBLE PRECISION"
+			case reflect.String:
+				return "VARCHAR(255)"
+			default:
+				return "TEXT"
+			}
+		},
+	},
+	schema.SQLite: {
+		transactionalDDL: true,
+		quoteChar:        `"`,
+		primaryKey:       "INTEGER PRIMARY KEY AUTOINCREMENT",
+		datetime:         "DATETIME",
+		types: func(k reflect.Kind) string {
+			switch k {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return "INTEGER"
+			case reflect.Float32, reflect.Float64:
+				return "REAL"
+			default:
+				return "TEXT"
+			}
+		},
+	},
+}
+
+func getDialect(name string) (dialect, error) {
+	d, ok := dialects[name]
+	if !ok {
+		return d, fmt.Errorf("migrate: unsupported dialect %s", name)
+	}
+	return d, nil
+}
+
+func (d dialect) quote(name string) string {
+	return d.quoteChar + name + d.quoteChar
+}
+
+func (d dialect) column(f models.TableField) string {
+	if f.IsTime {
+		return d.quote(f.Name) + " " + d.datetime + " NULL"
+	}
+	return d.quote(f.Name) + " " + d.types(f.Type) + " NULL"
+}
+
+// modelColumn 定义gorm模型的列, 计数器等字段会在SQL中直接运算, 不能为NULL
+func (d dialect) modelColumn(name string, k reflect.Kind) string {
+	def := "0"
+	switch k {
+	case reflect.String:
+		def = "''"
+	case reflect.Bool:
+		def = "FALSE"
+	}
+	return d.quote(name) + " " + d.types(k) + " NOT NULL DEFAULT " + def
+}
+
+func (d dialect) createTable(table, pk string, fields []models.TableField) string {
+	cols := []string{d.quote(pk) + " " + d.primaryKey}
+	for _, f := range fields {
+		if f.Name == pk {
+			continue
+		}
+		cols = append(cols, d.column(f))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", d.quote(table), strings.Join(cols, ",\n\t"))
+}
+
+func (d dialect) addColumn(table string, f models.TableField) string {
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.quote(table), d.column(f))
+}
+
+func (d dialect) createIndex(table, column string) string {
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", d.quote("idx_"+table+"_"+column), d.quote(table), d.quote(column))
+}
+
+func (d dialect) versionTable() string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version VARCHAR(64) NOT NULL PRIMARY KEY, statements TEXT NOT NULL, applied_at %s NOT NULL)",
+		d.quote(VersionTable), d.datetime)
+}
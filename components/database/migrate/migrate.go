@@ -0,0 +1,226 @@
+package migrate
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+)
+
+// VersionTable records the applied migrations
+const VersionTable = "bread_migrations"
+
+type desiredTable struct {
+	table   models.Table
+	fields  []models.TableField
+	indexed map[string]bool // 需要索引的字段
+}
+
+// Diff returns the DDL statements needed to bring the database in line with the entity models.
+// Columns are only ever added, never altered or dropped.
+func Diff(dialect string, fieldsMap group.FieldsMap, tables map[string]*schema.Table) ([]string, error) {
+	d, err := getDialect(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	desired, err := collect(fieldsMap)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(desired))
+	for k := range desired {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var stmts []string
+	for _, name := range names {
+		dt := desired[name]
+		pk := dt.table.PrimaryKey()
+		current, ok := tables[name]
+		if !ok {
+			stmts = append(stmts, d.createTable(name, pk, dt.fields))
+			current = &schema.Table{Name: name}
+		} else {
+			for _, f := range dt.fields {
+				if _, ok := current.Columns[f.Name]; !ok {
+					stmts = append(stmts, d.addColumn(name, f))
+				}
+			}
+		}
+
+		var cols []string
+		for col := range dt.indexed {
+			if col == pk || current.Indexed(col) {
+				continue
+			}
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		for _, col := range cols {
+			stmts = append(stmts, d.createIndex(name, col))
+		}
+	}
+
+	return stmts, nil
+}
+
+// DiffModels returns the statements adding the columns of gorm models missing in the database,
+// for the tables not described by an entity group such as auth.
+// 新增的列为NOT NULL并以零值为默认值, 已有的行无需回填
+func DiffModels(db *gorm.DB, tables map[string]*schema.Table, values ...interface{}) ([]string, error) {
+	d, err := getDialect(db.Dialect().GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	var stmts []string
+	for _, v := range values {
+		scope := db.NewScope(v)
+		name := scope.TableName()
+		current, ok := tables[name]
+
+		var pk string
+		var cols []string
+		for _, f := range scope.GetModelStruct().StructFields {
+			if f.IsIgnored || !f.IsNormal {
+				continue
+			}
+			if f.IsPrimaryKey {
+				pk = f.DBName
+				continue
+			}
+			if ok {
+				if _, exists := current.Columns[f.DBName]; exists {
+					continue
+				}
+			}
+			cols = append(cols, d.modelColumn(f.DBName, f.Struct.Type.Kind()))
+		}
+
+		if !ok {
+			if pk == "" {
+				return nil, fmt.Errorf("migrate: %s has no primary key", name)
+			}
+			cols = append([]string{d.quote(pk) + " " + d.primaryKey}, cols...)
+			stmts = append(stmts, fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", d.quote(name), strings.Join(cols, ",\n\t")))
+			continue
+		}
+		for _, col := range cols {
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.quote(name), col))
+		}
+	}
+	return stmts, nil
+}
+
+// Apply executes the statements and records them in the version table.
+// The statements run in one transaction on the dialects supporting transactional DDL.
+func Apply(db *gorm.DB, stmts []string) (string, error) {
+	if len(stmts) == 0 {
+		return "", nil
+	}
+	d, err := getDialect(db.Dialect().GetName())
+	if err != nil {
+		return "", err
+	}
+
+	if err := db.Exec(d.versionTable()).Error; err != nil {
+		return "", err
+	}
+
+	version := Version(stmts)
+	record := func(tx *gorm.DB) error {
+		return tx.Exec(fmt.Sprintf("INSERT INTO %s (version, statements, applied_at) VALUES (?, ?, ?)", d.quote(VersionTable)),
+			version, strings.Join(stmts, ";\n"), time.Now().UTC()).Error
+	}
+
+	if !d.transactionalDDL {
+		for i, stmt := range stmts {
+			if err := db.Exec(stmt).Error; err != nil {
+				return "", fmt.Errorf("migrate: statement %d of %d failed, the previous ones are applied: %v", i+1, len(stmts), err)
+			}
+		}
+		return version, record(db)
+	}
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		return "", tx.Error
+	}
+	for _, stmt := range stmts {
+		if err := tx.Exec(stmt).Error; err != nil {
+			tx.Rollback()
+			return "", err
+		}
+	}
+	if err := record(tx); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	return version, tx.Commit().Error
+}
+
+// Version identifies a migration by its time and content
+func Version(stmts []string) string {
+	sum := sha1.Sum([]byte(strings.Join(stmts, ";\n")))
+	return time.Now().UTC().Format("20060102150405") + "_" + hex.EncodeToString(sum[:4])
+}
+
+// collect merges the tables of all the entity groups
+func collect(fieldsMap group.FieldsMap) (map[string]*desiredTable, error) {
+	desired := make(map[string]*desiredTable)
+	for _, g := range fieldsMap {
+		for _, t := range g.Tables() {
+			if _, ok := desired[t.TableName()]; ok {
+				continue
+			}
+			fields, err := models.Fields(t)
+			if err != nil {
+				return nil, err
+			}
+			dt := &desiredTable{
+				table:   t,
+				fields:  fields,
+				indexed: make(map[string]bool),
+			}
+			// 租户字段不需要在模型中声明, 所有查询都会使用
+			if col := t.TenantColumn(); col != "" {
+				if !hasField(fields, col) {
+					dt.fields = append(dt.fields, models.TableField{Type: reflect.Int64, Name: col, Permission: models.Read})
+				}
+				dt.indexed[col] = true
+			}
+			desired[t.TableName()] = dt
+		}
+
+		// 可查询和可排序的字段需要索引
+		g.Walk(func(_ string, f field.Field) {
+			if f.Table == nil || !(f.CanQuery || f.CanOrder) {
+				return
+			}
+			desired[f.Table.TableName()].indexed[f.TableField.Name] = true
+		})
+	}
+	return desired, nil
+}
+
+func hasField(fields []models.TableField, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/validators/query"
+)
+
+// 排序字段只能是 表名.字段名
+var orderColumn = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\.[A-Za-z_][A-Za-z0-9_]*$`)
+
+// orderClauses renders the order by column, NULLS FIRST|LAST is emulated by ordering on IS NULL except on postgres
+func orderClauses(dialect, column string, d query.Direction) ([]string, error) {
+	if !orderColumn.MatchString(column) {
+		return nil, e.Wrap(e.ERROR, fmt.Errorf("invalid order column %q", column))
+	}
+	order := column + " " + sqlDirection(d)
+	switch {
+	case d.Nulls == "":
+		return []string{order}, nil
+	case dialect == "postgres":
+		return []string{order + " NULLS " + strings.ToUpper(d.Nulls)}, nil
+	case d.Nulls == query.NullsFirst:
+		return []string{column + " IS NULL DESC", order}, nil
+	default:
+		return []string{column + " IS NULL ASC", order}, nil
+	}
+}
+
+func sqlDirection(d query.Direction) string {
+	if d.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
@@ -0,0 +1,114 @@
+package outputs
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 脱敏方式
+const (
+	MaskKeep   = "keep"   // 保留首尾的字符, 如 138****5678
+	MaskHash   = "hash"   // 带密钥的哈希, 相同的值哈希相同
+	MaskRedact = "redact" // 完全隐藏, 包括长度
+	MaskEmail  = "email"  // 只隐藏邮箱@之前的部分, 如 a***@example.com
+)
+
+// Redacted replaces masked values entirely
+const Redacted = "***"
+
+// Mask hides a sensitive value in the output, it is applied after the callback of the field.
+// 声明了Mask的字段不能加入Search.Fields, 否则可以通过_search按原值匹配
+//
+//	"name": field.Field{
+//		Table:       models.Student,
+//		TableField:  models.Student.Name,
+//		Mask:        outputs.KeepEnds(1, 0), // 姓名只保留第一个字
+//		Permissions: field.Permissions{Unmask: []string{"student:sensitive", auth.RolePrefix + "admin"}},
+//	},
+type Mask struct {
+	Kind  string
+	First int // MaskKeep 保留的开头字符数
+	Last  int // MaskKeep 保留的结尾字符数
+}
+
+// KeepEnds keeps the first and last characters, e.g. KeepEnds(3, 4) for phone numbers
+func KeepEnds(first, last int) *Mask {
+	return &Mask{Kind: MaskKeep, First: first, Last: last}
+}
+
+// Hash replaces the value by its keyed hash, see SetMaskKey
+func Hash() *Mask {
+	return &Mask{Kind: MaskHash}
+}
+
+// Redact replaces the value by Redacted
+func Redact() *Mask {
+	return &Mask{Kind: MaskRedact}
+}
+
+// EmailLocal masks the local part of an email address
+func EmailLocal() *Mask {
+	return &Mask{Kind: MaskEmail}
+}
+
+// 未设置时使用随机密钥, 重启后哈希会变化
+var maskKey = randomKey()
+
+// SetMaskKey sets the key of MaskHash, keep it stable to compare hashes between restarts
+func SetMaskKey(key []byte) {
+	maskKey = key
+}
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// Apply returns the masked value, nil stays nil and other values are masked as strings
+func (m *Mask) Apply(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	var s string
+	switch vv := v.(type) {
+	case string:
+		s = vv
+	case []byte:
+		s = string(vv)
+	default:
+		s = fmt.Sprint(vv)
+	}
+
+	switch m.Kind {
+	case MaskKeep:
+		return keepEnds(s, m.First, m.Last)
+	case MaskHash:
+		h := hmac.New(sha256.New, maskKey)
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil))[:16]
+	case MaskEmail:
+		at := strings.LastIndex(s, "@")
+		if at <= 0 {
+			return Redacted
+		}
+		return keepEnds(s[:at], 1, 0) + s[at:]
+	default:
+		return Redacted
+	}
+}
+
+// 值过短时全部隐藏, 避免露出完整的值
+func keepEnds(s string, first, last int) string {
+	r := []rune(s)
+	if len(r) <= first+last {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[:first]) + strings.Repeat("*", len(r)-first-last) + string(r[len(r)-last:])
+}
@@ -0,0 +1,47 @@
+package outputs
+
+import (
+	"testing"
+)
+
+func TestMaskApply(t *testing.T) {
+	SetMaskKey([]byte("test key"))
+	cases := []struct {
+		name  string
+		mask  *Mask
+		value interface{}
+		want  interface{}
+	}{
+		{"phone", KeepEnds(3, 4), "13812345678", "138****5678"},
+		{"first character", KeepEnds(1, 0), "张小明", "张**"},
+		{"too short", KeepEnds(3, 4), "1234567", "*******"},
+		{"number", KeepEnds(0, 2), 123456, "****56"},
+		{"bytes", KeepEnds(1, 1), []byte("abc"), "a*c"},
+		{"nil", KeepEnds(1, 1), nil, nil},
+		{"redact", Redact(), "secret", Redacted},
+		{"email", EmailLocal(), "alice@example.com", "a****@example.com"},
+		{"email of one character", EmailLocal(), "a@example.com", "*@example.com"},
+		{"not an email", EmailLocal(), "alice", Redacted},
+		{"unknown kind", &Mask{Kind: "nope"}, "secret", Redacted},
+	}
+	for _, c := range cases {
+		if got := c.mask.Apply(c.value); got != c.want {
+			t.Errorf("%s: %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestMaskHash(t *testing.T) {
+	SetMaskKey([]byte("test key"))
+	a, b := Hash().Apply("alice"), Hash().Apply("alice")
+	if a != b || len(a.(string)) != 16 {
+		t.Errorf("hashes %v and %v of the same value", a, b)
+	}
+	if Hash().Apply("bob") == a {
+		t.Error("different values have the same hash")
+	}
+	SetMaskKey([]byte("other key"))
+	if Hash().Apply("alice") == a {
+		t.Error("the hash does not depend on the key")
+	}
+}
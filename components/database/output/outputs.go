@@ -1,12 +1,45 @@
 package outputs
 
-import "github.com/go-bread/iface/entity_query"
+import (
+	"time"
+
+	"github.com/go-bread/iface/entity_query"
+)
+
+// 时间的输出格式, 默认为 DefaultTimeLayout
+const (
+	TimeISO8601   = "iso8601"
+	TimeUnix      = "unix"
+	TimeUnixMilli = "unix_ms"
+	TimeDate      = "date"
+)
+
+const DefaultTimeLayout = "2006-01-02 15:04:05"
 
 type OutputField struct {
 	TableField string
 	Table      string
 	OutPut     string
 	F          entity_query.CallbackFunc
+	TimeFormat string
+	Mask       *Mask // 为空时不脱敏
 }
 
 type Callbacks map[string]entity_query.CallbackFunc
+
+// FormatTime formats t in loc
+func FormatTime(t time.Time, loc *time.Location, format string) interface{} {
+	t = t.In(loc)
+	switch format {
+	case TimeISO8601:
+		return t.Format(time.RFC3339)
+	case TimeUnix:
+		return t.Unix()
+	case TimeUnixMilli:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimeDate:
+		return t.Format("2006-01-02")
+	default:
+		return t.Format(DefaultTimeLayout)
+	}
+}
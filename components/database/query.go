@@ -1,19 +1,24 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/go-bread/components/entity/group"
-	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 
+	"github.com/go-bread/components/database/condition"
 	outputs "github.com/go-bread/components/database/output"
 	models2 "github.com/go-bread/components/entity/models"
 	"github.com/go-bread/iface/entity_query"
 	validatorIface "github.com/go-bread/iface/validator"
 	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/timezone"
 	"github.com/go-bread/validators/query"
 )
 
@@ -33,7 +38,16 @@ const (
 	Or  = "or"
 )
 
-func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validatorIface.Condition, outputs []*outputs.OutputField, pagination *query.Pagination, order [][2]string) ([]map[string]interface{}, error) {
+func QueryAndFormat(ctx *caller.Caller, group group.EntityGroup, params []validatorIface.Condition, outputs []*outputs.OutputField, pagination *query.Pagination, order [][2]string, search *Search) ([]map[string]interface{}, error) {
+	return queryAndFormat(ctx, models.GetDb(), false, group, params, outputs, pagination, order, search)
+}
+
+// QueryForUpdate is QueryAndFormat in the transaction tx, the rows of the drive table are locked until tx ends
+func QueryForUpdate(ctx *caller.Caller, tx *gorm.DB, group group.EntityGroup, params []validatorIface.Condition, outputs []*outputs.OutputField, pagination *query.Pagination) ([]map[string]interface{}, error) {
+	return queryAndFormat(ctx, tx, true, group, params, outputs, pagination, nil, nil)
+}
+
+func queryAndFormat(ctx *caller.Caller, db *gorm.DB, lock bool, group group.EntityGroup, params []validatorIface.Condition, outputs []*outputs.OutputField, pagination *query.Pagination, order [][2]string, search *Search) ([]map[string]interface{}, error) {
 	var r []map[string]interface{}
 	// 回调函数处理
 	callbacks := callbackBuild(outputs)
@@ -44,19 +58,33 @@ func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validato
 	}
 
 	tables := uniqueTables(outputs)
+	// 条件及搜索的字段不在输出中时同样需要关联对应的表
+	for _, p := range params {
+		if p.TableName() != "" {
+			tables[p.TableName()] = true
+		}
+	}
+	if search != nil {
+		for _, t := range search.Tables {
+			tables[t] = true
+		}
+	}
 
 	// select字段处理
-	selectFields := selectFieldsBuild(group, tables)
+	selectFields, err := selectFieldsBuild(db.Dialect().Quote, group, tables)
+	if err != nil {
+		return nil, err
+	}
 
 	// 多表关联查询
 	associations := make(map[string]*models2.Association)
 	var majorTable string
 	if len(tables) > 1 {
 		if len(tables) > 2 {
-			panic(fmt.Sprintf("暂不支持超过两张以上表关联查询 %+v", tables))
+			return nil, e.Wrap(e.ERROR, fmt.Errorf("暂不支持超过两张以上表关联查询 %+v", tables))
 		}
 		if group.JoinDriveTable == nil {
-			panic("多表关联查询必须声明驱动表")
+			return nil, e.Wrap(e.ERROR, errors.New("多表关联查询必须声明驱动表"))
 		}
 		majorTable = group.JoinDriveTable.TableName()
 		for t := range tables {
@@ -65,7 +93,7 @@ func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validato
 			}
 			ass := group.JoinDriveTable.GetAssociation(t)
 			if ass == nil {
-				panic(fmt.Sprintf("关联关系未定义: majorTable: %s, target: %s", group.JoinDriveTable.TableName(), t))
+				return nil, e.Wrap(e.ERROR, fmt.Errorf("关联关系未定义: majorTable: %s, target: %s", group.JoinDriveTable.TableName(), t))
 			}
 			if _, ok := associations[t]; !ok {
 				associations[t] = ass
@@ -78,49 +106,98 @@ func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validato
 		}
 	}
 
-	model := models.GetDb().Table(majorTable)
+	// 多租户的表只能访问请求所属租户的数据, 关联的表在ON中限制
+	queried := []string{majorTable}
+	for t := range associations {
+		queried = append(queried, t)
+	}
+	tenants, err := newTenantFilter(ctx, group, queried)
+	if err != nil {
+		return nil, err
+	}
+
+	model := db.Table(majorTable)
 	model.LogMode(true)
 	if len(associations) > 0 {
 		for _, ass := range associations {
-			model = model.Joins(fmt.Sprintf("%s %s on %s.%s = %s.%s", ass.Join, ass.TargetTable, ass.TargetTable, ass.ForeignKey, group.JoinDriveTable.TableName(), ass.LocalKey))
+			join := fmt.Sprintf("%s %s on %s.%s = %s.%s", ass.Join, ass.TargetTable, ass.TargetTable, ass.ForeignKey, group.JoinDriveTable.TableName(), ass.LocalKey)
+			sql, args := tenants.condition(ass.TargetTable)
+			if sql != "" {
+				join += " AND " + sql
+			}
+			model = model.Joins(join, args...)
 		}
 	}
+	if sql, args := tenants.condition(majorTable); sql != "" {
+		model = model.Where(sql, args...)
+	}
 	model = model.Where(cond, vals...)
+	dialect := model.Dialect().GetName()
+	if search != nil {
+		sql, args := search.where(dialect)
+		model = model.Where(sql, args...)
+	}
 	if pagination.Page != 0 {
-		model.Count(&pagination.TotalCount)
+		if err := model.Count(&pagination.TotalCount).Error; err != nil {
+			return nil, e.Wrap(e.ERROR_DATABASE, err)
+		}
 		model = model.Offset((pagination.Page - 1) * pagination.PageSize).Limit(pagination.PageSize)
 	}
+	if pagination.MaxRows != 0 && (pagination.Page == 0 || pagination.MaxRows < pagination.PageSize) {
+		model = model.Limit(pagination.MaxRows)
+	}
 
 	if len(order) > 0 {
 		for _, v := range order {
-			stringOrder := fmt.Sprintf("%s %s", v[0], v[1])
-			model = model.Order(stringOrder)
+			d, err := query.ParseDirection(v[1])
+			if err != nil {
+				return nil, e.New(e.ERROR_INVALID_ORDER)
+			}
+			if v[0] == relevanceOrder && search != nil {
+				// 相关度不为NULL, 只使用升降序
+				sql, args := search.relevance(dialect)
+				model = model.Order(gorm.Expr(sql+" "+sqlDirection(d), args...))
+				continue
+			}
+			clauses, err := orderClauses(dialect, v[0], d)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range clauses {
+				model = model.Order(c)
+			}
 		}
 	}
 	if len(selectFields) > 0 {
 		model = model.Select(selectFields)
 	}
+	// 只锁定查询的数据, 不包括前面的Count
+	if lock {
+		if clause := lockClause(dialect, majorTable); clause != "" {
+			model = model.Set("gorm:query_option", clause)
+		}
+	}
 	rows, err := model.Rows()
 	if err != nil {
-		return nil, err
+		return nil, e.Wrap(e.ERROR_DATABASE, err)
 	}
 	defer func() {
 		if closeErr := rows.Close(); closeErr != nil {
-			log.Print("close err:%s", closeErr.Error())
+			log.Printf("close err:%s", closeErr.Error())
 		}
 	}()
 
 	columns, err := rows.Columns()
 	length := len(columns)
 	if err != nil {
-		return nil, err
+		return nil, e.Wrap(e.ERROR_DATABASE, err)
 	}
 	var finalRows []map[string]interface{}
 	primaryKeys := make([]uint64, 0)
 	for rows.Next() {
 		current := makeResultReceiver(length)
 		if err := rows.Scan(current...); err != nil {
-			panic(err)
+			return nil, e.Wrap(e.ERROR_DATABASE, err)
 		}
 		row := make(map[string]interface{})
 		for i := 0; i < length; i++ {
@@ -138,12 +215,20 @@ func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validato
 		}
 		finalRows = append(finalRows, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, e.Wrap(e.ERROR_DATABASE, err)
+	}
 
 	ls := entity_query.NewLS()
 	// 存储当前页的所有主键, 部分场景下做数据预加载
 	if len(primaryKeys) > 0 {
 		ls.Set("primary_keys", primaryKeys)
 	}
+	// 时区无效的请求在解析条件时已返回错误
+	loc, err := ctx.Location()
+	if err != nil {
+		loc = timezone.Default
+	}
 	for _, row := range finalRows {
 		value := make(map[string]interface{})
 		for _, o := range outputs {
@@ -151,8 +236,8 @@ func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validato
 			if !ok {
 				continue
 			}
-			outputKey, outputVal := formatValue(ctx, v, o, callbacks, ls, row)
-			value[outputKey] = outputVal
+			outputKey, outputVal := formatValue(ctx, v, o, callbacks, ls, row, loc)
+			setPath(value, outputKey, outputVal)
 		}
 
 		r = append(r, value)
@@ -161,23 +246,46 @@ func QueryAndFormat(ctx *gin.Context, group group.EntityGroup, params []validato
 	return r, nil
 }
 
-func formatValue(ctx *gin.Context, v interface{}, o *outputs.OutputField, c outputs.Callbacks, storage *entity_query.LocalStorage, row map[string]interface{}) (string, interface{}) {
+// 脱敏在回调之后执行
+func formatValue(ctx *caller.Caller, v interface{}, o *outputs.OutputField, c outputs.Callbacks, storage *entity_query.LocalStorage, row map[string]interface{}, loc *time.Location) (string, interface{}) {
+	key, value := formatRawValue(ctx, v, o, c, storage, row, loc)
+	if o.Mask != nil {
+		value = o.Mask.Apply(value)
+	}
+	return key, value
+}
+
+func formatRawValue(ctx *caller.Caller, v interface{}, o *outputs.OutputField, c outputs.Callbacks, storage *entity_query.LocalStorage, row map[string]interface{}, loc *time.Location) (string, interface{}) {
 	if f, ok := c[fieldCallbackIndex(o)]; ok {
-		return o.OutPut, f(ctx, v, row, storage)
+		return o.OutPut, f(ctx.Context, v, row, storage)
 	}
 
 	switch v.(type) {
 	case []byte:
 		return o.OutPut, string(v.([]byte))
 	case time.Time:
-		return o.OutPut, v.(time.Time).Format("2006-01-02 15:04:05")
+		return o.OutPut, outputs.FormatTime(v.(time.Time), loc, o.TimeFormat)
 	case *time.Time:
-		return o.OutPut, v.(*time.Time).Format("2006-01-02 15:04:05")
+		return o.OutPut, outputs.FormatTime(*v.(*time.Time), loc, o.TimeFormat)
 	default:
 		return o.OutPut, v
 	}
 }
 
+// setPath renders the fields of nested namespaces such as class.name as nested objects
+func setPath(value map[string]interface{}, path string, v interface{}) {
+	names := strings.Split(path, group.Separator)
+	for _, name := range names[:len(names)-1] {
+		ns, ok := value[name].(map[string]interface{})
+		if !ok {
+			ns = make(map[string]interface{})
+			value[name] = ns
+		}
+		value = ns
+	}
+	value[names[len(names)-1]] = v
+}
+
 func makeResultReceiver(length int) []interface{} {
 	result := make([]interface{}, 0, length)
 	for i := 0; i < length; i++ {
@@ -205,8 +313,11 @@ func whereBuild(params []validatorIface.Condition) (whereSQL string, vals []inte
 			whereSQL += " AND "
 		}
 
-		// 如果指定了sql模板, 优先使用sql
+		// 如果指定了sql模板, 优先使用sql; 只接受通过condition.Template生成的sql
 		if v.GetSql() != "" {
+			if _, ok := v.(*condition.QueryParam); !ok {
+				return "", nil, e.Wrap(e.ERROR, fmt.Errorf("sql of condition %T on %s is not built from a template", v, v.GetFullField()))
+			}
 			whereSQL += fmt.Sprintf(" (%s) ", v.GetSql())
 		} else {
 			k := v.GetFullField()
@@ -237,18 +348,19 @@ func whereBuild(params []validatorIface.Condition) (whereSQL string, vals []inte
 	return
 }
 
-func selectFieldsBuild(group group.EntityGroup, tables map[string]bool) []string {
+// 字段的别名为 表名.字段名, 按数据库的方言引用
+func selectFieldsBuild(quote func(string) string, group group.EntityGroup, tables map[string]bool) ([]string, error) {
 	var selectFields []string
 	for k := range tables {
 		es, ok := group.GetDividedEntities()[k]
 		if !ok {
-			panic("不支持的table " + k)
+			return nil, e.Wrap(e.ERROR, errors.New("不支持的table "+k))
 		}
 		for _, v := range es {
-			selectFields = append(selectFields, fmt.Sprintf("`%s`.`%s` as '%s.%s'", k, v, k, v))
+			selectFields = append(selectFields, fmt.Sprintf("%s.%s as %s", quote(k), quote(v), quote(k+"."+v)))
 		}
 	}
-	return selectFields
+	return selectFields, nil
 }
 
 func callbackBuild(ofs []*outputs.OutputField) outputs.Callbacks {
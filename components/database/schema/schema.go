@@ -0,0 +1,232 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite3"
+)
+
+type Column struct {
+	Name     string
+	DataType string // 小写且不带长度, 如 varchar, int, datetime
+	Nullable bool
+}
+
+type Table struct {
+	Name    string
+	Columns map[string]Column
+	Indexes map[string][]string // 索引名 -> 按顺序排列的字段
+}
+
+// Indexed reports whether column is the leading column of an index
+func (t *Table) Indexed(column string) bool {
+	for _, cols := range t.Indexes {
+		if len(cols) > 0 && cols[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+// Inspect loads the tables and columns of the connected database
+func Inspect(db *gorm.DB) (map[string]*Table, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	switch db.Dialect().GetName() {
+	case MySQL:
+		rows, err = db.Raw("SELECT table_name, column_name, column_type, is_nullable FROM information_schema.columns WHERE table_schema = DATABASE()").Rows()
+	case Postgres:
+		rows, err = db.Raw("SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns WHERE table_schema = current_schema()").Rows()
+	case SQLite:
+		return inspectSQLite(db)
+	default:
+		return nil, fmt.Errorf("schema: unsupported dialect %s", db.Dialect().GetName())
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*Table)
+	for rows.Next() {
+		var table, name, dataType, nullable string
+		if err := rows.Scan(&table, &name, &dataType, &nullable); err != nil {
+			return nil, err
+		}
+		addColumn(tables, table, Column{
+			Name:     name,
+			DataType: NormalizeType(dataType),
+			Nullable: strings.EqualFold(nullable, "YES"),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tables, inspectIndexes(db, tables)
+}
+
+func inspectIndexes(db *gorm.DB, tables map[string]*Table) error {
+	var q string
+	switch db.Dialect().GetName() {
+	case MySQL:
+		q = "SELECT table_name, index_name, column_name FROM information_schema.statistics WHERE table_schema = DATABASE() ORDER BY table_name, index_name, seq_in_index"
+	case Postgres:
+		q = `SELECT t.relname, i.relname, a.attname FROM pg_index ix
+	JOIN pg_class t ON t.oid = ix.indrelid
+	JOIN pg_class i ON i.oid = ix.indexrelid
+	JOIN pg_namespace n ON n.oid = t.relnamespace
+	JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+	JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+	WHERE n.nspname = current_schema() ORDER BY t.relname, i.relname, k.ord`
+	}
+
+	rows, err := db.Raw(q).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table, index, column string
+		if err := rows.Scan(&table, &index, &column); err != nil {
+			return err
+		}
+		addIndexColumn(tables, table, index, column)
+	}
+	return rows.Err()
+}
+
+func inspectSQLite(db *gorm.DB) (map[string]*Table, error) {
+	var names []string
+	rows, err := db.Raw("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Rows()
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+
+	tables := make(map[string]*Table)
+	for _, table := range names {
+		cols, err := db.Raw(fmt.Sprintf("PRAGMA table_info(%q)", table)).Rows()
+		if err != nil {
+			return nil, err
+		}
+		for cols.Next() {
+			var (
+				cid, notNull, pk int
+				name, dataType   string
+				dflt             sql.NullString
+			)
+			if err := cols.Scan(&cid, &name, &dataType, &notNull, &dflt, &pk); err != nil {
+				cols.Close()
+				return nil, err
+			}
+			addColumn(tables, table, Column{
+				Name:     name,
+				DataType: NormalizeType(dataType),
+				Nullable: notNull == 0,
+			})
+			// INTEGER PRIMARY KEY 不会出现在 index_list 中
+			if pk > 0 {
+				addIndexColumn(tables, table, "PRIMARY", name)
+			}
+		}
+		cols.Close()
+
+		if err := inspectSQLiteIndexes(db, tables, table); err != nil {
+			return nil, err
+		}
+	}
+
+	return tables, nil
+}
+
+func inspectSQLiteIndexes(db *gorm.DB, tables map[string]*Table, table string) error {
+	var indexes []string
+	rows, err := db.Raw(fmt.Sprintf("PRAGMA index_list(%q)", table)).Rows()
+	if err != nil {
+		return err
+	}
+	cols, _ := rows.Columns()
+	for rows.Next() {
+		// index_list 的字段数量随sqlite版本变化, 只取第二列的索引名
+		dest := make([]interface{}, len(cols))
+		var name string
+		for i := range dest {
+			dest[i] = new(interface{})
+		}
+		dest[1] = &name
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, name)
+	}
+	rows.Close()
+
+	for _, index := range indexes {
+		info, err := db.Raw(fmt.Sprintf("PRAGMA index_info(%q)", index)).Rows()
+		if err != nil {
+			return err
+		}
+		for info.Next() {
+			var (
+				seqno, cid int
+				column     sql.NullString
+			)
+			if err := info.Scan(&seqno, &cid, &column); err != nil {
+				info.Close()
+				return err
+			}
+			addIndexColumn(tables, table, index, column.String)
+		}
+		info.Close()
+	}
+	return nil
+}
+
+func getTable(tables map[string]*Table, table string) *Table {
+	t, ok := tables[table]
+	if !ok {
+		t = &Table{Name: table, Columns: make(map[string]Column), Indexes: make(map[string][]string)}
+		tables[table] = t
+	}
+	return t
+}
+
+func addColumn(tables map[string]*Table, table string, c Column) {
+	getTable(tables, table).Columns[c.Name] = c
+}
+
+func addIndexColumn(tables map[string]*Table, table, index, column string) {
+	t := getTable(tables, table)
+	t.Indexes[index] = append(t.Indexes[index], column)
+}
+
+// NormalizeType strips length and modifiers: "int(11) unsigned" -> "int"
+func NormalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if i := strings.IndexByte(t, '('); i >= 0 {
+		t = t[:i]
+	}
+	t = strings.TrimSuffix(t, " unsigned")
+	return strings.TrimSpace(t)
+}
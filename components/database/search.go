@@ -0,0 +1,94 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity/group"
+)
+
+// 排序字段为 _relevance 时按 Search.relevance 排序
+const relevanceOrder = group.RelevanceField
+
+// Search is the _search of a query, matched against the columns of group.Search
+type Search struct {
+	Text     string
+	Columns  []string // table.column
+	Tables   []string
+	FullText bool
+	Language string
+}
+
+// NewSearch returns nil when text is empty
+func NewSearch(g group.EntityGroup, text string) *Search {
+	fields, ok := g.SearchFields()
+	if !ok || text == "" {
+		return nil
+	}
+	s := &Search{Text: text, FullText: g.Search.FullText, Language: g.Search.Language}
+	if s.Language == "" {
+		s.Language = group.DefaultSearchLanguage
+	}
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		t := f.Table.TableName()
+		s.Columns = append(s.Columns, t+"."+f.TableField.Name)
+		if !seen[t] {
+			seen[t] = true
+			s.Tables = append(s.Tables, t)
+		}
+	}
+	return s
+}
+
+// LIKE 使用 ! 作为转义字符, mysql 与 sqlite 对反斜杠的处理不同
+var searchLikeReplacer = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// where returns the condition of the search
+func (s *Search) where(dialect string) (string, []interface{}) {
+	switch {
+	case dialect == schema.MySQL && s.FullText:
+		return s.relevance(dialect)
+	case dialect == schema.Postgres:
+		doc, query, args := s.tsvector()
+		return doc + " @@ " + query, args
+	default:
+		conds := make([]string, len(s.Columns))
+		args := make([]interface{}, len(s.Columns))
+		for i, c := range s.Columns {
+			conds[i] = c + " LIKE ? ESCAPE '!'"
+			args[i] = s.pattern()
+		}
+		return strings.Join(conds, " OR "), args
+	}
+}
+
+// relevance returns the expression ordered by _relevance, LIKE匹配时为匹配的字段个数
+func (s *Search) relevance(dialect string) (string, []interface{}) {
+	switch {
+	case dialect == schema.MySQL && s.FullText:
+		return fmt.Sprintf("MATCH (%s) AGAINST (? IN NATURAL LANGUAGE MODE)", strings.Join(s.Columns, ", ")), []interface{}{s.Text}
+	case dialect == schema.Postgres:
+		doc, query, args := s.tsvector()
+		return fmt.Sprintf("ts_rank(%s, %s)", doc, query), args
+	default:
+		cases := make([]string, len(s.Columns))
+		args := make([]interface{}, len(s.Columns))
+		for i, c := range s.Columns {
+			cases[i] = fmt.Sprintf("CASE WHEN %s LIKE ? ESCAPE '!' THEN 1 ELSE 0 END", c)
+			args[i] = s.pattern()
+		}
+		return "(" + strings.Join(cases, " + ") + ")", args
+	}
+}
+
+func (s *Search) tsvector() (doc, query string, args []interface{}) {
+	doc = fmt.Sprintf("to_tsvector(CAST(? AS regconfig), concat_ws(' ', %s))", strings.Join(s.Columns, ", "))
+	query = "plainto_tsquery(CAST(? AS regconfig), ?)"
+	return doc, query, []interface{}{s.Language, s.Language, s.Text}
+}
+
+func (s *Search) pattern() string {
+	return "%" + searchLikeReplacer.Replace(s.Text) + "%"
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity/group"
+	models2 "github.com/go-bread/components/entity/models"
+	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+)
+
+// tenantFilter limits the tables shared by tenants to the rows of the tenant of the request
+type tenantFilter struct {
+	id      int64
+	columns map[string]string // 表名 -> 租户字段
+}
+
+// 查询的表中有多租户的表时, 请求必须属于某个租户
+func newTenantFilter(ctx *caller.Caller, g group.EntityGroup, tables []string) (*tenantFilter, error) {
+	columns := make(map[string]string)
+	for _, t := range g.Tables() {
+		columns[t.TableName()] = t.TenantColumn()
+	}
+	f := &tenantFilter{columns: make(map[string]string)}
+	for _, t := range tables {
+		if col := columns[t]; col != "" {
+			f.columns[t] = col
+		}
+	}
+	if len(f.columns) == 0 {
+		return f, nil
+	}
+
+	id, err := ctx.Tenant()
+	if err != nil {
+		return nil, err
+	}
+	if id == 0 {
+		names := make([]string, 0, len(f.columns))
+		for t := range f.columns {
+			names = append(names, t)
+		}
+		sort.Strings(names)
+		return nil, e.New(e.ERROR_TENANT_REQUIRED, strings.Join(names, ", "))
+	}
+	f.id = id
+	return f, nil
+}
+
+// condition returns the tenant condition of table, an empty sql for the tables not shared by tenants
+func (f *tenantFilter) condition(table string) (string, []interface{}) {
+	col, ok := f.columns[table]
+	if !ok {
+		return "", nil
+	}
+	return fmt.Sprintf("%s.%s = ?", table, col), []interface{}{f.id}
+}
+
+// Insert inserts a row into t and returns its primary key,
+// the tenant column of a table shared by tenants is always the tenant of the request
+func Insert(ctx *caller.Caller, t models2.Table, values map[string]interface{}) (int64, error) {
+	row := make(map[string]interface{}, len(values)+1)
+	for k, v := range values {
+		row[k] = v
+	}
+	col, id, err := writeTenant(ctx, t, row)
+	if err != nil {
+		return 0, err
+	}
+	if col != "" {
+		row[col] = id
+	}
+
+	db := models.GetDb()
+	dialect := db.Dialect()
+	columns := sortedColumns(row)
+	quoted := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, k := range columns {
+		quoted[i] = dialect.Quote(k)
+		args[i] = row[k]
+	}
+	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", dialect.Quote(t.TableName()), strings.Join(quoted, ", "), placeholders(len(columns)))
+	// PostgreSQL的驱动不支持LastInsertId, 由RETURNING返回主键; gorm的Raw会转换占位符
+	if dialect.GetName() == schema.Postgres {
+		var pk int64
+		if err := db.Raw(sql+" RETURNING "+dialect.Quote(t.PrimaryKey()), args...).Row().Scan(&pk); err != nil {
+			return 0, e.Wrap(e.ERROR_DATABASE, err)
+		}
+		return pk, nil
+	}
+	// gorm的Exec不返回自增的主键, 直接使用底层的连接
+	res, err := db.CommonDB().Exec(sql, args...)
+	if err != nil {
+		return 0, e.Wrap(e.ERROR_DATABASE, err)
+	}
+	pk, err := res.LastInsertId()
+	if err != nil {
+		return 0, e.Wrap(e.ERROR_DATABASE, err)
+	}
+	return pk, nil
+}
+
+// Update sets values on the rows of t whose primary key is one of keys in the transaction tx and returns the number of rows updated.
+// 多租户的表只更新请求所属租户的数据, 且不能修改租户字段
+func Update(ctx *caller.Caller, tx *gorm.DB, t models2.Table, keys []interface{}, values map[string]interface{}) (int64, error) {
+	if len(keys) == 0 || len(values) == 0 {
+		return 0, nil
+	}
+	col, id, err := writeTenant(ctx, t, values)
+	if err != nil {
+		return 0, err
+	}
+
+	dialect := tx.Dialect()
+	columns := sortedColumns(values)
+	sets := make([]string, len(columns))
+	args := make([]interface{}, 0, len(columns)+len(keys)+1)
+	for i, k := range columns {
+		sets[i] = dialect.Quote(k) + " = ?"
+		args = append(args, values[k])
+	}
+	where, whereArgs := keysCondition(dialect.Quote, t, keys, col, id)
+	sql := fmt.Sprintf("UPDATE %s SET %s WHERE %s", dialect.Quote(t.TableName()), strings.Join(sets, ", "), where)
+	r := tx.Exec(sql, append(args, whereArgs...)...)
+	if r.Error != nil {
+		return 0, e.Wrap(e.ERROR_DATABASE, r.Error)
+	}
+	return r.RowsAffected, nil
+}
+
+// Delete deletes the rows of t whose primary key is one of keys in the transaction tx and returns the number of rows deleted
+func Delete(ctx *caller.Caller, tx *gorm.DB, t models2.Table, keys []interface{}) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	col, id, err := writeTenant(ctx, t, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	where, args := keysCondition(tx.Dialect().Quote, t, keys, col, id)
+	r := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s", tx.Dialect().Quote(t.TableName()), where), args...)
+	if r.Error != nil {
+		return 0, e.Wrap(e.ERROR_DATABASE, r.Error)
+	}
+	return r.RowsAffected, nil
+}
+
+// writeTenant returns the tenant column of t and the tenant of the request, an empty column for the tables not shared by tenants.
+// 不允许写入其他租户的数据
+func writeTenant(ctx *caller.Caller, t models2.Table, values map[string]interface{}) (string, int64, error) {
+	col := t.TenantColumn()
+	if col == "" {
+		return "", 0, nil
+	}
+	id, err := ctx.Tenant()
+	if err != nil {
+		return "", 0, err
+	}
+	if id == 0 {
+		return "", 0, e.New(e.ERROR_TENANT_REQUIRED, t.TableName())
+	}
+	if v, ok := values[col]; ok && fmt.Sprint(v) != fmt.Sprint(id) {
+		return "", 0, e.New(e.ERROR_FORBIDDEN_ROW).WithField(col)
+	}
+	return col, id, nil
+}
+
+// 主键在keys中, 多租户的表同时限制租户
+func keysCondition(quote func(string) string, t models2.Table, keys []interface{}, tenantColumn string, tenantID int64) (string, []interface{}) {
+	where := fmt.Sprintf("%s IN (%s)", quote(t.PrimaryKey()), placeholders(len(keys)))
+	args := append([]interface{}{}, keys...)
+	if tenantColumn != "" {
+		where += fmt.Sprintf(" AND %s = ?", quote(tenantColumn))
+		args = append(args, tenantID)
+	}
+	return where, args
+}
+
+// lockClause locks the rows of table selected in a transaction, empty for SQLite which locks the whole database on write.
+// PostgreSQL不能锁定外连接中可为NULL的一侧, 只锁定驱动表
+func lockClause(dialect, table string) string {
+	switch dialect {
+	case schema.MySQL:
+		return "FOR UPDATE"
+	case schema.Postgres:
+		return "FOR UPDATE OF " + table
+	}
+	return ""
+}
+
+func sortedColumns(row map[string]interface{}) []string {
+	columns := make([]string, 0, len(row))
+	for k := range row {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	models2 "github.com/go-bread/components/entity/models"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/setting"
+	"github.com/go-bread/pkg/tenant"
+)
+
+var course = models2.NewTenantTable("course", "tenant_id", nil)
+
+// tenantGroup joins the course table shared by tenants to the student table
+func tenantGroup() group.EntityGroup {
+	return group.EntityGroup{
+		JoinDriveTable: models2.Student,
+		Entities: map[string]interface{}{
+			"id": field.Field{Table: models2.Student, TableField: models2.Student.ID},
+			"course": map[string]interface{}{
+				"id": field.Field{Table: course, TableField: models2.TableField{Name: "id"}},
+			},
+		},
+	}
+}
+
+func tenantCaller(user int64, header string) *caller.Caller {
+	h := make(http.Header)
+	if header != "" {
+		h.Set(tenant.Header, header)
+	}
+	return caller.New(&auth.Identity{ID: 1, TenantID: user}, nil, "127.0.0.1", h)
+}
+
+func TestTenantFilter(t *testing.T) {
+	defer func(trust bool) { setting.TenantSetting.TrustHeader = trust }(setting.TenantSetting.TrustHeader)
+	setting.TenantSetting.TrustHeader = true
+
+	cases := []struct {
+		name   string
+		ctx    *caller.Caller
+		tables []string
+		id     int64 // 课程表条件的租户, 0为无条件
+		code   int
+	}{
+		{"user tenant", tenantCaller(7, ""), []string{"student", "course"}, 7, 0},
+		{"user tenant over the header", tenantCaller(7, "8"), []string{"course"}, 7, 0},
+		{"header tenant", tenantCaller(0, "8"), []string{"course"}, 8, 0},
+		{"no tenant", tenantCaller(0, ""), []string{"student", "course"}, 0, e.ERROR_TENANT_REQUIRED},
+		{"invalid header", tenantCaller(0, "-1"), []string{"course"}, 0, e.ERROR_INVALID_TENANT},
+		{"tables not shared", tenantCaller(0, ""), []string{"student"}, 0, 0},
+	}
+	for _, c := range cases {
+		f, err := newTenantFilter(c.ctx, tenantGroup(), c.tables)
+		if c.code != 0 {
+			if err == nil || e.As(err).Code != c.code {
+				t.Errorf("%s: got %v, want code %d", c.name, err, c.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if sql, _ := f.condition("student"); sql != "" {
+			t.Errorf("%s: student is filtered by %q", c.name, sql)
+		}
+		sql, args := f.condition("course")
+		if c.id == 0 {
+			if sql != "" {
+				t.Errorf("%s: course is filtered by %q", c.name, sql)
+			}
+			continue
+		}
+		if sql != "course.tenant_id = ?" || !reflect.DeepEqual(args, []interface{}{c.id}) {
+			t.Errorf("%s: %q %v, want tenant %d", c.name, sql, args, c.id)
+		}
+	}
+
+	setting.TenantSetting.TrustHeader = false
+	if f, err := newTenantFilter(tenantCaller(0, "8"), tenantGroup(), []string{"course"}); err == nil {
+		t.Errorf("the header is trusted: %+v", f)
+	}
+}
+
+func TestWriteTenant(t *testing.T) {
+	cases := []struct {
+		name   string
+		ctx    *caller.Caller
+		table  models2.Table
+		values map[string]interface{}
+		column string
+		code   int
+	}{
+		{"own tenant", tenantCaller(7, ""), course, map[string]interface{}{"tenant_id": int64(7)}, "tenant_id", 0},
+		{"tenant set", tenantCaller(7, ""), course, map[string]interface{}{"name": "a"}, "tenant_id", 0},
+		{"other tenant", tenantCaller(7, ""), course, map[string]interface{}{"tenant_id": int64(8)}, "", e.ERROR_FORBIDDEN_ROW},
+		{"no tenant", tenantCaller(0, ""), course, map[string]interface{}{}, "", e.ERROR_TENANT_REQUIRED},
+		{"table not shared", tenantCaller(0, ""), models2.Student, map[string]interface{}{"tenant_id": int64(8)}, "", 0},
+	}
+	for _, c := range cases {
+		col, id, err := writeTenant(c.ctx, c.table, c.values)
+		if c.code != 0 {
+			if err == nil || e.As(err).Code != c.code {
+				t.Errorf("%s: got %v, want code %d", c.name, err, c.code)
+			}
+			continue
+		}
+		if err != nil || col != c.column || (col != "" && id != 7) {
+			t.Errorf("%s: %q %d %v", c.name, col, id, err)
+		}
+	}
+}
+
+func TestKeysCondition(t *testing.T) {
+	quote := func(s string) string { return `"` + s + `"` }
+	where, args := keysCondition(quote, course, []interface{}{1, 2}, "tenant_id", 7)
+	if where != `"id" IN (?, ?) AND "tenant_id" = ?` || !reflect.DeepEqual(args, []interface{}{1, 2, int64(7)}) {
+		t.Errorf("%s %v", where, args)
+	}
+	where, args = keysCondition(quote, models2.Student, []interface{}{1}, "", 0)
+	if where != `"id" IN (?)` || !reflect.DeepEqual(args, []interface{}{1}) {
+		t.Errorf("%s %v", where, args)
+	}
+}
@@ -0,0 +1,227 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/validators/query"
+)
+
+// 同时执行的查询数, 避免一个批量请求占用过多的数据库连接
+const batchConcurrency = 4
+
+// RefKey marks a value of a query document taken from the result of an earlier query of the batch,
+// e.g. {"class_id": {"$ref": "classes.list.*.id"}}, * 取列表中每一项的值
+const RefKey = "$ref"
+
+// BatchQuery is a named query of a batch request, the query document is the same as /list/:form
+type BatchQuery struct {
+	Name    string          `json:"name" validate:"required,max=50"`
+	Form    string          `json:"form" validate:"required"`
+	Version string          `json:"version"`
+	Query   json.RawMessage `json:"query" validate:"required"`
+}
+
+// BatchResult is the result or the error of a query of a batch
+type BatchResult struct {
+	Data   interface{}
+	Err    error
+	Header http.Header // 查询的Deprecation及Warning响应头
+}
+
+// BatchGuard is called before each query of a batch with the caller of the query, e.g. to apply the rate limits
+type BatchGuard func(ctx *caller.Caller, q BatchQuery) error
+
+// QueryBatch runs the queries concurrently, the queries referencing earlier ones wait for them.
+// 查询名必须唯一, 由调用方校验; 引用的查询失败时该查询同样失败
+func QueryBatch(ctx *caller.Caller, fieldsMap group.FieldsMap, queries []BatchQuery, guard BatchGuard) []BatchResult {
+	b := &batch{
+		fieldsMap: fieldsMap,
+		queries:   queries,
+		guard:     guard,
+		index:     make(map[string]int, len(queries)),
+		results:   make([]BatchResult, len(queries)),
+		done:      make([]chan struct{}, len(queries)),
+		sem:       make(chan struct{}, batchConcurrency),
+	}
+	for i, q := range queries {
+		b.index[q.Name] = i
+		b.done[i] = make(chan struct{})
+	}
+
+	for i := range queries {
+		// 每个查询使用独立的副本, 分别记录额度及响应头
+		cp := ctx.Copy()
+		go func(i int, cp *caller.Caller) {
+			defer close(b.done[i])
+			defer func() {
+				if r := recover(); r != nil {
+					b.results[i] = BatchResult{Err: e.Wrap(e.ERROR, fmt.Errorf("panic: %v", r))}
+				}
+			}()
+			data, err := b.run(cp, i)
+			b.results[i] = BatchResult{Data: data, Err: err, Header: cp.Header}
+		}(i, cp)
+	}
+	for _, d := range b.done {
+		<-d
+	}
+
+	return b.results
+}
+
+type batch struct {
+	fieldsMap group.FieldsMap
+	queries   []BatchQuery
+	guard     BatchGuard
+	index     map[string]int
+	results   []BatchResult
+	done      []chan struct{} // 查询结束时关闭, 之后可以读取results中对应的结果
+	sem       chan struct{}
+}
+
+func (b *batch) run(ctx *caller.Caller, i int) (interface{}, error) {
+	q := b.queries[i]
+
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(q.Query))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return nil, e.New(e.INVALID_PARAMS, err.Error()).WithField("query")
+	}
+	// 引用的值为空列表时不会匹配任何数据, 不执行查询
+	empty := false
+	doc, err := resolveRefs(doc, func(ref string) (interface{}, error) {
+		v, err := b.resolve(i, ref)
+		if list, ok := v.([]interface{}); ok && len(list) == 0 {
+			empty = true
+		}
+		return v, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	version := q.Version
+	if version == "" {
+		version = ctx.Version
+	}
+	qp, err := query.ParseDocument(resolved, version)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return outputFields{List: []map[string]interface{}{}, Page: qp.Pagination}, nil
+	}
+
+	b.sem <- struct{}{}
+	defer func() { <-b.sem }()
+
+	if b.guard != nil {
+		if err := b.guard(ctx, q); err != nil {
+			return nil, err
+		}
+	}
+	return QueryAndFormatAll(ctx, b.fieldsMap, consts.EntityGroupName(q.Form), qp)
+}
+
+// resolve waits for the query referenced by ref and returns the value of its path
+func (b *batch) resolve(i int, ref string) (interface{}, error) {
+	path := strings.Split(ref, ".")
+	j, ok := b.index[path[0]]
+	if !ok || j >= i {
+		return nil, e.NewKey(e.INVALID_PARAMS, "batch.ref", ref).WithField(RefKey)
+	}
+	<-b.done[j]
+	if b.results[j].Err != nil {
+		return nil, e.New(e.ERROR_BATCH_DEPENDENCY, path[0]).WithField(RefKey)
+	}
+
+	// 结果转换为json的通用形式, 与客户端看到的结构一致
+	raw, err := json.Marshal(b.results[j].Data)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&result); err != nil {
+		return nil, err
+	}
+	v, ok := lookupPath(result, path[1:])
+	if !ok {
+		return nil, e.NewKey(e.INVALID_PARAMS, "batch.ref_path", ref).WithField(RefKey)
+	}
+	return v, nil
+}
+
+// resolveRefs replaces the objects {"$ref": "..."} of doc by the resolved values
+func resolveRefs(doc interface{}, resolve func(ref string) (interface{}, error)) (interface{}, error) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		if ref, ok := v[RefKey].(string); ok && len(v) == 1 {
+			return resolve(ref)
+		}
+		for k, item := range v {
+			r, err := resolveRefs(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	case []interface{}:
+		for k, item := range v {
+			r, err := resolveRefs(item, resolve)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	}
+	return doc, nil
+}
+
+// lookupPath returns the value of path such as list.0.id, * maps the rest of the path over a list
+func lookupPath(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		item, ok := vv[path[0]]
+		if !ok {
+			return nil, false
+		}
+		return lookupPath(item, path[1:])
+	case []interface{}:
+		if path[0] == "*" {
+			values := make([]interface{}, 0, len(vv))
+			for _, item := range vv {
+				r, ok := lookupPath(item, path[1:])
+				if !ok {
+					return nil, false
+				}
+				values = append(values, r)
+			}
+			return values, true
+		}
+		n, err := strconv.Atoi(path[0])
+		if err != nil || n < 0 || n >= len(vv) {
+			return nil, false
+		}
+		return lookupPath(vv[n], path[1:])
+	}
+	return nil, false
+}
@@ -0,0 +1,136 @@
+package entity
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+)
+
+func TestLookupPath(t *testing.T) {
+	var result interface{}
+	if err := json.Unmarshal([]byte(`{"list":[{"id":1,"class":{"id":7}},{"id":2,"class":{"id":8}}],"page_info":{"page":1}}`), &result); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{"list.*.id", []interface{}{1.0, 2.0}, true},
+		{"list.*.class.id", []interface{}{7.0, 8.0}, true},
+		{"list.1.id", 2.0, true},
+		{"page_info.page", 1.0, true},
+		{"list.2.id", nil, false},
+		{"list.-1.id", nil, false},
+		{"list.*.name", nil, false},
+		{"list.id", nil, false},
+		{"page_info.*", nil, false},
+	}
+	for _, c := range cases {
+		v, ok := lookupPath(result, strings.Split(c.path, "."))
+		if ok != c.found || !reflect.DeepEqual(v, c.value) {
+			t.Errorf("%s: %v %v, want %v %v", c.path, v, ok, c.value, c.found)
+		}
+	}
+}
+
+func TestResolveRefs(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{"fields":["id"],"class_id":{"$ref":"a.list.*.id"},"class":{"name":{"$ref":"b"}},"id":[{"$ref":"c"}],"sex":{"$ref":"d","x":1}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	got, err := resolveRefs(doc, func(ref string) (interface{}, error) { return "<" + ref + ">", nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"fields":   []interface{}{"id"},
+		"class_id": "<a.list.*.id>",
+		"class":    map[string]interface{}{"name": "<b>"},
+		"id":       []interface{}{"<c>"},
+		// 带其他键的对象不是引用
+		"sex": map[string]interface{}{"$ref": "d", "x": 1.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%v, want %v", got, want)
+	}
+
+	if _, err := resolveRefs(doc, func(string) (interface{}, error) { return nil, e.New(e.ERROR) }); err != nil {
+		t.Error("a resolved document is resolved again")
+	}
+}
+
+func TestBatchResolve(t *testing.T) {
+	b := &batch{
+		index:   map[string]int{"classes": 0, "broken": 1, "students": 2},
+		results: []BatchResult{{Data: outputFields{List: []map[string]interface{}{{"id": uint64(7)}, {"id": uint64(8)}}}}, {Err: e.New(e.ERROR)}, {}},
+		done:    []chan struct{}{make(chan struct{}), make(chan struct{}), make(chan struct{})},
+	}
+	for _, d := range b.done[:2] {
+		close(d)
+	}
+
+	v, err := b.resolve(2, "classes.list.*.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(v, []interface{}{json.Number("7"), json.Number("8")}) {
+		t.Errorf("%#v", v)
+	}
+
+	cases := []struct {
+		ref  string
+		code int
+		key  string
+	}{
+		{"nope.list", e.INVALID_PARAMS, "batch.ref"},
+		{"students.list", e.INVALID_PARAMS, "batch.ref"}, // 引用自身
+		{"broken.list.*.id", e.ERROR_BATCH_DEPENDENCY, ""},
+		{"classes.list.*.name", e.INVALID_PARAMS, "batch.ref_path"},
+	}
+	for _, c := range cases {
+		_, err := b.resolve(2, c.ref)
+		if err == nil {
+			t.Errorf("%s: no error", c.ref)
+			continue
+		}
+		if ee := e.As(err); ee.Code != c.code || ee.Key != c.key || ee.Field != RefKey {
+			t.Errorf("%s: %d %s at %s, want %d %s", c.ref, ee.Code, ee.Key, ee.Field, c.code, c.key)
+		}
+	}
+}
+
+// 引用失败的查询或之后的查询时报错, 不执行查询
+func TestQueryBatchRefs(t *testing.T) {
+	queries := []BatchQuery{
+		{Name: "missing", Form: "nope", Query: json.RawMessage(`{"fields":["id"]}`)},
+		{Name: "dependent", Form: "nope", Query: json.RawMessage(`{"fields":["id"],"class_id":{"$ref":"missing.list.*.id"}}`)},
+		{Name: "forward", Form: "nope", Query: json.RawMessage(`{"fields":["id"],"class_id":{"$ref":"later.list.*.id"}}`)},
+		{Name: "later", Form: "nope", Query: json.RawMessage(`{"fields":["id"]}`)},
+	}
+	var guarded int32
+	guard := func(*caller.Caller, BatchQuery) error {
+		atomic.AddInt32(&guarded, 1)
+		return nil
+	}
+	results := QueryBatch(caller.New(nil, nil, "", nil), group.FieldsMap{}, queries, guard)
+
+	if results[0].Err == nil {
+		t.Error("the query of an unknown form succeeded")
+	}
+	if err := results[1].Err; err == nil || e.As(err).Code != e.ERROR_BATCH_DEPENDENCY {
+		t.Errorf("dependent: %v", err)
+	}
+	if err := results[2].Err; err == nil || e.As(err).Key != "batch.ref" {
+		t.Errorf("forward: %v", err)
+	}
+	if guarded != 2 {
+		t.Errorf("guard called %d times, want 2", guarded)
+	}
+}
@@ -2,17 +2,24 @@ package entity
 
 import (
 	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
 	"github.com/go-bread/components/entity/field/views"
-	"github.com/pkg/errors"
 
 	"github.com/go-bread/components/database"
+	"github.com/go-bread/components/database/condition"
 	outputs "github.com/go-bread/components/database/output"
 	"github.com/go-bread/components/entity/field"
 	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
 	"github.com/go-bread/consts"
 	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/ratelimit"
 	"github.com/go-bread/validators/query"
-	"github.com/gin-gonic/gin"
 )
 
 type outputFields struct {
@@ -29,12 +36,12 @@ var (
 )
 
 const (
-	SceneUpdate = "update"
-	SceneQuery  = "query"
-	SceneCreate = "create"
+	SceneUpdate = group.SceneUpdate
+	SceneQuery  = group.SceneQuery
+	SceneCreate = group.SceneCreate
 )
 
-func QueryAndFormatOne(ctx *gin.Context, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams) (map[string]interface{}, error) {
+func QueryAndFormatOne(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams) (map[string]interface{}, error) {
 	data, err := parseAndQueryAll(ctx, fieldsMap, gn, &params)
 	if err != nil {
 		return nil, err
@@ -48,146 +55,390 @@ func QueryAndFormatOne(ctx *gin.Context, fieldsMap group.FieldsMap, gn consts.En
 	return data[0], nil
 }
 
-func QueryAndFormatAll(ctx *gin.Context, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams) (interface{}, error) {
-	data, err := parseAndQueryAll(ctx, fieldsMap, gn, &params)
+func QueryAndFormatAll(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams) (interface{}, error) {
+	data, page, err := QueryAll(ctx, fieldsMap, gn, params)
 	if err != nil {
 		return nil, err
 	}
 
-	var r outputFields
-	if data == nil {
-		r.List = []map[string]interface{}{}
-	} else {
-		for _, v := range data {
-			dealValue(v)
-		}
-		r.List = data
+	return outputFields{List: data, Page: page}, nil
+}
+
+// QueryAll returns the formatted rows of the query and its pagination including the total count
+func QueryAll(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams) ([]map[string]interface{}, query.Pagination, error) {
+	data, err := parseAndQueryAll(ctx, fieldsMap, gn, &params)
+	if err != nil {
+		return nil, params.Pagination, err
 	}
-	r.Page = params.Pagination
 
-	return r, nil
+	if data == nil {
+		data = []map[string]interface{}{}
+	}
+	for _, v := range data {
+		dealValue(v)
+	}
+	return data, params.Pagination, nil
 }
 
-func parseAndQueryAll(ctx *gin.Context, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params *query.QParams) ([]map[string]interface{}, error) {
-	fm, ok := fieldsMap[gn]
-
-	if !ok {
-		return nil, 	errors.New("invalid group")
+func parseAndQueryAll(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params *query.QParams) ([]map[string]interface{}, error) {
+	fm, err := loadGroup(ctx, fieldsMap, gn, params.Version)
+	if err != nil {
+		return nil, err
 	}
-	if !fm.Initialized() {
-		fm.Init()
-		fieldsMap[gn] = fm
+
+	// 时间按请求的时区解析
+	loc, err := ctx.Location()
+	if err != nil {
+		return nil, err
 	}
 
 	// 参数校验
-	var queryParams []validatorIface.Condition
-	queryParams, err := ValidateAndBuildParams(queryParams, SceneQuery, fm.Entities, params.QFields)
+	queryParams, values, err := buildParams(nil, SceneQuery, loc, fm, params.QFields)
 	if err != nil {
 		return nil, err
 	}
 
+	// 大表必须包含的查询条件, 在生成sql之前检查
+	if err := fm.CheckPolicies(values); err != nil {
+		return nil, err
+	}
+
 	// 输出字段校验及构建
 	outputFields, err := ValidateAndBuildOutputs(fm, params.ReturnFields)
 	if err != nil {
 		return nil, err
 	}
 
+	// 全文搜索, 需要能查询所有搜索的字段, 且字段未脱敏
+	search := database.NewSearch(fm, params.Search)
+	searchFields, ok := fm.SearchFields()
+	if !ok && params.Search != "" {
+		return nil, e.New(e.ERROR_SEARCH_NOT_SUPPORTED, gn).WithField("_search")
+	}
+	if search != nil {
+		for _, f := range searchFields {
+			if !fm.Allowed(f, field.AccessFilter) {
+				return nil, e.New(e.ERROR_FORBIDDEN_FIELD).WithField("_search")
+			}
+			if fm.Masked(f) {
+				return nil, e.New(e.ERROR_FIELD_MASKED).WithField("_search")
+			}
+		}
+	}
+
 	// order by 处理
-	orders, err := validateAndBuildOrders(fm, params.Orders)
+	orders, err := validateAndBuildOrders(fm, params.Orders, search != nil)
 	if err != nil {
 		return nil, err
 	}
 
-	// 执行查询
-	q, err := database.QueryAndFormat(ctx, fieldsMap[gn], queryParams, outputFields, &params.Pagination, orders)
+	paths := filterPaths(fm, params.QFields)
+	for _, o := range outputFields {
+		paths = append(paths, o.OutPut)
+	}
+	for _, o := range params.Orders {
+		paths = append(paths, o[0])
+	}
+	warnDeprecated(ctx, fm, paths)
+
+	// 执行查询, 返回的行数不超过预留的额度
+	settle, err := reserveRows(ctx, &params.Pagination)
 	if err != nil {
 		return nil, err
 	}
+	q, err := database.QueryAndFormat(ctx, fm, queryParams, outputFields, &params.Pagination, orders, search)
+	if err != nil {
+		settle(0)
+		return nil, err
+	}
+	if err := settle(len(q)); err != nil {
+		return nil, err
+	}
 
 	return q, nil
 }
 
+// reserveRows reserves the rows of a query from the row budget of the request, a page or all the rows left,
+// and caps the query at one row more than reserved to detect a result exceeding them.
+// settle returns the rows not used, a result exceeding the reserved rows is rejected
+func reserveRows(ctx *caller.Caller, p *query.Pagination) (settle func(rows int) error, err error) {
+	settle = func(int) error { return nil }
+	b := ctx.Budget
+	if b == nil {
+		return settle, nil
+	}
+	want := 0
+	if p.Page != 0 {
+		want = int(p.PageSize)
+	}
+	n, wait, err := b.Reserve(want)
+	if err != nil {
+		// 存储出错时不限制
+		log.Printf("ratelimit: reserve rows: %v", err)
+		return settle, nil
+	}
+	if n == 0 {
+		return nil, rowQuota(ctx, wait)
+	}
+	p.MaxRows = uint32(n + 1)
+
+	return func(rows int) error {
+		if rows > n {
+			release(b, n)
+			// 已预留全部额度时等待也无法返回
+			if wait == 0 {
+				return e.NewKey(e.ERROR_ROW_QUOTA, "row_quota.page", n)
+			}
+			return rowQuota(ctx, wait)
+		}
+		release(b, n-rows)
+		return nil
+	}, nil
+}
+
+func release(b *ratelimit.Budget, n int) {
+	if err := b.Release(n); err != nil {
+		log.Printf("ratelimit: release rows: %v", err)
+	}
+}
+
+func rowQuota(ctx *caller.Caller, wait time.Duration) error {
+	seconds := ratelimit.RetryAfter(wait)
+	ctx.Header.Set("Retry-After", strconv.Itoa(seconds))
+	return e.New(e.ERROR_ROW_QUOTA, seconds)
+}
+
+// InitGroups initializes all the groups of fieldsMap before serving,
+// 之后loadGroup不再写入fieldsMap, 并发的请求及批量查询可以安全地读取
+func InitGroups(fieldsMap group.FieldsMap) {
+	for gn, fm := range fieldsMap {
+		if !fm.Initialized() {
+			initGroup(gn, &fm)
+			fieldsMap[gn] = fm
+		}
+	}
+}
+
+// initGroup adds the name of the group to the panic of an invalid declaration
+func initGroup(gn consts.EntityGroupName, fm *group.EntityGroup) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("entity group %s: %v", gn, r))
+		}
+	}()
+	fm.Init()
+}
+
+// loadGroup returns the group of version as used by the logged in user
+func loadGroup(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, version string) (group.EntityGroup, error) {
+	fm, ok := fieldsMap[gn]
+
+	if !ok {
+		return fm, e.New(e.ERROR_NOT_EXIST_GROUP, gn)
+	}
+	if !fm.Initialized() {
+		fm.Init()
+		fieldsMap[gn] = fm
+	}
+
+	fm, err := fm.ForVersion(version)
+	if err == group.ErrInvalidVersion {
+		return fm, e.New(e.ERROR_INVALID_VERSION, version)
+	} else if err != nil {
+		return fm, err
+	}
+
+	// 字段权限按登录用户检查
+	return fm.WithIdentity(ctx.Identity), nil
+}
+
 // validate input params and build db params
-func ValidateAndBuildParams(queryParams []validatorIface.Condition, scene string, groupFields map[string]interface{}, params map[string]interface{}) ([]validatorIface.Condition, error) {
-	for p, v := range params {
-		f, ok := groupFields[p]
+func ValidateAndBuildParams(queryParams []validatorIface.Condition, scene string, loc *time.Location, g group.EntityGroup, params map[string]interface{}) ([]validatorIface.Condition, error) {
+	queryParams, _, err := buildParams(queryParams, scene, loc, g, params)
+	return queryParams, err
+}
+
+// buildParams also returns the coerced values keyed by field path
+func buildParams(queryParams []validatorIface.Condition, scene string, loc *time.Location, g group.EntityGroup, params map[string]interface{}) ([]validatorIface.Condition, map[string]interface{}, error) {
+	flat := make(map[string]interface{})
+	if err := flattenParams(g.Entities, params, "", flat); err != nil {
+		return nil, nil, err
+	}
+	paths := make([]string, 0, len(flat))
+	for p := range flat {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	values := make(map[string]interface{})
+	for _, p := range paths {
+		ff, _ := g.Field(p)
+		ff.InputField = p
+		ff.SetLocation(loc)
+		if err := checkParamAccess(g, ff, scene); err != nil {
+			return nil, nil, err
+		}
+		v, err := ff.TableField.Coerce(flat[p], loc)
+		if err != nil {
+			return nil, nil, e.AsInvalid(err).WithField(p)
+		}
+		err = validateFieldValue(v, ff)
+		if err != nil {
+			return nil, nil, err
+		}
+		// 使用sql模板的字段, 值的类型需要与模板的占位符一致
+		if ff.Template != "" && ff.CanQuery {
+			if _, err := ff.TemplateCondition(v); err != nil {
+				return nil, nil, e.Invalid("value.template").WithField(p)
+			}
+		}
+		if !ff.CanQuery {
+			continue
+		}
+		if v != nil {
+			values[p] = v
+		}
+		conds := ff.TransferCondition(v, flat)
+		if scene == SceneQuery && g.Masked(ff) {
+			if err := checkMaskedConditions(ff, conds); err != nil {
+				return nil, nil, err
+			}
+		}
+		queryParams = append(queryParams, conds...)
+	}
+
+	// 字段组合规则在单个字段校验通过后执行
+	if err := g.CheckRules(scene, values); err != nil {
+		return nil, nil, err
+	}
+
+	// 行级权限: 查询及更新时与客户端的条件AND, 写入的值必须在用户可以访问的范围内
+	if scene != SceneQuery {
+		if err := g.CheckScopes(scene, values); err != nil {
+			return nil, nil, err
+		}
+	}
+	if scene != SceneCreate {
+		scopes, err := g.ScopeConditions()
+		if err != nil {
+			return nil, nil, err
+		}
+		queryParams = append(queryParams, scopes...)
+	}
+
+	return queryParams, values, nil
+}
+
+// 查询时检查查询权限, 写入时检查写入权限以及表字段是否可写
+func checkParamAccess(g group.EntityGroup, f field.Field, scene string) error {
+	if scene == SceneQuery {
+		if !g.Allowed(f, field.AccessFilter) {
+			return e.New(e.ERROR_FORBIDDEN_FIELD).WithField(f.InputField)
+		}
+		return nil
+	}
+	if f.TableField.Permission != models.ReadWrite {
+		return e.New(e.ERROR_FIELD_READ_ONLY).WithField(f.InputField)
+	}
+	if !g.Allowed(f, field.AccessWrite) {
+		return e.New(e.ERROR_FORBIDDEN_FIELD).WithField(f.InputField)
+	}
+	return nil
+}
+
+// 脱敏的字段只能按完整的值查询, 避免通过like或范围条件推测原始值
+func checkMaskedConditions(f field.Field, conds []validatorIface.Condition) error {
+	for _, c := range conds {
+		if c.GetSql() != "" || (c.Operator() != condition.OpEqual && c.Operator() != condition.OpIn) {
+			return e.New(e.ERROR_FIELD_MASKED).WithField(f.InputField)
+		}
+	}
+	return nil
+}
+
+// flattenParams names the params by field path, {"class": {"name": "a"}} is the same as {"class.name": "a"}
+func flattenParams(entities map[string]interface{}, params map[string]interface{}, prefix string, flat map[string]interface{}) error {
+	for k, v := range params {
+		path := prefix + k
 		// 检测字段是否存在
+		ent, ok := group.Lookup(entities, k)
 		if !ok {
-			return nil, errors.New("invalid params, "+ p+"字段不存在")
+			return e.New(e.ERROR_NOT_EXIST_FIELD).WithField(path)
 		}
 
-		if ff, ok := f.(field.Field); ok {
-			ff.InputField = p
-			err := validateFieldValue(v, ff)
-			if err != nil {
-				return nil, err
+		switch ent := ent.(type) {
+		case field.Field:
+			if _, ok := flat[path]; ok {
+				return e.Invalid("value.duplicate").WithField(path)
 			}
-			if !ff.CanQuery {
-				continue
-			}
-			queryParams = append(queryParams, ff.TransferCondition(v, params)...)
-		} else if ff, ok := f.(map[string]interface{}); ok {
+			flat[path] = v
+		case map[string]interface{}:
 			vv, ok := v.(map[string]interface{})
 			if !ok {
-				return nil, errors.New("invalid params, " + p + "实体不存在")
+				return e.Invalid("value.object", e.TypeName(v)).WithField(path)
 			}
-
-			return ValidateAndBuildParams(queryParams, scene, ff, vv)
-		} else {
-			panic("wrong fields map: key " + p)
+			if err := flattenParams(ent, vv, path+group.Separator, flat); err != nil {
+				return err
+			}
+		default:
+			panic("wrong fields map: key " + path)
 		}
 	}
-
-	return queryParams, nil
+	return nil
 }
 
+// fields may name a namespace such as class, which outputs all of its fields
 func ValidateAndBuildOutputs(g group.EntityGroup, fields []string) ([]*outputs.OutputField, error) {
 	var ops []*outputs.OutputField
 	// 用于字段去重
 	fp := make(map[string]struct{})
-	for _, k := range fields {
+	add := func(k string, ff field.Field) {
 		if _, ok := fp[k]; ok {
-			continue
+			return
+		}
+		// 命名空间中无权读取的字段不输出
+		if !g.Allowed(ff, field.AccessRead) {
+			return
 		}
 		fp[k] = struct{}{}
-
-		f, ok := g.Entities[k]
-		if !ok {
-			return nil, errors.New("invalid field, " + fmt.Sprintf("字段%s不存在", k))
+		var mask *outputs.Mask
+		if g.Masked(ff) {
+			mask = ff.Mask
 		}
+		ops = append(ops, &outputs.OutputField{
+			TableField: ff.TableField.Name,
+			Table:      ff.Table.TableName(),
+			OutPut:     k,
+			F:          ff.Callback,
+			TimeFormat: ff.TimeFormat,
+			Mask:       mask,
+		})
+	}
 
-		if ff, ok := f.(field.Field); ok {
-			ops = append(ops, &outputs.OutputField{
-				TableField: ff.TableField.Name,
-				Table:      ff.Table.TableName(),
-				OutPut:     k,
-				F:          ff.Callback,
-			})
+	for _, k := range fields {
+		f, ok := g.Lookup(k)
+		if !ok {
+			return nil, e.New(e.ERROR_NOT_EXIST_FIELD).WithField(k)
 		}
 
-		if ff, ok := f.(map[string]field.Field); ok {
-			_, ok := ff[k]
-			if !ok {
-				return nil, errors.New("invalid field, " + fmt.Sprintf("字段%s不存在", k))
-			}
-
-			if ff, ok := f.(field.Field); ok {
-				ops = append(ops, &outputs.OutputField{
-					TableField: ff.TableField.Name,
-					Table:      ff.Table.TableName(),
-					OutPut:     k,
-					F:          ff.Callback,
-				})
+		switch f := f.(type) {
+		case field.Field:
+			if !g.Allowed(f, field.AccessRead) {
+				return nil, e.New(e.ERROR_FORBIDDEN_FIELD).WithField(k)
 			}
+			add(k, f)
+		case map[string]interface{}:
+			group.WalkEntities(f, k+group.Separator, add)
 		}
 	}
 	return ops, nil
 }
 
 // 构建order by
-func validateAndBuildOrders(g group.EntityGroup, orders [][2]string) ([][2]string, error) {
+// _relevance 只能在提供了 _search 时使用, 未指定排序时使用实体组的默认排序
+func validateAndBuildOrders(g group.EntityGroup, orders [][2]string, search bool) ([][2]string, error) {
 	if len(orders) == 0 {
-		return [][2]string{}, nil
+		return defaultOrders(g), nil
 	}
 	var formatedOrders [][2]string
 	// 用于字段去重
@@ -198,46 +449,124 @@ func validateAndBuildOrders(g group.EntityGroup, orders [][2]string) ([][2]strin
 		}
 		fp[k[0]] = struct{}{}
 
-		f, ok := g.Entities[k[0]]
+		if _, ok := g.SearchFields(); ok && k[0] == group.RelevanceField {
+			if !search {
+				return nil, e.NewKey(e.ERROR_INVALID_ORDER, "order.relevance").WithField(k[0])
+			}
+			formatedOrders = append(formatedOrders, k)
+			continue
+		}
+
+		ff, ok := g.Field(k[0])
 		if !ok {
-			return nil, errors.New("invalid field, " + fmt.Sprintf("排序字段%s不存在", k[0]))
+			return nil, e.New(e.ERROR_NOT_EXIST_FIELD).WithField(k[0])
 		}
 
-		if ff, ok := f.(field.Field); ok {
-			if !ff.CanOrder {
-				return nil, errors.New("invalid field, " + fmt.Sprintf("不能使用字段%s进行排序", k[0]))
-			}
-			formatedOrders = append(formatedOrders, [2]string{
-				fmt.Sprintf("%s.%s", ff.Table.TableName(), ff.TableField.Name),
-				k[1],
-			})
+		if !ff.CanOrder {
+			return nil, e.New(e.ERROR_FIELD_NOT_ORDERABLE).WithField(k[0])
 		}
+		if !g.Allowed(ff, field.AccessOrder) {
+			return nil, e.New(e.ERROR_FORBIDDEN_FIELD).WithField(k[0])
+		}
+		if g.Masked(ff) {
+			return nil, e.New(e.ERROR_FIELD_MASKED).WithField(k[0])
+		}
+		formatedOrders = append(formatedOrders, [2]string{
+			fmt.Sprintf("%s.%s", ff.Table.TableName(), ff.TableField.Name),
+			k[1],
+		})
 	}
 	return formatedOrders, nil
 }
 
+// 默认排序中无权排序及脱敏的字段直接忽略
+func defaultOrders(g group.EntityGroup) [][2]string {
+	orders := [][2]string{}
+	for _, o := range g.DefaultOrder {
+		ff, ok := g.Field(o[0])
+		if !ok || !ff.CanOrder || !g.Allowed(ff, field.AccessOrder) || g.Masked(ff) {
+			continue
+		}
+		orders = append(orders, [2]string{
+			fmt.Sprintf("%s.%s", ff.Table.TableName(), ff.TableField.Name),
+			o[1],
+		})
+	}
+	return orders
+}
+
 // use field.Field.Validators to validate the value
 func validateFieldValue(v interface{}, f field.Field) error {
 	if !f.CanQuery {
-		return errors.New("invalid field, " + fmt.Sprintf("%s字段不能作为查询条件", f.InputField))
+		return e.New(e.ERROR_FIELD_NOT_QUERYABLE).WithField(f.InputField)
 	}
 
-	if f.Validator == nil {
+	validator := f.GetValidator()
+	if validator == nil {
+		if m, ok := v.(map[string]interface{}); ok {
+			return validateOperators(m, f)
+		}
 		return nil
 	}
 
-	err := f.Validator.Validate(v)
+	err := validator.Validate(v)
 	if err != nil {
-		return err
+		return e.AsInvalid(err).WithField(f.InputField)
 	}
 
 	return nil
 }
 
+// 未声明校验规则时, 对象形式的值只能使用字段允许的操作符
+func validateOperators(m map[string]interface{}, f field.Field) error {
+	if len(m) == 0 {
+		return e.Invalid("value.empty_condition").WithField(f.InputField)
+	}
+	allowed := make(map[string]struct{})
+	for _, op := range f.Operators() {
+		allowed[op] = struct{}{}
+	}
+	for k := range m {
+		op, ok := condition.OperatorByKey(k)
+		if _, allow := allowed[op]; !ok || !allow {
+			return e.Invalid("value.operator", k).WithField(f.InputField)
+		}
+	}
+	return nil
+}
+
+// 使用了当前版本中已废弃的字段时, 通过Warning响应头提示调用方; paths为解析后的字段路径, 包括别名及嵌套对象中的字段
+func warnDeprecated(ctx *caller.Caller, g group.EntityGroup, paths []string) {
+	sort.Strings(paths)
+	for i, p := range paths {
+		if i > 0 && paths[i-1] == p {
+			continue
+		}
+		if msg, ok := g.Deprecation(p); ok {
+			ctx.Header.Set("Deprecation", "true")
+			ctx.Header.Add("Warning", fmt.Sprintf("299 - %q", fmt.Sprintf("field %s is deprecated: %s", p, msg)))
+		}
+	}
+}
+
+// filterPaths returns the paths of the fields of filters, the objects of namespaces are expanded
+func filterPaths(g group.EntityGroup, filters map[string]interface{}) []string {
+	flat := make(map[string]interface{})
+	// 条件已校验, 不会出错
+	_ = flattenParams(g.Entities, filters, "", flat)
+	paths := make([]string, 0, len(flat))
+	for p := range flat {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
 func dealValue(m map[string]interface{}) {
 	for k, v := range m {
 		if v == nil {
 			delete(m, k)
+		} else if ns, ok := v.(map[string]interface{}); ok {
+			dealValue(ns)
 		}
 	}
 }
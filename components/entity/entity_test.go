@@ -0,0 +1,100 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/go-bread/components/database/condition"
+	"github.com/go-bread/components/entity/field"
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/ratelimit"
+	"github.com/go-bread/validators/query"
+)
+
+func TestReserveRows(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 1.0 / 3600, Burst: 10}
+	cases := []struct {
+		name     string
+		left     int // 查询前的额度
+		page     query.Pagination
+		rows     int
+		maxRows  uint32
+		code     int // reserveRows 或 settle 的错误码
+		leftOver int // settle后的额度
+	}{
+		{"page", 10, query.Pagination{Page: 1, PageSize: 4}, 3, 5, 0, 7},
+		{"all rows", 10, query.Pagination{}, 10, 11, 0, 0},
+		{"page larger than left", 3, query.Pagination{Page: 1, PageSize: 5}, 3, 4, 0, 0},
+		{"more rows than left", 3, query.Pagination{Page: 1, PageSize: 5}, 4, 4, e.ERROR_ROW_QUOTA, 3},
+		{"more rows than all", 10, query.Pagination{}, 11, 11, e.ERROR_ROW_QUOTA, 10},
+		{"quota used", 0, query.Pagination{Page: 1, PageSize: 5}, 0, 0, e.ERROR_ROW_QUOTA, 0},
+	}
+	for _, c := range cases {
+		store := ratelimit.NewMemoryStore()
+		if _, err := store.Take("rows", limit, float64(10-c.left), true); err != nil {
+			t.Fatal(err)
+		}
+		ctx := caller.New(nil, nil, "", nil)
+		ctx.Budget = ratelimit.NewBudget(store, "rows", limit)
+
+		p := c.page
+		settle, err := reserveRows(ctx, &p)
+		if err == nil {
+			err = settle(c.rows)
+		}
+		if c.code == 0 && err != nil || c.code != 0 && (err == nil || e.As(err).Code != c.code) {
+			t.Errorf("%s: got %v, want code %d", c.name, err, c.code)
+			continue
+		}
+		if p.MaxRows != c.maxRows {
+			t.Errorf("%s: limit %d, want %d", c.name, p.MaxRows, c.maxRows)
+		}
+		if left, _, _ := ctx.Budget.Reserve(0); left != c.leftOver {
+			t.Errorf("%s: %d rows left, want %d", c.name, left, c.leftOver)
+		}
+		if c.code != 0 && c.left < 10 && ctx.Header.Get("Retry-After") == "" {
+			t.Errorf("%s: no Retry-After", c.name)
+		}
+	}
+
+	ctx := caller.New(nil, nil, "", nil)
+	p := query.Pagination{Page: 1, PageSize: 5}
+	if settle, err := reserveRows(ctx, &p); err != nil || settle(100) != nil || p.MaxRows != 0 {
+		t.Error("the rows of a request without a budget are limited")
+	}
+}
+
+var phonePrefix = condition.RegisterTemplate("test.phone_prefix", "{field} LIKE {prefix:string}")
+
+func TestCheckMaskedConditions(t *testing.T) {
+	f := field.Field{InputField: "phone"}
+	prefix, err := phonePrefix.Param("student", "phone", map[string]interface{}{"prefix": "138%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name  string
+		conds []validatorIface.Condition
+		ok    bool
+	}{
+		{"equal", []validatorIface.Condition{condition.NewQueryParam("student", "phone", condition.OpEqual, "1")}, true},
+		{"in", []validatorIface.Condition{condition.NewQueryParam("student", "phone", condition.OpIn, []interface{}{"1"})}, true},
+		{"like", []validatorIface.Condition{condition.NewQueryParam("student", "phone", "like", "%1%")}, false},
+		{"range", []validatorIface.Condition{
+			condition.NewQueryParam("student", "phone", condition.OpEqual, "1"),
+			condition.NewQueryParam("student", "phone", ">", "1"),
+		}, false},
+		{"template", []validatorIface.Condition{prefix}, false},
+	}
+	for _, c := range cases {
+		err := checkMaskedConditions(f, c.conds)
+		if c.ok != (err == nil) {
+			t.Errorf("%s: got %v", c.name, err)
+			continue
+		}
+		if err != nil && (e.As(err).Code != e.ERROR_FIELD_MASKED || e.As(err).Field != "phone") {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+	}
+}
@@ -1,30 +1,131 @@
 package field
 
 import (
+	"reflect"
+	"sort"
+	"time"
+
 	"github.com/go-bread/components/database/condition"
+	outputs "github.com/go-bread/components/database/output"
 	"github.com/go-bread/components/entity/models"
 	"github.com/go-bread/iface/entity_query"
 	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/validators/rule"
 )
 
 type Field struct {
-	Table      models.Table
-	TableField models.TableField
-	Validator  validatorIface.Validator
-	CanQuery   bool // 是否可以用来做查询
-	CanOrder   bool // 是否可以用来排序
-	InputField string
-	Callback   entity_query.CallbackFunc
+	Table       models.Table
+	TableField  models.TableField
+	Validator   validatorIface.Validator
+	Rule        string // 校验规则, 如 "int,min=1,max=100", 未设置Validator时生效
+	CanQuery    bool   // 是否可以用来做查询
+	CanOrder    bool   // 是否可以用来排序
+	InputField  string
+	Callback    entity_query.CallbackFunc
+	TimeFormat  string        // 时间的输出格式, 如 outputs.TimeISO8601, 默认为 outputs.DefaultTimeLayout
+	Permissions Permissions   // 读取, 查询, 排序及写入需要的权限
+	Template    string        // 查询条件使用的sql模板名, 见 condition.RegisterTemplate, 查询的值为占位符value
+	Mask        *outputs.Mask // 输出时的脱敏方式, 拥有Permissions.Unmask的用户不脱敏
 }
 
+// TemplateValue is the placeholder of the query value in the template of a field
+const TemplateValue = "value"
+
 func (f *Field) TransferCondition(v interface{}, params map[string]interface{}) []validatorIface.Condition {
 	if !f.CanQuery {
 		return []validatorIface.Condition{}
 	}
+	if f.Template != "" {
+		return []validatorIface.Condition{f.mustTemplateCondition(v)}
+	}
 
-	if f.Validator == nil {
+	validator := f.GetValidator()
+	if validator == nil {
+		// 对象形式, 如时间范围 {"gte": from, "lt": to}
+		if m, ok := v.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			conditions := make([]validatorIface.Condition, 0, len(keys))
+			for _, k := range keys {
+				op, _ := condition.OperatorByKey(k)
+				conditions = append(conditions, condition.NewQueryParam(f.Table.TableName(), f.TableField.Name, op, m[k]))
+			}
+			return conditions
+		}
 		return []validatorIface.Condition{condition.NewDefaultQueryParam(f.Table.TableName(), f.TableField.Name, v)}
 	}
 
-	return f.Validator.TransferCondition(f.Table.TableName(), f.TableField.Name, v, params)
+	return validator.TransferCondition(f.Table.TableName(), f.TableField.Name, v, params)
+}
+
+// TemplateCondition builds the condition of a field declaring a Template, the error reports a value not matching the placeholder type
+func (f *Field) TemplateCondition(v interface{}) (validatorIface.Condition, error) {
+	t, ok := condition.LookupTemplate(f.Template)
+	if !ok {
+		panic("field " + f.TableField.Name + ": unknown template " + f.Template)
+	}
+	return t.Param(f.Table.TableName(), f.TableField.Name, map[string]interface{}{TemplateValue: v})
+}
+
+// 调用前已通过TemplateCondition检查值的类型
+func (f *Field) mustTemplateCondition(v interface{}) validatorIface.Condition {
+	cond, err := f.TemplateCondition(v)
+	if err != nil {
+		panic(err)
+	}
+	return cond
+}
+
+// GetValidator returns Validator, or the validator built from Rule
+func (f *Field) GetValidator() validatorIface.Validator {
+	if f.Validator != nil {
+		return f.Validator
+	}
+	if f.Rule != "" {
+		return rule.MustParse(f.Rule)
+	}
+	return nil
+}
+
+// SetLocation makes the validator of the field parse times in loc, the timezone of the request
+func (f *Field) SetLocation(loc *time.Location) {
+	if l, ok := f.GetValidator().(validatorIface.Localizer); ok && loc != nil {
+		f.Validator = l.InLocation(loc)
+	}
+}
+
+// Operators lists the condition operators the field accepts as a filter
+func (f *Field) Operators() []string {
+	if !f.CanQuery {
+		return nil
+	}
+	if f.Template != "" {
+		return []string{condition.OpEqual}
+	}
+
+	if ol, ok := f.GetValidator().(validatorIface.OperatorLister); ok {
+		return ol.Operators()
+	}
+
+	// 未声明校验规则的时间字段可以按范围查询
+	if f.TableField.IsTime {
+		return []string{condition.OpEqual, condition.OpIn, ">", ">=", "<", "<="}
+	}
+	return []string{condition.OpEqual, condition.OpIn}
+}
+
+// OutputKind is the kind of the value returned for the field, times are formatted as strings or unix numbers
+func (f *Field) OutputKind() reflect.Kind {
+	if !f.TableField.IsTime {
+		return f.TableField.Type
+	}
+	switch f.TimeFormat {
+	case outputs.TimeUnix, outputs.TimeUnixMilli:
+		return reflect.Int64
+	default:
+		return reflect.String
+	}
 }
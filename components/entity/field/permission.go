@@ -0,0 +1,46 @@
+package field
+
+// 字段的使用方式
+const (
+	AccessRead   = "read"
+	AccessFilter = "filter"
+	AccessOrder  = "order"
+	AccessWrite  = "write"
+)
+
+// Permissions lists the permissions required to use a field, the caller needs any one of a list.
+// An entry is a permission name such as student:read, or a role prefixed by role:, such as role:admin.
+// Filter and Order default to Read, an empty Read allows everyone.
+//
+// 如性别只有拥有student:sensitive权限或管理员角色的用户可以读取和查询:
+//
+//	"sex": field.Field{
+//		Table:       models.Student,
+//		TableField:  models.Student.Sex,
+//		CanQuery:    true,
+//		Permissions: field.Permissions{Read: []string{"student:sensitive", auth.RolePrefix + "admin"}},
+//	},
+type Permissions struct {
+	Read   []string
+	Filter []string
+	Order  []string
+	Write  []string
+	Unmask []string // 查看声明了Mask的字段的原始值, 为空时所有人都只能看到脱敏后的值
+}
+
+// Required returns the permissions required for access
+func (p Permissions) Required(access string) []string {
+	switch access {
+	case AccessFilter:
+		if len(p.Filter) > 0 {
+			return p.Filter
+		}
+	case AccessOrder:
+		if len(p.Order) > 0 {
+			return p.Order
+		}
+	case AccessWrite:
+		return p.Write
+	}
+	return p.Read
+}
@@ -8,6 +8,9 @@ import (
 
 var (
 	Class = group.EntityGroup{
+		Search: &group.Search{
+			Fields: []string{"class_name"},
+		},
 		Entities: map[string]interface{}{
 			"id": field.Field{
 				Table:      models.Class,
@@ -3,20 +3,21 @@ package views
 import (
 	"github.com/go-bread/components/entity/field"
 	"github.com/go-bread/components/entity/group"
-	"time"
 
-	"github.com/gin-gonic/gin"
 	"github.com/go-bread/components/entity/models"
-	"github.com/go-bread/iface/entity_query"
 )
 
 var (
 	Student = group.EntityGroup{
 		JoinDriveTable: models.Student,
+		Search: &group.Search{
+			Fields: []string{"class.name"},
+		},
 		Entities: map[string]interface{}{
 			"id": field.Field{
 				Table:      models.Student,
 				TableField: models.Student.ID,
+				Rule:       "ids,max=100",
 				CanQuery:   true,
 			},
 			"name": field.Field{
@@ -26,33 +27,42 @@ var (
 			"sex": field.Field{
 				Table:      models.Student,
 				TableField: models.Student.Sex,
+				Rule:       "enum,values=0|1",
 				CanQuery:   true,
 			},
 			"class_id": field.Field{
 				Table:      models.Student,
 				TableField: models.Student.ClassId,
+				Rule:       "ids,max=50",
 				CanQuery:   true,
 			},
 			"class_name": field.Field{
 				Table:      models.Class,
 				TableField: models.Class.ClassName,
+				Rule:       "string,max=200",
 				CanQuery:   true,
 			},
+			"class": map[string]interface{}{
+				"id": field.Field{
+					Table:      models.Class,
+					TableField: models.Class.Id,
+					Rule:       "ids,max=50",
+					CanQuery:   true,
+				},
+				"name": field.Field{
+					Table:      models.Class,
+					TableField: models.Class.ClassName,
+					Rule:       "string,max=200",
+					CanQuery:   true,
+					CanOrder:   true,
+				},
+			},
 			"create_time": field.Field{
 				Table:      models.Student,
 				TableField: models.Student.CreateTime,
+				Rule:       "datetime",
 				CanQuery:   true,
-				Callback: func(ctx *gin.Context, i interface{}, values map[string]interface{}, ls *entity_query.LocalStorage) interface{} {
-					if t, ok := i.(time.Time); ok {
-						return t.Format("2006-01-02 15:04:05")
-					}
-					if t, ok := i.(*time.Time); ok {
-						return t.Format("2006-01-02 15:04:05")
-					}
-					return i
-				},
 			},
 		},
 	}
 )
-
@@ -0,0 +1,43 @@
+package group
+
+import (
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/pkg/auth"
+)
+
+// WithIdentity returns the group as used by identity, a nil identity is anonymous.
+// 未调用WithIdentity的实体组 (如生成文档及客户端时) 不检查字段权限
+func (e *EntityGroup) WithIdentity(identity *auth.Identity) EntityGroup {
+	c := *e
+	c.checkAccess = true
+	c.identity = identity
+	return c
+}
+
+// Allowed reports whether the identity of the group may use f for access, see field.Permissions
+func (e *EntityGroup) Allowed(f field.Field, access string) bool {
+	if !e.checkAccess {
+		return true
+	}
+	return e.identity.Allowed(f.Permissions.Required(access))
+}
+
+// Masked reports whether the output of f is masked for the identity of the group.
+// 脱敏的字段只能按完整的值查询, 且不能排序及搜索
+func (e *EntityGroup) Masked(f field.Field) bool {
+	if f.Mask == nil || !e.checkAccess {
+		return false
+	}
+	return len(f.Permissions.Unmask) == 0 || !e.identity.Allowed(f.Permissions.Unmask)
+}
+
+// CanDelete reports whether the identity of the group may delete rows, the group must declare Delete
+func (e *EntityGroup) CanDelete() bool {
+	if len(e.Delete) == 0 {
+		return false
+	}
+	if !e.checkAccess {
+		return true
+	}
+	return e.identity.Allowed(e.Delete)
+}
@@ -0,0 +1,39 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/go-bread/components/database/output"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/pkg/auth"
+)
+
+func TestMasked(t *testing.T) {
+	unmask := field.Permissions{Unmask: []string{"student:sensitive", auth.RolePrefix + "admin"}}
+	masked := field.Field{Mask: outputs.KeepEnds(1, 0), Permissions: unmask}
+	cases := []struct {
+		name     string
+		f        field.Field
+		identity *auth.Identity
+		check    bool // 是否调用WithIdentity
+		masked   bool
+	}{
+		{"without mask", field.Field{Permissions: unmask}, nil, true, false},
+		{"anonymous", masked, nil, true, true},
+		{"user", masked, &auth.Identity{ID: 1}, true, true},
+		{"permission", masked, &auth.Identity{ID: 1, Permissions: []string{"student:sensitive"}}, true, false},
+		{"role", masked, &auth.Identity{ID: 1, Roles: []string{"admin"}}, true, false},
+		{"no unmask permission", field.Field{Mask: outputs.Redact()}, &auth.Identity{ID: 1, Roles: []string{"admin"}}, true, true},
+		{"without identity", masked, nil, false, false},
+	}
+	for _, c := range cases {
+		g := &EntityGroup{}
+		if c.check {
+			ug := g.WithIdentity(c.identity)
+			g = &ug
+		}
+		if got := g.Masked(c.f); got != c.masked {
+			t.Errorf("%s: masked %v, want %v", c.name, got, c.masked)
+		}
+	}
+}
@@ -1,11 +1,14 @@
 package group
 
 import (
-	"reflect"
+	"sort"
+	"strings"
 	"sync/atomic"
 
 	"github.com/go-bread/components/entity/field"
 	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/ratelimit"
 
 	"github.com/go-bread/components/entity/models"
 )
@@ -16,38 +19,51 @@ type FieldsMap map[consts.EntityGroupName]EntityGroup
 type EntityGroup struct {
 	JoinDriveTable  models.Table // 关联驱动表
 	Entities        map[string]interface{}
+	Versions        map[string]Version // 接口版本
+	DefaultVersion  string             // 未指定版本时使用的版本
+	Rules           []Rule             // 字段组合的校验规则
+	Search          *Search            // _search 全文搜索的字段
+	Policies        []Policy           // 查询必须包含的条件, 避免大表全表扫描
+	Scopes          []Scope            // 行级权限, 限制用户可以访问的数据
+	DefaultOrder    [][2]string        // 客户端未指定排序时的排序, 如 {{"id", "desc"}}
+	RateLimit       *ratelimit.Limits  // 每个用户的请求及返回行数的限制, 为空时使用 ratelimit.Default
+	Delete          []string           // 删除行需要的权限, 为空时不能删除, 如 {"student:delete", auth.RolePrefix + "admin"}
 	loadedAllFields int32
 	dividedFields   map[string][]string
+	deprecated      map[string]string
+	baseEntities    map[string]interface{} // 未按版本修改的Entities, Search及Scopes的字段不受版本的别名和删除影响
+	searchFields    []field.Field          // Init解析的Search.Fields
+	checkAccess     bool                   // 是否按identity检查字段权限
+	identity        *auth.Identity
 }
 
 func (e *EntityGroup) Init() {
 	if atomic.CompareAndSwapInt32(&e.loadedAllFields, 0, 1) {
 		divide := make(map[string][]string)
-		for _, v := range e.Entities {
-			f, ok := v.(field.Field)
-			if !ok {
-				continue
-			}
+		e.Walk(func(_ string, f field.Field) {
 			if _, ok := divide[f.Table.TableName()]; !ok {
+				tfs, err := models.Fields(f.Table)
+				if err != nil {
+					panic(err)
+				}
 				var fields []string
-				table := f.Table
-				t := reflect.ValueOf(table)
-				pv := reflect.Indirect(t)
-				tp := pv.Type()
-				for i := 0; i < pv.NumField(); i++ {
-					f := pv.Field(i)
-					if tp.Field(i).Name == "Table" {
-						continue
-					}
-					if f.Interface().(models.TableField).Name == "" {
-						continue
-					}
-					fields = append(fields, f.Interface().(models.TableField).Name)
+				for _, tf := range tfs {
+					fields = append(fields, tf.Name)
 				}
 				divide[f.Table.TableName()] = fields
 			}
-		}
+		})
 		e.dividedFields = divide
+		// 声明的字段在启动时解析, 不存在时直接失败而不是在请求时panic
+		if err := e.resolveVersions(); err != nil {
+			panic(err)
+		}
+		if err := e.resolveSearch(); err != nil {
+			panic(err)
+		}
+		if err := e.resolveScopes(); err != nil {
+			panic(err)
+		}
 	}
 }
 
@@ -61,3 +77,82 @@ func (e *EntityGroup) GetDividedEntities() map[string][]string {
 	}
 	return e.dividedFields
 }
+
+// Walk calls fn for every field of the group ordered by name, fields of nested namespaces are named by path such as class.name
+func (e *EntityGroup) Walk(fn func(name string, f field.Field)) {
+	WalkEntities(e.Entities, "", fn)
+}
+
+// WalkEntities walks the fields of a namespace, prefix is the path of the namespace followed by Separator
+func WalkEntities(entities map[string]interface{}, prefix string, fn func(name string, f field.Field)) {
+	names := make([]string, 0, len(entities))
+	for k := range entities {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		switch v := entities[k].(type) {
+		case field.Field:
+			fn(prefix+k, v)
+		case map[string]interface{}:
+			WalkEntities(v, prefix+k+Separator, fn)
+		}
+	}
+}
+
+// Separator separates the namespaces of a field path
+const Separator = "."
+
+// Lookup returns the entity of path, a field.Field or the map[string]interface{} of a namespace
+func (e *EntityGroup) Lookup(path string) (interface{}, bool) {
+	return Lookup(e.Entities, path)
+}
+
+// Lookup returns the entity of path relative to the namespace entities
+func Lookup(entities map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = entities
+	for _, name := range strings.Split(path, Separator) {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Field returns the field of path
+func (e *EntityGroup) Field(path string) (field.Field, bool) {
+	v, ok := e.Lookup(path)
+	if !ok {
+		return field.Field{}, false
+	}
+	f, ok := v.(field.Field)
+	return f, ok
+}
+
+// Tables returns the distinct tables referenced by the group, ordered by name
+func (e *EntityGroup) Tables() []models.Table {
+	tables := make(map[string]models.Table)
+	if e.JoinDriveTable != nil {
+		tables[e.JoinDriveTable.TableName()] = e.JoinDriveTable
+	}
+	e.Walk(func(_ string, f field.Field) {
+		if f.Table != nil {
+			tables[f.Table.TableName()] = f.Table
+		}
+	})
+
+	names := make([]string, 0, len(tables))
+	for k := range tables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	ts := make([]models.Table, 0, len(names))
+	for _, k := range names {
+		ts = append(ts, tables[k])
+	}
+	return ts
+}
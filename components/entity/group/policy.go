@@ -0,0 +1,127 @@
+package group
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-bread/pkg/e"
+)
+
+// Policy forbids unbounded scans of big tables: a query must contain at least one of the filters,
+// e.g. 不允许不带条件查询全部学生:
+//
+//	Policies: []group.Policy{
+//		group.RequireOneOf(group.Exact("id"), group.Exact("class_id"), group.Within("create_time", 31*24*time.Hour)),
+//	},
+type Policy struct {
+	Filters []Filter // 满足其中之一即可
+}
+
+// Filter is one way of satisfying a Policy
+type Filter struct {
+	Field string
+	// 为0时字段需为等值或in条件;
+	// 否则需为同时提供开始和结束的时间范围, 如 "2020-01-01..2020-02-01", 且范围不超过MaxRange
+	MaxRange time.Duration
+}
+
+// RequireOneOf returns a policy satisfied by any of filters
+func RequireOneOf(filters ...Filter) Policy {
+	return Policy{Filters: filters}
+}
+
+// Exact requires an equality or in condition of field
+func Exact(field string) Filter {
+	return Filter{Field: field}
+}
+
+// Within requires a time range of field no wider than max
+func Within(field string, max time.Duration) Filter {
+	return Filter{Field: field, MaxRange: max}
+}
+
+// 31d, 12h0m0s
+func (f Filter) String() string {
+	if f.MaxRange == 0 {
+		return f.Field
+	}
+	return fmt.Sprintf("%s (<= %s)", f.Field, formatRange(f.MaxRange))
+}
+
+func formatRange(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+	return d.String()
+}
+
+// CheckPolicies checks the coerced values of a query keyed by field name
+func (e *EntityGroup) CheckPolicies(values map[string]interface{}) error {
+	for _, p := range e.Policies {
+		if err := p.check(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 提供了时间范围但范围不满足时返回该字段的错误, 否则列出所有可选的条件
+func (p Policy) check(values map[string]interface{}) error {
+	var rangeErr error
+	for _, f := range p.Filters {
+		v := values[f.Field]
+		if v == nil {
+			continue
+		}
+		err := f.check(v)
+		if err == nil {
+			return nil
+		}
+		if rangeErr == nil {
+			rangeErr = err
+		}
+	}
+	if rangeErr != nil {
+		return rangeErr
+	}
+
+	names := make([]string, len(p.Filters))
+	for i, f := range p.Filters {
+		names[i] = f.String()
+	}
+	err := e.NewKey(e.ERROR_FILTER_REQUIRED, "policy.required", strings.Join(names, ", "))
+	if len(p.Filters) == 1 {
+		err = err.WithField(p.Filters[0].Field)
+	}
+	return err
+}
+
+func (f Filter) check(v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if f.MaxRange == 0 {
+		if ok {
+			return e.NewKey(e.ERROR_FILTER_REQUIRED, "policy.exact").WithField(f.Field)
+		}
+		return nil
+	}
+	// 单个时间或时间列表不是范围查询, 总是满足
+	if !ok {
+		return nil
+	}
+
+	var start, end time.Time
+	for k, b := range map[string]*time.Time{"gt": &start, "gte": &start, "lt": &end, "lte": &end} {
+		if t, ok := m[k].(time.Time); ok {
+			*b = t
+		}
+	}
+	if start.IsZero() || end.IsZero() {
+		return e.NewKey(e.ERROR_FILTER_REQUIRED, "policy.unbounded").WithField(f.Field)
+	}
+	if end.Sub(start) > f.MaxRange {
+		return e.NewKey(e.ERROR_FILTER_REQUIRED, "policy.range", f.Field, formatRange(f.MaxRange)).WithField(f.Field)
+	}
+	return nil
+}
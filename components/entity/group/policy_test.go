@@ -0,0 +1,63 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-bread/pkg/e"
+)
+
+func TestCheckPolicies(t *testing.T) {
+	day := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	g := EntityGroup{Policies: []Policy{
+		RequireOneOf(Exact("id"), Exact("class_id"), Within("create_time", 31*24*time.Hour)),
+	}}
+
+	cases := []struct {
+		name   string
+		values map[string]interface{}
+		key    string // 空为满足
+		field  string
+	}{
+		{"exact", map[string]interface{}{"id": int64(1)}, "", ""},
+		{"in", map[string]interface{}{"class_id": []interface{}{int64(1), int64(2)}}, "", ""},
+		{"range", map[string]interface{}{"create_time": map[string]interface{}{"gte": day, "lt": day.AddDate(0, 0, 31)}}, "", ""},
+		{"single time", map[string]interface{}{"create_time": day}, "", ""},
+		{"other filter", map[string]interface{}{"name": "a"}, "policy.required", ""},
+		{"none", map[string]interface{}{}, "policy.required", ""},
+		{"operator on exact", map[string]interface{}{"id": map[string]interface{}{"gt": int64(1)}}, "policy.exact", "id"},
+		{"unbounded", map[string]interface{}{"create_time": map[string]interface{}{"gte": day}}, "policy.unbounded", "create_time"},
+		{"too wide", map[string]interface{}{"create_time": map[string]interface{}{"gt": day, "lte": day.AddDate(0, 1, 1)}}, "policy.range", "create_time"},
+		{"too wide but exact", map[string]interface{}{"id": int64(1), "create_time": map[string]interface{}{"gte": day}}, "", ""},
+	}
+	for _, c := range cases {
+		err := g.CheckPolicies(c.values)
+		if c.key == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: no error", c.name)
+			continue
+		}
+		ee := e.As(err)
+		if ee.Code != e.ERROR_FILTER_REQUIRED || ee.Key != c.key || ee.Field != c.field {
+			t.Errorf("%s: %d %s at %q, want %s at %q", c.name, ee.Code, ee.Key, ee.Field, c.key, c.field)
+		}
+	}
+}
+
+func TestFilterString(t *testing.T) {
+	cases := map[Filter]string{
+		Exact("id"):                           "id",
+		Within("create_time", 7*24*time.Hour): "create_time (<= 7d)",
+		Within("create_time", 36*time.Hour):   "create_time (<= 36h0m0s)",
+	}
+	for f, want := range cases {
+		if got := f.String(); got != want {
+			t.Errorf("%s, want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package group
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-bread/pkg/e"
+)
+
+// 校验场景
+const (
+	SceneQuery  = "query"
+	SceneCreate = "create"
+	SceneUpdate = "update"
+)
+
+// Rule validates several fields of a group together, e.g. end_time must be after start_time
+type Rule struct {
+	Fields []string // 规则引用的字段
+	Scenes []string // 适用的场景, 为空时适用于所有场景
+	// Check receives the field names as seen by the request version and their coerced values in the same order,
+	// nil for the fields not given
+	Check func(fields []string, values []interface{}) error
+}
+
+// On restricts the rule to scenes
+func (r Rule) On(scenes ...string) Rule {
+	r.Scenes = scenes
+	return r
+}
+
+func (r Rule) appliesTo(scene string) bool {
+	if len(r.Scenes) == 0 {
+		return true
+	}
+	for _, s := range r.Scenes {
+		if s == scene {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRules evaluates the rules of scene over the coerced values of the request keyed by field name
+func (e *EntityGroup) CheckRules(scene string, values map[string]interface{}) error {
+	for _, r := range e.Rules {
+		if !r.appliesTo(scene) {
+			continue
+		}
+		vs := make([]interface{}, len(r.Fields))
+		for i, f := range r.Fields {
+			vs[i] = values[f]
+		}
+		if err := r.Check(r.Fields, vs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RuleFunc declares a rule checked by fn
+func RuleFunc(fn func(fields []string, values []interface{}) error, fields ...string) Rule {
+	return Rule{Fields: fields, Check: fn}
+}
+
+// After requires field to be later (or greater) than other when both are given as single values
+func After(field, other string) Rule {
+	return Rule{
+		Fields: []string{field, other},
+		Check: func(fields []string, values []interface{}) error {
+			if c, ok := compare(values[0], values[1]); ok && c <= 0 {
+				return e.NewKey(e.ERROR_RULE, "rule.after", fields[0], fields[1]).WithField(fields[0])
+			}
+			return nil
+		},
+	}
+}
+
+// RequiredWith requires field when any of with is given, e.g. 按性别查询时必须指定班级:
+//
+//	Rules: []group.Rule{
+//		group.RequiredWith("class_id", "sex").On(group.SceneQuery),
+//	},
+func RequiredWith(field string, with ...string) Rule {
+	return Rule{
+		Fields: append([]string{field}, with...),
+		Check: func(fields []string, values []interface{}) error {
+			if values[0] != nil {
+				return nil
+			}
+			for i, v := range values[1:] {
+				if v != nil {
+					return e.NewKey(e.ERROR_RULE, "rule.required_with", fields[0], fields[i+1]).WithField(fields[0])
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// AtMostOne allows at most one of fields
+func AtMostOne(fields ...string) Rule {
+	return Rule{
+		Fields: fields,
+		Check: func(fields []string, values []interface{}) error {
+			var given []string
+			for i, v := range values {
+				if v != nil {
+					given = append(given, fields[i])
+				}
+			}
+			if len(given) > 1 {
+				return e.NewKey(e.ERROR_RULE, "rule.at_most_one", strings.Join(fields, ", ")).WithField(given[1])
+			}
+			return nil
+		},
+	}
+}
+
+// compare compares two single values of the same type, ranges and lists are not comparable
+func compare(a, b interface{}) (int, bool) {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(sa, sb), true
+	}
+
+	fa, ok := toFloat(a)
+	if !ok {
+		return 0, false
+	}
+	fb, ok := toFloat(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case fa < fb:
+		return -1, true
+	case fa > fb:
+		return 1, true
+	default:
+		return 0, true
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
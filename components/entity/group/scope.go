@@ -0,0 +1,168 @@
+package group
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-bread/components/database/condition"
+	"github.com/go-bread/components/entity/field"
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/e"
+)
+
+// Scope limits the rows of the group to those the user may access, e.g. the students of the classes of a teacher.
+// 查询时 Field in (Values) 与客户端的条件AND, 客户端无法绕过; 写入时Field的值必须属于Values
+//
+// 如教师只能访问所教班级的学生, 拥有student:all权限或管理员角色的用户不受限制:
+//
+//	Scopes: []group.Scope{
+//		{Field: "class_id", Values: teacherClasses, Bypass: []string{"student:all", auth.RolePrefix + "admin"}},
+//	},
+//
+//	func teacherClasses(identity *auth.Identity) ([]interface{}, error) {
+//		ids, err := models.GetTeacherClassIDs(identity.ID)
+//		if err != nil {
+//			return nil, e.Wrap(e.ERROR_DATABASE, err)
+//		}
+//		values := make([]interface{}, len(ids))
+//		for i, id := range ids {
+//			values[i] = id
+//		}
+//		return values, nil
+//	}
+type Scope struct {
+	Field  string                                               // 字段路径, 不受版本的别名和删除影响
+	Values func(identity *auth.Identity) ([]interface{}, error) // 用户可以访问的值, 为空时不能访问任何数据
+	Bypass []string                                             // 拥有其中任一权限的用户不受限制, 如 student:all, role:admin
+	input  string                                               // 该版本中的字段名
+	field  field.Field                                          // Init解析的Field
+}
+
+// 没有可以访问的值时不返回任何数据
+var denyAll = condition.RegisterTemplate("bread.deny_all", "1 = 0")
+
+// scopeValues is a Scope resolved for the identity of the group
+type scopeValues struct {
+	field  field.Field
+	input  string
+	values []interface{}
+	allow  map[string]struct{}
+}
+
+// ScopeConditions returns the conditions ANDed into the queries and updates of the identity of the group.
+// 未调用WithIdentity的实体组不限制
+func (e *EntityGroup) ScopeConditions() ([]validatorIface.Condition, error) {
+	scopes, err := e.scopes()
+	if err != nil {
+		return nil, err
+	}
+	conds := make([]validatorIface.Condition, 0, len(scopes))
+	for _, s := range scopes {
+		table, name := s.field.Table.TableName(), s.field.TableField.Name
+		if len(s.values) == 0 {
+			cond, err := denyAll.Param(table, name, nil)
+			if err != nil {
+				return nil, err
+			}
+			conds = append(conds, cond)
+			continue
+		}
+		conds = append(conds, condition.NewQueryParam(table, name, condition.OpIn, s.values))
+	}
+	return conds, nil
+}
+
+// CheckScopes checks the coerced values of a write keyed by field name.
+// 创建时必须提供限制的字段, 更新时提供的值同样必须在可以访问的范围内
+func (e *EntityGroup) CheckScopes(scene string, values map[string]interface{}) error {
+	scopes, err := e.scopes()
+	if err != nil {
+		return err
+	}
+	for _, s := range scopes {
+		v, ok := values[s.input]
+		if !ok && scene != SceneCreate {
+			continue
+		}
+		if err := s.check(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *EntityGroup) scopes() ([]scopeValues, error) {
+	if !e.checkAccess {
+		return nil, nil
+	}
+	var scopes []scopeValues
+	for _, sc := range e.Scopes {
+		if len(sc.Bypass) > 0 && e.identity.Allowed(sc.Bypass) {
+			continue
+		}
+		if !e.Initialized() {
+			panic("uninitialized entity group")
+		}
+		f := sc.field
+		s := scopeValues{field: f, input: sc.input, allow: make(map[string]struct{})}
+		if s.input == "" {
+			s.input = sc.Field
+		}
+		// 匿名用户不能访问任何数据
+		if e.identity != nil && sc.Values != nil {
+			values, err := sc.Values(e.identity)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) > 0 {
+				coerced, err := f.TableField.Coerce(values, time.UTC)
+				if err != nil {
+					return nil, fmt.Errorf("scope %s: %v", sc.Field, err)
+				}
+				s.values = coerced.([]interface{})
+			}
+		}
+		for _, v := range s.values {
+			s.allow[fmt.Sprint(v)] = struct{}{}
+		}
+		scopes = append(scopes, s)
+	}
+	return scopes, nil
+}
+
+// resolveScopes looks up the fields of Scopes in the unversioned entities,
+// Scopes被复制, 不修改声明中共享的切片
+func (e *EntityGroup) resolveScopes() error {
+	if len(e.Scopes) == 0 {
+		return nil
+	}
+	scopes := make([]Scope, len(e.Scopes))
+	for i, sc := range e.Scopes {
+		ent, ok := Lookup(e.entitiesOfBase(), sc.Field)
+		f, isField := ent.(field.Field)
+		if !ok || !isField {
+			return fmt.Errorf("scope %d: unknown field %s", i, sc.Field)
+		}
+		sc.field = f
+		scopes[i] = sc
+	}
+	e.Scopes = scopes
+	return nil
+}
+
+func (s scopeValues) check(v interface{}) error {
+	if v == nil {
+		return e.NewKey(e.ERROR_FORBIDDEN_ROW, "scope.required").WithField(s.input)
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	for _, item := range list {
+		if _, ok := s.allow[fmt.Sprint(item)]; !ok {
+			return e.New(e.ERROR_FORBIDDEN_ROW).WithField(s.input)
+		}
+	}
+	return nil
+}
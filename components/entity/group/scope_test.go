@@ -0,0 +1,128 @@
+package group
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/models"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/e"
+)
+
+// scopedGroup limits the students to the classes of the identity, its ID doubles as the only class
+func scopedGroup() *EntityGroup {
+	g := &EntityGroup{
+		JoinDriveTable: models.Student,
+		Entities: map[string]interface{}{
+			"id":       field.Field{Table: models.Student, TableField: models.Student.ID},
+			"class_id": field.Field{Table: models.Student, TableField: models.Student.ClassId},
+		},
+		Scopes: []Scope{{
+			Field: "class_id",
+			Values: func(identity *auth.Identity) ([]interface{}, error) {
+				if identity.ID < 0 {
+					return nil, errors.New("lookup failed")
+				}
+				if identity.ID == 0 {
+					return nil, nil
+				}
+				return []interface{}{json.Number("1"), identity.ID}, nil
+			},
+			Bypass: []string{"student:all", auth.RolePrefix + "admin"},
+		}},
+	}
+	g.Init()
+	return g
+}
+
+func TestScopeConditions(t *testing.T) {
+	g := scopedGroup()
+	cases := []struct {
+		name     string
+		identity *auth.Identity
+		values   []interface{} // nil为不限制
+		deny     bool
+	}{
+		{"teacher", &auth.Identity{ID: 2}, []interface{}{int64(1), int64(2)}, false},
+		{"no classes", &auth.Identity{ID: 0}, nil, true},
+		{"anonymous", nil, nil, true},
+		{"permission", &auth.Identity{ID: 2, Permissions: []string{"student:all"}}, nil, false},
+		{"role", &auth.Identity{ID: 2, Roles: []string{"admin"}}, nil, false},
+	}
+	for _, c := range cases {
+		ug := g.WithIdentity(c.identity)
+		conds, err := ug.ScopeConditions()
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if c.values == nil && !c.deny {
+			if len(conds) != 0 {
+				t.Errorf("%s: %d conditions, want none", c.name, len(conds))
+			}
+			continue
+		}
+		if len(conds) != 1 {
+			t.Errorf("%s: %d conditions, want 1", c.name, len(conds))
+			continue
+		}
+		if c.deny {
+			if conds[0].GetSql() != "1 = 0" {
+				t.Errorf("%s: %q, want the deny all template", c.name, conds[0].GetSql())
+			}
+			continue
+		}
+		if conds[0].FieldName() != "class_id" || conds[0].Operator() != "in" || !reflect.DeepEqual(conds[0].ConditionValue()[0], c.values) {
+			t.Errorf("%s: %s %s %v", c.name, conds[0].FieldName(), conds[0].Operator(), conds[0].ConditionValue())
+		}
+	}
+
+	if conds, _ := g.ScopeConditions(); len(conds) != 0 {
+		t.Error("the group without an identity is scoped")
+	}
+	ug := g.WithIdentity(&auth.Identity{ID: -1})
+	if _, err := ug.ScopeConditions(); err == nil {
+		t.Error("the error of Values is ignored")
+	}
+}
+
+func TestCheckScopes(t *testing.T) {
+	g := scopedGroup()
+	ug := g.WithIdentity(&auth.Identity{ID: 2})
+	cases := []struct {
+		name   string
+		scene  string
+		values map[string]interface{}
+		code   int // 0为允许
+	}{
+		{"create", SceneCreate, map[string]interface{}{"class_id": int64(2)}, 0},
+		{"create elsewhere", SceneCreate, map[string]interface{}{"class_id": int64(3)}, e.ERROR_FORBIDDEN_ROW},
+		{"create without the field", SceneCreate, map[string]interface{}{"id": int64(1)}, e.ERROR_FORBIDDEN_ROW},
+		{"update without the field", SceneUpdate, map[string]interface{}{"id": int64(1)}, 0},
+		{"move elsewhere", SceneUpdate, map[string]interface{}{"class_id": int64(3)}, e.ERROR_FORBIDDEN_ROW},
+		{"list", SceneQuery, map[string]interface{}{"class_id": []interface{}{int64(1), int64(3)}}, e.ERROR_FORBIDDEN_ROW},
+	}
+	for _, c := range cases {
+		err := ug.CheckScopes(c.scene, c.values)
+		if c.code == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || e.As(err).Code != c.code || e.As(err).Field != "class_id" {
+			t.Errorf("%s: got %v, want code %d at class_id", c.name, err, c.code)
+		}
+	}
+}
+
+func TestResolveScopesUnknownField(t *testing.T) {
+	g := scopedGroup()
+	g.Scopes = []Scope{{Field: "class.id"}}
+	if err := g.resolveScopes(); err == nil {
+		t.Error("a scope of an unknown field resolved")
+	}
+}
@@ -0,0 +1,58 @@
+package group
+
+import (
+	"fmt"
+
+	"github.com/go-bread/components/entity/field"
+)
+
+// RelevanceField is the virtual field for ordering the results of _search by relevance
+const RelevanceField = "_relevance"
+
+// Search declares the text fields searched by the _search query key.
+// mysql使用 MATCH ... AGAINST 时需要声明FullText, 且FULLTEXT索引的字段与Fields一致;
+// postgres使用tsvector, 其他情况使用LIKE匹配
+type Search struct {
+	Fields   []string // 字段路径, 如 name, class.name
+	FullText bool     // mysql 已建立 FULLTEXT 索引
+	Language string   // postgres 的 text search 配置, 默认 simple
+}
+
+// DefaultSearchLanguage is the postgres text search configuration used when Language is empty
+const DefaultSearchLanguage = "simple"
+
+// SearchFields returns the fields searched by _search as resolved by Init, the second value is false when the group has no Search
+func (e *EntityGroup) SearchFields() ([]field.Field, bool) {
+	if e.Search == nil || len(e.Search.Fields) == 0 {
+		return nil, false
+	}
+	if !e.Initialized() {
+		panic("uninitialized entity group")
+	}
+	return e.searchFields, true
+}
+
+// resolveSearch looks up the fields of Search in the unversioned entities
+func (e *EntityGroup) resolveSearch() error {
+	if e.Search == nil {
+		return nil
+	}
+	fields := make([]field.Field, 0, len(e.Search.Fields))
+	for _, name := range e.Search.Fields {
+		ent, ok := Lookup(e.entitiesOfBase(), name)
+		f, isField := ent.(field.Field)
+		if !ok || !isField {
+			return fmt.Errorf("search: unknown field %s", name)
+		}
+		fields = append(fields, f)
+	}
+	e.searchFields = fields
+	return nil
+}
+
+func (e *EntityGroup) entitiesOfBase() map[string]interface{} {
+	if e.baseEntities != nil {
+		return e.baseEntities
+	}
+	return e.Entities
+}
@@ -0,0 +1,190 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidVersion = errors.New("invalid version")
+
+// Version describes how the fields of a version differ from Entities.
+//
+// 如版本2将sex改名为gender, 版本1中的sex提示已弃用:
+//
+//	DefaultVersion: "1",
+//	Versions: map[string]group.Version{
+//		"1": {Deprecated: map[string]string{"sex": "use gender of version 2"}},
+//		"2": {Aliases: map[string]string{"gender": "sex"}, Removed: []string{"sex"}},
+//	},
+type Version struct {
+	Aliases    map[string]string // 该版本中的字段名 -> Entities中的字段名
+	Deprecated map[string]string // 该版本中的字段名 -> 提示信息
+	Removed    []string          // 该版本中不再提供的字段
+}
+
+// ForVersion returns the group as seen by the clients of version v, an empty v selects DefaultVersion.
+// The receiver must be initialized.
+func (e *EntityGroup) ForVersion(v string) (EntityGroup, error) {
+	if v == "" {
+		v = e.DefaultVersion
+	}
+	if v == "" {
+		return *e, nil
+	}
+	ver, ok := e.Versions[v]
+	if !ok {
+		return EntityGroup{}, ErrInvalidVersion
+	}
+
+	entities := make(map[string]interface{}, len(e.Entities)+len(ver.Aliases))
+	for k, f := range e.Entities {
+		entities[k] = f
+	}
+	// 别名的字段已由Init检查, 带.的别名放入对应的命名空间
+	for alias, name := range ver.Aliases {
+		f, _ := Lookup(e.Entities, name)
+		insert(entities, alias, f)
+	}
+	for _, name := range ver.Removed {
+		remove(entities, name)
+	}
+
+	// 规则, 查询策略, 默认排序及写入时检查的行级权限使用该版本中的字段名
+	names := make(map[string]string, len(ver.Aliases))
+	for alias, name := range ver.Aliases {
+		names[name] = alias
+	}
+	rename := func(f string) string {
+		if alias, ok := names[f]; ok {
+			return alias
+		}
+		return f
+	}
+	rules := make([]Rule, len(e.Rules))
+	for i, r := range e.Rules {
+		fields := make([]string, len(r.Fields))
+		for j, f := range r.Fields {
+			fields[j] = rename(f)
+		}
+		r.Fields = fields
+		rules[i] = r
+	}
+	policies := make([]Policy, len(e.Policies))
+	for i, p := range e.Policies {
+		filters := make([]Filter, len(p.Filters))
+		for j, f := range p.Filters {
+			f.Field = rename(f.Field)
+			filters[j] = f
+		}
+		policies[i] = Policy{Filters: filters}
+	}
+	defaultOrder := make([][2]string, len(e.DefaultOrder))
+	for i, o := range e.DefaultOrder {
+		defaultOrder[i] = [2]string{rename(o[0]), o[1]}
+	}
+	scopes := make([]Scope, len(e.Scopes))
+	for i, sc := range e.Scopes {
+		sc.input = rename(sc.Field)
+		scopes[i] = sc
+	}
+
+	// 各版本的字段对应的表与字段相同, 直接复用已初始化的数据
+	c := *e
+	c.Entities = entities
+	c.Rules = rules
+	c.Policies = policies
+	c.Scopes = scopes
+	c.DefaultOrder = defaultOrder
+	c.deprecated = ver.Deprecated
+	c.baseEntities = e.entitiesOfBase()
+	return c, nil
+}
+
+// Default returns the group as seen by the clients that do not ask for a version, such as the generated documents and clients
+func (e *EntityGroup) Default() EntityGroup {
+	v, err := e.ForVersion("")
+	if err != nil {
+		// DefaultVersion已由Init检查
+		panic(err)
+	}
+	return v
+}
+
+// resolveVersions checks that DefaultVersion is declared and the aliases and removed fields of the versions exist
+func (e *EntityGroup) resolveVersions() error {
+	if _, ok := e.Versions[e.DefaultVersion]; e.DefaultVersion != "" && !ok {
+		return fmt.Errorf("default version %s is not declared", e.DefaultVersion)
+	}
+	for v, ver := range e.Versions {
+		for alias, name := range ver.Aliases {
+			if _, ok := Lookup(e.Entities, name); !ok {
+				return fmt.Errorf("version %s: alias %s of unknown field %s", v, alias, name)
+			}
+			// 别名的上级必须是命名空间, 如 class.title 中的 class
+			names := strings.Split(alias, Separator)
+			for i := 1; i < len(names); i++ {
+				parent := strings.Join(names[:i], Separator)
+				if f, ok := Lookup(e.Entities, parent); ok {
+					if _, ok := f.(map[string]interface{}); !ok {
+						return fmt.Errorf("version %s: alias %s is nested in the field %s", v, alias, parent)
+					}
+				}
+			}
+		}
+		for _, name := range ver.Removed {
+			if _, ok := Lookup(e.Entities, name); !ok {
+				return fmt.Errorf("version %s: removed unknown field %s", v, name)
+			}
+		}
+	}
+	return nil
+}
+
+// Deprecation returns the deprecation message of the field or namespace of path in the version,
+// the fields of a deprecated namespace are deprecated too
+func (e *EntityGroup) Deprecation(path string) (string, bool) {
+	if len(e.deprecated) == 0 {
+		return "", false
+	}
+	names := strings.Split(path, Separator)
+	for i := len(names); i > 0; i-- {
+		if msg, ok := e.deprecated[strings.Join(names[:i], Separator)]; ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// remove deletes the entity of path, the namespaces on the path are copied so that Entities stay unchanged
+func remove(entities map[string]interface{}, path string) {
+	names := strings.Split(path, Separator)
+	for _, name := range names[:len(names)-1] {
+		ns, ok := entities[name].(map[string]interface{})
+		if !ok {
+			return
+		}
+		c := make(map[string]interface{}, len(ns))
+		for k, v := range ns {
+			c[k] = v
+		}
+		entities[name] = c
+		entities = c
+	}
+	delete(entities, names[len(names)-1])
+}
+
+// insert sets the entity of path, the namespaces on the path are copied or created so that Entities stay unchanged
+func insert(entities map[string]interface{}, path string, v interface{}) {
+	names := strings.Split(path, Separator)
+	for _, name := range names[:len(names)-1] {
+		ns, _ := entities[name].(map[string]interface{})
+		c := make(map[string]interface{}, len(ns)+1)
+		for k, v := range ns {
+			c[k] = v
+		}
+		entities[name] = c
+		entities = c
+	}
+	entities[names[len(names)-1]] = v
+}
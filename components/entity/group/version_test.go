@@ -0,0 +1,121 @@
+package group
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/models"
+)
+
+func versionedGroup(versions map[string]Version) *EntityGroup {
+	return &EntityGroup{
+		JoinDriveTable: models.Student,
+		Versions:       versions,
+		Entities: map[string]interface{}{
+			"id":  field.Field{Table: models.Student, TableField: models.Student.ID},
+			"sex": field.Field{Table: models.Student, TableField: models.Student.Sex},
+			"class": map[string]interface{}{
+				"id":   field.Field{Table: models.Class, TableField: models.Class.Id},
+				"name": field.Field{Table: models.Class, TableField: models.Class.ClassName},
+			},
+		},
+	}
+}
+
+func TestForVersionAliases(t *testing.T) {
+	g := versionedGroup(map[string]Version{
+		"1": {Aliases: map[string]string{"gender": "sex", "class.title": "class.name", "room.id": "class.id"}, Removed: []string{"class.name"}},
+	})
+	g.Init()
+	v, err := g.ForVersion("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path  string
+		table string
+		found bool
+	}{
+		{"gender", "sex", true},
+		{"sex", "sex", true},
+		{"class.title", "class_name", true},
+		{"class.id", "id", true},
+		{"room.id", "id", true},
+		{"class.name", "", false},
+		{"class.title.x", "", false},
+	}
+	for _, c := range cases {
+		f, ok := v.Field(c.path)
+		if ok != c.found {
+			t.Errorf("%s: found %v, want %v", c.path, ok, c.found)
+			continue
+		}
+		if ok && f.TableField.Name != c.table {
+			t.Errorf("%s: column %s, want %s", c.path, f.TableField.Name, c.table)
+		}
+	}
+	if _, ok := g.Field("class.title"); ok {
+		t.Error("the alias changed the entities of the group")
+	}
+	if _, ok := g.Field("class.name"); !ok {
+		t.Error("the removal changed the entities of the group")
+	}
+}
+
+func TestResolveVersionsErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		version Version
+		msg     string
+	}{
+		{"unknown field", Version{Aliases: map[string]string{"gender": "nope"}}, "alias gender of unknown field nope"},
+		{"nested in a field", Version{Aliases: map[string]string{"sex.code": "sex"}}, "alias sex.code is nested in the field sex"},
+		{"unknown removal", Version{Removed: []string{"class.nope"}}, "removed unknown field class.nope"},
+	}
+	for _, c := range cases {
+		g := versionedGroup(map[string]Version{"1": c.version})
+		err := g.resolveVersions()
+		if err == nil || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%s: got %v, want %q", c.name, err, c.msg)
+		}
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	g := versionedGroup(map[string]Version{
+		"1": {
+			Aliases:    map[string]string{"class.title": "class.name"},
+			Deprecated: map[string]string{"sex": "use gender", "class.title": "use class.name", "room": "use class"},
+		},
+	})
+	g.Versions["1"].Aliases["room.id"] = "class.id"
+	g.Init()
+	v, err := g.ForVersion("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path string
+		msg  string
+	}{
+		{"sex", "use gender"},
+		{"class.title", "use class.name"},
+		{"room", "use class"},
+		{"room.id", "use class"},
+		{"class.name", ""},
+		{"class", ""},
+		{"id", ""},
+	}
+	for _, c := range cases {
+		msg, _ := v.Deprecation(c.path)
+		if msg != c.msg {
+			t.Errorf("%s: got %q, want %q", c.path, msg, c.msg)
+		}
+	}
+	if _, ok := g.Deprecation("sex"); ok {
+		t.Error("the group without a version reports a deprecation")
+	}
+}
@@ -0,0 +1,102 @@
+package entity
+
+import (
+	"reflect"
+
+	"github.com/go-bread/components/database/condition"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/caller"
+)
+
+// Meta describes an entity group as usable by the caller, fields the caller can not read are left out
+type Meta struct {
+	Name       string      `json:"name"`
+	Version    string      `json:"version,omitempty"`
+	Searchable bool        `json:"searchable"`
+	Fields     []FieldMeta `json:"fields"`
+}
+
+type FieldMeta struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Filterable bool     `json:"filterable"`
+	Operators  []string `json:"operators,omitempty"`
+	Orderable  bool     `json:"orderable"`
+	Writable   bool     `json:"writable"`
+	Masked     bool     `json:"masked,omitempty"`
+	Deprecated string   `json:"deprecated,omitempty"`
+}
+
+// QueryMeta returns the meta of the group gn of version for the logged in user
+func QueryMeta(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, version string) (*Meta, error) {
+	g, err := loadGroup(ctx, fieldsMap, gn, version)
+	if err != nil {
+		return nil, err
+	}
+	if version == "" {
+		version = g.DefaultVersion
+	}
+
+	m := &Meta{Name: string(gn), Version: version, Fields: []FieldMeta{}}
+	if fields, ok := g.SearchFields(); ok {
+		m.Searchable = true
+		for _, f := range fields {
+			m.Searchable = m.Searchable && g.Allowed(f, field.AccessFilter) && !g.Masked(f)
+		}
+	}
+	g.Walk(func(name string, f field.Field) {
+		if !g.Allowed(f, field.AccessRead) {
+			return
+		}
+		fm := FieldMeta{
+			Name:      name,
+			Type:      typeName(f.TableField),
+			Orderable: f.CanOrder && g.Allowed(f, field.AccessOrder) && !g.Masked(f),
+			Writable:  f.TableField.Permission == models.ReadWrite && g.Allowed(f, field.AccessWrite),
+		}
+		if f.CanQuery && g.Allowed(f, field.AccessFilter) {
+			fm.Filterable = true
+			fm.Operators = f.Operators()
+		}
+		if g.Masked(f) {
+			fm.Masked = true
+			fm.Operators = exactOperators(fm.Operators)
+		}
+		fm.Deprecated, _ = g.Deprecation(name)
+		m.Fields = append(m.Fields, fm)
+	})
+	return m, nil
+}
+
+// 脱敏的字段只能使用等值及in条件
+func exactOperators(ops []string) []string {
+	var exact []string
+	for _, op := range ops {
+		if op == condition.OpEqual || op == condition.OpIn {
+			exact = append(exact, op)
+		}
+	}
+	return exact
+}
+
+func typeName(tf models.TableField) string {
+	if tf.IsTime {
+		return "datetime"
+	}
+	switch tf.Type {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
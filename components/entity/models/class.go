@@ -16,8 +16,9 @@ var (
 			Name:       "class_name",
 			Permission: Read,
 		},
+		// create_time为datetime列, 不能按驱动返回的[]uint8声明为整数
 		CreateTime: TableField{
-			Type:       reflect.Uint8,
+			IsTime:     true,
 			Name:       "create_time",
 			Permission: Read,
 		},
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-bread/pkg/e"
+)
+
+// Coerce converts a query value decoded from json into the Go type of the field.
+// Arrays are coerced element by element, objects such as {"gte": 1} value by value, except like which stays a string.
+// The values of IsTime fields are parsed in loc, see coerceDatetime for ranges.
+func (f TableField) Coerce(v interface{}, loc *time.Location) (interface{}, error) {
+	if s, ok := v.(string); ok && f.IsTime {
+		return coerceDatetime(s, loc)
+	}
+
+	switch vv := v.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(vv))
+		for i, elem := range vv {
+			c, err := f.coerce(elem, loc)
+			if err != nil {
+				return nil, e.AsInvalid(err).WithField(fmt.Sprintf("[%d]", i))
+			}
+			list[i] = c
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, elem := range vv {
+			if k == "like" {
+				m[k] = elem
+				continue
+			}
+			c, err := f.coerce(elem, loc)
+			if err != nil {
+				return nil, e.AsInvalid(err).WithField(k)
+			}
+			m[k] = c
+		}
+		return m, nil
+	default:
+		return f.coerce(v, loc)
+	}
+}
+
+func (f TableField) coerce(v interface{}, loc *time.Location) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if f.IsTime {
+		s, ok := v.(string)
+		if !ok {
+			return nil, e.Invalid("value.time", e.TypeName(v))
+		}
+		t, _, err := parseDatetime(s, loc)
+		if err != nil {
+			return nil, err
+		}
+		return t.UTC(), nil
+	}
+
+	switch f.Type {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt(v)
+		if err != nil {
+			return nil, err
+		}
+		if bits := bitSize(f.Type); bits < 64 && (i < -1<<(bits-1) || i > 1<<(bits-1)-1) {
+			return nil, e.Invalid("value.overflow", f.Type)
+		}
+		return i, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := toUint(v)
+		if err != nil {
+			return nil, err
+		}
+		if bits := bitSize(f.Type); bits < 64 && u > 1<<bits-1 {
+			return nil, e.Invalid("value.overflow", f.Type)
+		}
+		return u, nil
+	case reflect.Float32, reflect.Float64:
+		s, ok := numberString(v)
+		if !ok {
+			return nil, e.Invalid("value.number", e.TypeName(v))
+		}
+		fl, err := strconv.ParseFloat(s, bitSize(f.Type))
+		if err != nil {
+			if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+				return nil, e.Invalid("value.overflow", f.Type)
+			}
+			return nil, e.Invalid("value.not_number", s)
+		}
+		return fl, nil
+	case reflect.Bool:
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	case reflect.String:
+		switch s := v.(type) {
+		case string:
+			return s, nil
+		case json.Number:
+			return s.String(), nil
+		default:
+			return nil, e.Invalid("value.string", e.TypeName(v))
+		}
+	default:
+		return v, nil
+	}
+}
+
+func toInt(v interface{}) (int64, error) {
+	s, ok := numberString(v)
+	if !ok {
+		return 0, e.Invalid("value.integer", e.TypeName(v))
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err == nil {
+		return i, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return 0, e.Invalid("value.overflow", reflect.Int64)
+	}
+	// 1e3, 3.0 等整数值
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil || f != math.Trunc(f) {
+		return 0, e.Invalid("value.not_integer", s)
+	}
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, e.Invalid("value.overflow", reflect.Int64)
+	}
+	return int64(f), nil
+}
+
+func toUint(v interface{}) (uint64, error) {
+	s, ok := numberString(v)
+	if !ok {
+		return 0, e.Invalid("value.unsigned", e.TypeName(v))
+	}
+	if strings.HasPrefix(s, "-") {
+		return 0, e.Invalid("value.negative", s)
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err == nil {
+		return u, nil
+	}
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return 0, e.Invalid("value.overflow", reflect.Uint64)
+	}
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil || f != math.Trunc(f) {
+		return 0, e.Invalid("value.not_integer", s)
+	}
+	if f >= math.MaxUint64 {
+		return 0, e.Invalid("value.overflow", reflect.Uint64)
+	}
+	return uint64(f), nil
+}
+
+// 1, 0, "true", "false", "1", "0" 均可作为布尔值
+func toBool(v interface{}) (bool, error) {
+	switch b := v.(type) {
+	case bool:
+		return b, nil
+	case json.Number:
+		switch b.String() {
+		case "1":
+			return true, nil
+		case "0":
+			return false, nil
+		}
+	case float64:
+		switch b {
+		case 1:
+			return true, nil
+		case 0:
+			return false, nil
+		}
+	case string:
+		switch strings.ToLower(b) {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+	}
+	return false, e.Invalid("value.boolean", e.TypeName(v))
+}
+
+// 数字以及数字字符串 (如字符串形式的id) 统一转为字符串再解析, 避免float64丢失精度
+func numberString(v interface{}) (string, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.String(), true
+	case string:
+		s := strings.TrimSpace(n)
+		return s, s != ""
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
+		return fmt.Sprint(n), true
+	default:
+		return "", false
+	}
+}
+
+func bitSize(k reflect.Kind) int {
+	switch k {
+	case reflect.Int8, reflect.Uint8:
+		return 8
+	case reflect.Int16, reflect.Uint16:
+		return 16
+	case reflect.Int32, reflect.Uint32, reflect.Float32:
+		return 32
+	default:
+		return 64
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-bread/pkg/e"
+)
+
+func TestCoerce(t *testing.T) {
+	cases := []struct {
+		name  string
+		kind  reflect.Kind
+		value interface{}
+		want  interface{}
+	}{
+		{"int", reflect.Int, json.Number("42"), int64(42)},
+		{"int string", reflect.Int, "42", int64(42)},
+		{"int exponent", reflect.Int64, json.Number("1e3"), int64(1000)},
+		{"big id", reflect.Uint64, json.Number("18446744073709551615"), uint64(18446744073709551615)},
+		{"float", reflect.Float64, json.Number("1.5"), 1.5},
+		{"bool number", reflect.Bool, json.Number("1"), true},
+		{"bool string", reflect.Bool, "False", false},
+		{"string number", reflect.String, json.Number("007"), "007"},
+		{"null", reflect.Int, nil, nil},
+		{"list", reflect.Int, []interface{}{json.Number("1"), "2"}, []interface{}{int64(1), int64(2)}},
+		{"object", reflect.Int, map[string]interface{}{"gte": "1", "like": json.Number("2")}, map[string]interface{}{"gte": int64(1), "like": json.Number("2")}},
+	}
+	for _, c := range cases {
+		got, err := TableField{Type: c.kind}.Coerce(c.value, nil)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: %#v, want %#v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestCoerceErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		kind  reflect.Kind
+		value interface{}
+		key   string
+		field string
+	}{
+		{"fraction", reflect.Int, json.Number("1.5"), "value.not_integer", ""},
+		{"int8 overflow", reflect.Int8, json.Number("128"), "value.overflow", ""},
+		{"int64 overflow", reflect.Int64, json.Number("9223372036854775808"), "value.overflow", ""},
+		{"negative unsigned", reflect.Uint, json.Number("-1"), "value.negative", ""},
+		{"int of bool", reflect.Int, true, "value.integer", ""},
+		{"empty string", reflect.Int, " ", "value.integer", ""},
+		{"bool", reflect.Bool, json.Number("2"), "value.boolean", ""},
+		{"string of bool", reflect.String, true, "value.string", ""},
+		{"list element", reflect.Int, []interface{}{json.Number("1"), "x"}, "value.not_integer", "[1]"},
+		{"object value", reflect.Int, map[string]interface{}{"lt": "x"}, "value.not_integer", "lt"},
+		{"time of number", reflect.Invalid, json.Number("1"), "value.time", ""},
+	}
+	for _, c := range cases {
+		tf := TableField{Type: c.kind, IsTime: c.kind == reflect.Invalid}
+		_, err := tf.Coerce(c.value, nil)
+		if err == nil {
+			t.Errorf("%s: no error", c.name)
+			continue
+		}
+		if ee := e.As(err); ee.Key != c.key || ee.Field != c.field {
+			t.Errorf("%s: %s at %q, want %s at %q", c.name, ee.Key, ee.Field, c.key, c.field)
+		}
+	}
+}
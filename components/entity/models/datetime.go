@@ -0,0 +1,108 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-bread/pkg/e"
+)
+
+// DatetimeLayouts are the accepted formats of the values of IsTime fields, tried in order
+var DatetimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// now-7d, today+1w, now
+var relativeTime = regexp.MustCompile(`^(now|today)(?:([+-])(\d+)([smhdw]))?$`)
+
+// coerceDatetime converts a Datetime query value into UTC bounds:
+// "2026-10-01..2026-10-17" becomes {"gte": from, "lt": day after to}, either side of a range may be omitted,
+// a date such as "2026-10-01" matches the whole day, other values are a single time.
+func coerceDatetime(s string, loc *time.Location) (interface{}, error) {
+	if i := strings.Index(s, ".."); i >= 0 {
+		from, to := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+2:])
+		if from == "" && to == "" {
+			return nil, e.Invalid("value.time_range", s)
+		}
+		m := make(map[string]interface{})
+		if from != "" {
+			t, _, err := parseDatetime(from, loc)
+			if err != nil {
+				return nil, err
+			}
+			m["gte"] = t.UTC()
+		}
+		if to != "" {
+			t, day, err := parseDatetime(to, loc)
+			if err != nil {
+				return nil, err
+			}
+			if day {
+				m["lt"] = t.AddDate(0, 0, 1).UTC()
+			} else {
+				m["lte"] = t.UTC()
+			}
+		}
+		return m, nil
+	}
+
+	t, day, err := parseDatetime(s, loc)
+	if err != nil {
+		return nil, err
+	}
+	if day {
+		return map[string]interface{}{"gte": t.UTC(), "lt": t.AddDate(0, 0, 1).UTC()}, nil
+	}
+	return t.UTC(), nil
+}
+
+// parseDatetime parses s in loc, day reports whether s names a whole day
+func parseDatetime(s string, loc *time.Location) (t time.Time, day bool, err error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	s = strings.TrimSpace(s)
+
+	if m := relativeTime.FindStringSubmatch(s); m != nil {
+		t = time.Now().In(loc)
+		if m[1] == "today" {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+			day = m[4] == "" || m[4] == "d" || m[4] == "w"
+		}
+		if m[2] != "" {
+			n, _ := strconv.Atoi(m[3])
+			if m[2] == "-" {
+				n = -n
+			}
+			t = shift(t, n, m[4])
+		}
+		return t, day, nil
+	}
+
+	for _, layout := range DatetimeLayouts {
+		if t, err = time.ParseInLocation(layout, s, loc); err == nil {
+			return t, len(layout) == len("2006-01-02"), nil
+		}
+	}
+	return t, false, e.Invalid("value.time_format", s, DatetimeLayouts[0])
+}
+
+// 天和周按日历计算, 夏令时切换时仍对齐到当地零点
+func shift(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Add(time.Duration(n) * time.Second)
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, n)
+	default:
+		return t.AddDate(0, 0, 7*n)
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoerceDatetime(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	at := func(s string) time.Time {
+		t, _ := time.Parse(time.RFC3339, s)
+		return t
+	}
+	cases := []struct {
+		value string
+		want  interface{}
+	}{
+		{"2020-01-02 03:04:05", at("2020-01-01T19:04:05Z")},
+		{"2020-01-02T03:04:05Z", at("2020-01-02T03:04:05Z")},
+		{"2020-01-02", map[string]interface{}{"gte": at("2020-01-01T16:00:00Z"), "lt": at("2020-01-02T16:00:00Z")}},
+		{"2020-01-01..2020-01-02", map[string]interface{}{"gte": at("2019-12-31T16:00:00Z"), "lt": at("2020-01-02T16:00:00Z")}},
+		{"2020-01-01 08:00:00..2020-01-02 08:00:00", map[string]interface{}{"gte": at("2020-01-01T00:00:00Z"), "lte": at("2020-01-02T00:00:00Z")}},
+		{"..2020-01-02", map[string]interface{}{"lt": at("2020-01-02T16:00:00Z")}},
+		{" 2020-01-01 .. ", map[string]interface{}{"gte": at("2019-12-31T16:00:00Z")}},
+	}
+	for _, c := range cases {
+		got, err := TableField{IsTime: true}.Coerce(c.value, shanghai)
+		if err != nil {
+			t.Errorf("%q: %v", c.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: %v, want %v", c.value, got, c.want)
+		}
+	}
+
+	for _, s := range []string{"..", "2020-13-01", "yesterday", "now-7x", "2020-01-01..x"} {
+		if _, err := coerceDatetime(s, shanghai); err == nil {
+			t.Errorf("%q: no error", s)
+		}
+	}
+}
+
+func TestRelativeDatetime(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	cases := []struct {
+		value  string
+		offset time.Duration // 相对now的偏移, today的值按当地零点检查
+		day    bool
+	}{
+		{"now", 0, false},
+		{"now-90s", -90 * time.Second, false},
+		{"now+2h", 2 * time.Hour, false},
+		{"now-7d", -7 * 24 * time.Hour, false},
+		{"today", 0, true},
+		{"today-1d", -24 * time.Hour, true},
+		{"today+1w", 7 * 24 * time.Hour, true},
+		{"today+3h", 3 * time.Hour, false},
+	}
+	for _, c := range cases {
+		before := time.Now()
+		got, day, err := parseDatetime(c.value, shanghai)
+		after := time.Now()
+		if err != nil {
+			t.Errorf("%q: %v", c.value, err)
+			continue
+		}
+		if day != c.day {
+			t.Errorf("%q: day %v, want %v", c.value, day, c.day)
+		}
+		if strings.HasPrefix(c.value, "today") {
+			now := before.In(shanghai)
+			midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, shanghai)
+			if want := midnight.Add(c.offset); !got.Equal(want) {
+				t.Errorf("%q: %v, want %v", c.value, got, want)
+			}
+			continue
+		}
+		if got.Before(before.Add(c.offset)) || got.After(after.Add(c.offset)) {
+			t.Errorf("%q: %v not %v from now", c.value, got, c.offset)
+		}
+	}
+}
+
+// 天按日历计算, 夏令时切换的当天为23或25小时
+func TestDatetimeDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	got, err := coerceDatetime("2021-03-14", ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := got.(map[string]interface{})
+	if d := m["lt"].(time.Time).Sub(m["gte"].(time.Time)); d != 23*time.Hour {
+		t.Errorf("the spring forward day lasts %v", d)
+	}
+
+	fall := time.Date(2021, 11, 7, 0, 0, 0, 0, ny)
+	if next := shift(fall, 1, "d"); next.Sub(fall) != 25*time.Hour || next.Hour() != 0 {
+		t.Errorf("the fall back day ends at %v", next)
+	}
+	if prev := shift(time.Date(2021, 3, 21, 0, 0, 0, 0, ny), -1, "w"); !prev.Equal(time.Date(2021, 3, 14, 0, 0, 0, 0, ny)) {
+		t.Errorf("a week before the change is %v", prev)
+	}
+}
@@ -1,20 +1,33 @@
 package models
 
-import "reflect"
+import (
+	"fmt"
+	"reflect"
+)
 
 type table struct {
 	name         string                  // 表名
 	primaryKey   string                  // 主键
 	associations map[string]*Association // 关联关系
+	tenantColumn string                  // 租户字段, 为空时不区分租户
 }
 
+// NewTable declares a table whose primary key is id
 func NewTable(name string, associations map[string]*Association) Table {
 	return table{
 		name:         name,
+		primaryKey:   "id",
 		associations: associations,
 	}
 }
 
+// NewTenantTable declares a table shared by the tenants, the rows of a tenant are those whose tenantColumn is the tenant id
+func NewTenantTable(name, tenantColumn string, associations map[string]*Association) Table {
+	t := NewTable(name, associations).(table)
+	t.tenantColumn = tenantColumn
+	return t
+}
+
 func (t table) TableName() string {
 	return t.name
 }
@@ -26,14 +39,24 @@ func (t table) GetAssociation(name string) *Association {
 	return nil
 }
 
+func (t table) Associations() map[string]*Association {
+	return t.associations
+}
+
 func (t table) PrimaryKey() string {
 	return t.primaryKey
 }
 
+func (t table) TenantColumn() string {
+	return t.tenantColumn
+}
+
 type Table interface {
 	TableName() string
 	GetAssociation(name string) *Association
+	Associations() map[string]*Association
 	PrimaryKey() string
+	TenantColumn() string
 }
 
 type JoinMethod string
@@ -59,7 +82,32 @@ const (
 )
 
 type TableField struct {
-	Type       reflect.Kind
+	Type       reflect.Kind // 字段值的类型, 时间字段不使用
 	Name       string
 	Permission Permission
+	IsTime     bool // 日期时间字段, 查询及写入的值按 DatetimeLayouts 解析为time.Time
+}
+
+// Fields returns the table fields declared on a model such as Student
+func Fields(t Table) ([]TableField, error) {
+	pv := reflect.Indirect(reflect.ValueOf(t))
+	if pv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("model of table %s is not a struct", t.TableName())
+	}
+	tp := pv.Type()
+	var fields []TableField
+	for i := 0; i < pv.NumField(); i++ {
+		if tp.Field(i).Name == "Table" {
+			continue
+		}
+		f, ok := pv.Field(i).Interface().(TableField)
+		if !ok {
+			return nil, fmt.Errorf("field %s of table %s is not a TableField", tp.Field(i).Name, t.TableName())
+		}
+		if f.Name == "" {
+			continue
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
 }
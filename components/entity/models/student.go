@@ -18,20 +18,20 @@ var Student = studentModel{
 	Name: TableField{
 		Type:       reflect.String,
 		Name:       "name",
-		Permission: Read,
+		Permission: ReadWrite,
 	},
 	Sex: TableField{
 		Type:       reflect.Int,
 		Name:       "sex",
-		Permission: Read,
+		Permission: ReadWrite,
 	},
 	ClassId: TableField{
 		Type:       reflect.Int,
 		Name:       "class_id",
-		Permission: Read,
+		Permission: ReadWrite,
 	},
 	CreateTime: TableField{
-		Type:       reflect.String,
+		IsTime:     true,
 		Name:       "create_time",
 		Permission: Read,
 	},
@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-bread/components/entity/models"
+)
+
+var (
+	intTypes    = []string{"tinyint", "smallint", "mediumint", "int", "integer", "bigint", "year", "serial", "bigserial"}
+	boolTypes   = []string{"tinyint", "bit", "boolean", "bool"}
+	floatTypes  = []string{"float", "double", "decimal", "numeric", "real"}
+	stringTypes = []string{
+		"char", "varchar", "character", "text", "tinytext", "mediumtext", "longtext", "enum", "set", "json", "jsonb", "uuid",
+		// 时间类型可以作为字符串输出
+		"date", "datetime", "timestamp", "time",
+	}
+	timeTypes = []string{"date", "datetime", "timestamp", "time"}
+)
+
+// compatible reports whether a column of dataType can be read into the table field f
+func compatible(f models.TableField, dataType string) bool {
+	if f.IsTime {
+		return hasPrefix(dataType, timeTypes)
+	}
+	switch f.Type {
+	case reflect.Bool:
+		return hasPrefix(dataType, boolTypes)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return hasPrefix(dataType, intTypes)
+	case reflect.Float32, reflect.Float64:
+		return hasPrefix(dataType, floatTypes) || hasPrefix(dataType, intTypes)
+	case reflect.String:
+		return hasPrefix(dataType, stringTypes)
+	default:
+		return false
+	}
+}
+
+// datetime, int64
+func kindName(f models.TableField) string {
+	if f.IsTime {
+		return "datetime"
+	}
+	return f.Type.String()
+}
+
+// 兼容 "timestamp without time zone", "character varying" 等写法
+func hasPrefix(dataType string, types []string) bool {
+	for _, t := range types {
+		if dataType == t || strings.HasPrefix(dataType, t+" ") {
+			return true
+		}
+	}
+	return false
+}
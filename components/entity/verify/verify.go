@@ -0,0 +1,400 @@
+package verify
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-bread/components/database/condition"
+	outputs "github.com/go-bread/components/database/output"
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/validators/query"
+	"github.com/go-bread/validators/rule"
+)
+
+type Level string
+
+const (
+	Error   Level = "error"
+	Warning Level = "warning"
+)
+
+type Problem struct {
+	Level   Level
+	Group   consts.EntityGroupName
+	Field   string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Field == "" {
+		return fmt.Sprintf("[%s] %s: %s", p.Level, p.Group, p.Message)
+	}
+	return fmt.Sprintf("[%s] %s.%s: %s", p.Level, p.Group, p.Field, p.Message)
+}
+
+// Report aggregates the problems of all the entity groups
+type Report struct {
+	Problems []Problem
+}
+
+func (r *Report) HasErrors() bool {
+	for _, p := range r.Problems {
+		if p.Level == Error {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Report) String() string {
+	if len(r.Problems) == 0 {
+		return "entity check: ok"
+	}
+	lines := make([]string, 0, len(r.Problems)+1)
+	lines = append(lines, fmt.Sprintf("entity check: %d problem(s)", len(r.Problems)))
+	for _, p := range r.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (r *Report) add(level Level, gn consts.EntityGroupName, f, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, Problem{
+		Level:   level,
+		Group:   gn,
+		Field:   f,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// Verify checks the entity groups against the tables of the connected database
+func Verify(fieldsMap group.FieldsMap, tables map[string]*schema.Table) *Report {
+	r := &Report{}
+
+	names := make([]string, 0, len(fieldsMap))
+	for gn := range fieldsMap {
+		names = append(names, string(gn))
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		gn := consts.EntityGroupName(name)
+		g := fieldsMap[gn]
+		verifyFields(r, gn, g, tables)
+		verifyTables(r, gn, g, tables)
+		verifyVersions(r, gn, g)
+		verifyRules(r, gn, g)
+		verifySearch(r, gn, g, tables)
+		verifyPolicies(r, gn, g)
+		verifyScopes(r, gn, g)
+		verifyOrders(r, gn, g)
+	}
+
+	return r
+}
+
+func verifyFields(r *Report, gn consts.EntityGroupName, g group.EntityGroup, tables map[string]*schema.Table) {
+	verifyNamespace(r, gn, g.Entities, "")
+
+	g.Walk(func(name string, f field.Field) {
+		if f.Table == nil {
+			r.add(Error, gn, name, "table is not declared")
+			return
+		}
+		if f.TableField.Name == "" {
+			r.add(Error, gn, name, "table field is not declared")
+			return
+		}
+
+		t, ok := tables[f.Table.TableName()]
+		if !ok {
+			// 表不存在的错误由verifyTables统一报告
+			return
+		}
+		c, ok := t.Columns[f.TableField.Name]
+		if !ok {
+			r.add(Error, gn, name, "column %s.%s does not exist", t.Name, f.TableField.Name)
+			return
+		}
+		if !compatible(f.TableField, c.DataType) {
+			r.add(Error, gn, name, "type %s is not compatible with column %s.%s of type %s", kindName(f.TableField), t.Name, c.Name, c.DataType)
+		}
+
+		switch f.TimeFormat {
+		case "", outputs.TimeISO8601, outputs.TimeUnix, outputs.TimeUnixMilli, outputs.TimeDate:
+			if f.TimeFormat != "" && !f.TableField.IsTime {
+				r.add(Warning, gn, name, "time format %s is ignored, the field is not a time", f.TimeFormat)
+			}
+		default:
+			r.add(Error, gn, name, "unknown time format %s", f.TimeFormat)
+		}
+		if f.Validator == nil && f.Rule != "" {
+			if _, err := rule.Parse(f.Rule); err != nil {
+				r.add(Error, gn, name, "%v", err)
+			} else if !f.CanQuery {
+				r.add(Warning, gn, name, "rule is declared but the field can not be queried")
+			}
+		}
+		if f.Validator != nil {
+			if rv := reflect.ValueOf(f.Validator); rv.Kind() == reflect.Ptr && rv.IsNil() {
+				r.add(Error, gn, name, "validator %T is a nil pointer", f.Validator)
+			} else if !f.CanQuery {
+				r.add(Warning, gn, name, "validator is registered but the field can not be queried")
+			}
+		}
+		// 回调直接声明在字段上, 其返回值替换输出的原始值, 时间格式不再生效
+		if f.Callback != nil && f.TimeFormat != "" {
+			r.add(Warning, gn, name, "time format %s is ignored, the field is formatted by its callback", f.TimeFormat)
+		}
+		if f.Template != "" {
+			t, ok := condition.LookupTemplate(f.Template)
+			if !ok {
+				r.add(Error, gn, name, "template %s is not registered", f.Template)
+			} else if params := t.Params(); len(params) != 1 || params[0] != field.TemplateValue {
+				r.add(Error, gn, name, "template %s must have the single placeholder %s", f.Template, field.TemplateValue)
+			}
+		}
+		if f.Mask != nil {
+			switch f.Mask.Kind {
+			case outputs.MaskKeep:
+				if f.Mask.First < 0 || f.Mask.Last < 0 {
+					r.add(Error, gn, name, "mask keeps a negative number of characters")
+				}
+			case outputs.MaskHash, outputs.MaskRedact, outputs.MaskEmail:
+			default:
+				r.add(Error, gn, name, "unknown mask %s", f.Mask.Kind)
+			}
+		}
+	})
+}
+
+func verifyTables(r *Report, gn consts.EntityGroupName, g group.EntityGroup, tables map[string]*schema.Table) {
+	ts := g.Tables()
+	for _, t := range ts {
+		st, ok := tables[t.TableName()]
+		if !ok {
+			r.add(Error, gn, "", "table %s does not exist", t.TableName())
+			continue
+		}
+		if _, err := models.Fields(t); err != nil {
+			r.add(Error, gn, "", "%v", err)
+		}
+		verifyAssociations(r, gn, t, st, tables)
+		if col := t.TenantColumn(); col != "" {
+			if _, ok := st.Columns[col]; !ok {
+				r.add(Error, gn, "", "tenant column %s.%s does not exist", t.TableName(), col)
+			}
+		}
+	}
+
+	// 租户字段由请求所属的租户决定, 不能由客户端写入
+	g.Walk(func(name string, f field.Field) {
+		if f.Table != nil && f.Table.TenantColumn() == f.TableField.Name && f.TableField.Permission == models.ReadWrite {
+			r.add(Error, gn, name, "tenant column %s.%s must not be writable", f.Table.TableName(), f.TableField.Name)
+		}
+	})
+
+	// 多表关联查询必须声明驱动表, 且驱动表需要定义与其他表的关联关系
+	if len(ts) > 1 {
+		if g.JoinDriveTable == nil {
+			r.add(Error, gn, "", "multi-table group must declare JoinDriveTable")
+			return
+		}
+		for _, t := range ts {
+			if t.TableName() == g.JoinDriveTable.TableName() {
+				continue
+			}
+			if g.JoinDriveTable.GetAssociation(t.TableName()) == nil {
+				r.add(Error, gn, "", "association from %s to %s is not defined", g.JoinDriveTable.TableName(), t.TableName())
+			}
+		}
+	}
+}
+
+func verifyAssociations(r *Report, gn consts.EntityGroupName, t models.Table, st *schema.Table, tables map[string]*schema.Table) {
+	for name, ass := range t.Associations() {
+		if name != ass.TargetTable {
+			r.add(Warning, gn, "", "association %s.%s is looked up by target table %s", t.TableName(), name, ass.TargetTable)
+		}
+		if _, ok := st.Columns[ass.LocalKey]; !ok {
+			r.add(Error, gn, "", "association %s.%s: local key %s.%s does not exist", t.TableName(), name, t.TableName(), ass.LocalKey)
+		}
+		target, ok := tables[ass.TargetTable]
+		if !ok {
+			r.add(Error, gn, "", "association %s.%s: target table %s does not exist", t.TableName(), name, ass.TargetTable)
+			continue
+		}
+		if _, ok := target.Columns[ass.ForeignKey]; !ok {
+			r.add(Error, gn, "", "association %s.%s: foreign key %s.%s does not exist", t.TableName(), name, ass.TargetTable, ass.ForeignKey)
+		}
+	}
+}
+
+func verifyVersions(r *Report, gn consts.EntityGroupName, g group.EntityGroup) {
+	if g.DefaultVersion != "" {
+		if _, ok := g.Versions[g.DefaultVersion]; !ok {
+			r.add(Error, gn, "", "default version %s is not declared", g.DefaultVersion)
+		}
+	}
+	for v, ver := range g.Versions {
+		for alias, name := range ver.Aliases {
+			if _, ok := g.Lookup(name); !ok {
+				r.add(Error, gn, alias, "version %s: alias of unknown field %s", v, name)
+			}
+		}
+		for _, name := range ver.Removed {
+			if _, ok := g.Lookup(name); !ok {
+				if _, ok := ver.Aliases[name]; !ok {
+					r.add(Warning, gn, name, "version %s: removed field does not exist", v)
+				}
+			}
+		}
+	}
+}
+
+// 命名空间只能包含字段和命名空间, 名称中不能有分隔符
+func verifyNamespace(r *Report, gn consts.EntityGroupName, entities map[string]interface{}, prefix string) {
+	for k, v := range entities {
+		if strings.Contains(k, group.Separator) {
+			r.add(Error, gn, prefix+k, "name must not contain %q", group.Separator)
+		}
+		switch v := v.(type) {
+		case field.Field:
+		case map[string]interface{}:
+			verifyNamespace(r, gn, v, prefix+k+group.Separator)
+		default:
+			r.add(Error, gn, prefix+k, "unsupported entity type %T", v)
+		}
+	}
+}
+
+// 规则引用的字段必须存在且可以查询
+func verifyRules(r *Report, gn consts.EntityGroupName, g group.EntityGroup) {
+	for i, gr := range g.Rules {
+		if gr.Check == nil {
+			r.add(Error, gn, "", "rule %d has no check", i)
+		}
+		for _, name := range gr.Fields {
+			f, ok := g.Field(name)
+			if !ok {
+				r.add(Error, gn, name, "rule %d references an unknown field", i)
+				continue
+			}
+			if !f.CanQuery {
+				r.add(Warning, gn, name, "rule %d references a field that can not be queried", i)
+			}
+		}
+	}
+}
+
+func verifyPolicies(r *Report, gn consts.EntityGroupName, g group.EntityGroup) {
+	for i, p := range g.Policies {
+		if len(p.Filters) == 0 {
+			r.add(Error, gn, "", "policy %d has no filters", i)
+		}
+		for _, pf := range p.Filters {
+			f, ok := g.Field(pf.Field)
+			if !ok {
+				r.add(Error, gn, pf.Field, "policy %d references an unknown field", i)
+				continue
+			}
+			if !f.CanQuery {
+				r.add(Error, gn, pf.Field, "policy %d references a field that can not be queried", i)
+			}
+			if pf.MaxRange < 0 {
+				r.add(Error, gn, pf.Field, "policy %d has a negative range", i)
+			} else if pf.MaxRange > 0 && !f.TableField.IsTime {
+				r.add(Error, gn, pf.Field, "policy %d limits the range of a field that is not a time", i)
+			}
+		}
+	}
+}
+
+func verifyScopes(r *Report, gn consts.EntityGroupName, g group.EntityGroup) {
+	for i, sc := range g.Scopes {
+		if sc.Values == nil {
+			r.add(Warning, gn, sc.Field, "scope %d has no values, only the bypassing users can access the group", i)
+		}
+		if _, ok := g.Field(sc.Field); !ok {
+			r.add(Error, gn, sc.Field, "scope %d references an unknown field", i)
+		}
+	}
+}
+
+func verifyOrders(r *Report, gn consts.EntityGroupName, g group.EntityGroup) {
+	for i, o := range g.DefaultOrder {
+		f, ok := g.Field(o[0])
+		if !ok {
+			r.add(Error, gn, o[0], "default order %d references an unknown field", i)
+		} else if !f.CanOrder {
+			r.add(Error, gn, o[0], "default order %d references a field that can not be ordered", i)
+		}
+		if _, err := query.ParseDirection(o[1]); err != nil {
+			r.add(Error, gn, o[0], "default order %d has an invalid direction %q", i, o[1])
+		}
+	}
+}
+
+func verifySearch(r *Report, gn consts.EntityGroupName, g group.EntityGroup, tables map[string]*schema.Table) {
+	if g.Search == nil {
+		return
+	}
+	if len(g.Search.Fields) == 0 {
+		r.add(Error, gn, group.RelevanceField, "search declares no fields")
+		return
+	}
+
+	var fields []field.Field
+	for _, name := range g.Search.Fields {
+		f, ok := g.Field(name)
+		if !ok {
+			r.add(Error, gn, name, "search references an unknown field")
+			continue
+		}
+		if f.TableField.Type != reflect.String {
+			r.add(Warning, gn, name, "search field is not a string")
+		}
+		// _search按原值匹配, 脱敏的字段会被逐字猜出
+		if f.Mask != nil {
+			r.add(Error, gn, name, "search field is masked, _search matches its unmasked value")
+		}
+		fields = append(fields, f)
+	}
+	if !g.Search.FullText || len(fields) != len(g.Search.Fields) {
+		return
+	}
+
+	// MATCH 的字段必须属于同一张表, 且与某个FULLTEXT索引的字段完全一致
+	table := fields[0].Table.TableName()
+	columns := make(map[string]bool)
+	for _, f := range fields {
+		if f.Table.TableName() != table {
+			r.add(Error, gn, "", "full text search fields must belong to one table")
+			return
+		}
+		columns[f.TableField.Name] = true
+	}
+	st, ok := tables[table]
+	if !ok {
+		return
+	}
+	for _, cols := range st.Indexes {
+		if len(cols) != len(columns) {
+			continue
+		}
+		matched := true
+		for _, c := range cols {
+			matched = matched && columns[c]
+		}
+		if matched {
+			return
+		}
+	}
+	r.add(Warning, gn, "", "no index of table %s covers exactly the full text search fields", table)
+}
@@ -0,0 +1,202 @@
+package entity
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/go-bread/components/database"
+	outputs "github.com/go-bread/components/database/output"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/validators/query"
+)
+
+// 写入只针对实体组的驱动表, 更新及删除先按查询条件找出主键, 一次最多匹配 query.MaxPageSize 行;
+// 查找与写入在同一事务中, 匹配的行在事务结束前被锁定, 避免在两者之间被修改到行级权限的范围之外
+
+// Create validates values as a creation of the group and inserts them into its drive table, returns the primary key of the row
+func Create(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, version string, values map[string]interface{}) (int64, error) {
+	fm, err := loadWritableGroup(ctx, fieldsMap, gn, version)
+	if err != nil {
+		return 0, err
+	}
+	loc, err := ctx.Location()
+	if err != nil {
+		return 0, err
+	}
+
+	columns, err := buildValues(SceneCreate, loc, fm, values)
+	if err != nil {
+		return 0, err
+	}
+	warnDeprecated(ctx, fm, filterPaths(fm, values))
+	return database.Insert(ctx, fm.JoinDriveTable, columns)
+}
+
+// Update sets values on the rows matching the filters of params, returns the number of rows updated
+func Update(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams, values map[string]interface{}) (int64, error) {
+	fm, err := loadWritableGroup(ctx, fieldsMap, gn, params.Version)
+	if err != nil {
+		return 0, err
+	}
+	loc, err := ctx.Location()
+	if err != nil {
+		return 0, err
+	}
+
+	columns, err := buildValues(SceneUpdate, loc, fm, values)
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	err = models.GetDb().Transaction(func(tx *gorm.DB) error {
+		keys, err := matchedKeys(ctx, tx, fm, loc, params.QFields)
+		if err != nil {
+			return err
+		}
+		warnDeprecated(ctx, fm, append(filterPaths(fm, values), filterPaths(fm, params.QFields)...))
+		affected, err = database.Update(ctx, tx, fm.JoinDriveTable, keys, columns)
+		return err
+	})
+	return affected, err
+}
+
+// Delete deletes the rows matching the filters of params, returns the number of rows deleted.
+// 实体组必须声明Delete权限
+func Delete(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, params query.QParams) (int64, error) {
+	fm, err := loadWritableGroup(ctx, fieldsMap, gn, params.Version)
+	if err != nil {
+		return 0, err
+	}
+	if len(fm.Delete) == 0 {
+		return 0, e.New(e.ERROR_GROUP_READ_ONLY, gn)
+	}
+	if !fm.CanDelete() {
+		return 0, e.NewKey(e.ERROR_FORBIDDEN_ROW, "write.delete", gn)
+	}
+	loc, err := ctx.Location()
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	err = models.GetDb().Transaction(func(tx *gorm.DB) error {
+		keys, err := matchedKeys(ctx, tx, fm, loc, params.QFields)
+		if err != nil {
+			return err
+		}
+		warnDeprecated(ctx, fm, filterPaths(fm, params.QFields))
+		affected, err = database.Delete(ctx, tx, fm.JoinDriveTable, keys)
+		return err
+	})
+	return affected, err
+}
+
+// 没有驱动表的实体组不能写入
+func loadWritableGroup(ctx *caller.Caller, fieldsMap group.FieldsMap, gn consts.EntityGroupName, version string) (group.EntityGroup, error) {
+	fm, err := loadGroup(ctx, fieldsMap, gn, version)
+	if err != nil {
+		return fm, err
+	}
+	if fm.JoinDriveTable == nil {
+		return fm, e.New(e.ERROR_GROUP_READ_ONLY, gn)
+	}
+	return fm, nil
+}
+
+// buildValues validates the values of a write and returns them keyed by the column of the drive table
+func buildValues(scene string, loc *time.Location, g group.EntityGroup, params map[string]interface{}) (map[string]interface{}, error) {
+	if len(params) == 0 {
+		return nil, e.Invalid("write.values")
+	}
+	flat := make(map[string]interface{})
+	if err := flattenParams(g.Entities, params, "", flat); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(flat))
+	for p := range flat {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	drive := g.JoinDriveTable.TableName()
+	values := make(map[string]interface{}, len(paths))
+	columns := make(map[string]interface{}, len(paths))
+	for _, p := range paths {
+		ff, _ := g.Field(p)
+		ff.InputField = p
+		ff.SetLocation(loc)
+		if err := checkParamAccess(g, ff, scene); err != nil {
+			return nil, err
+		}
+		// 关联表的字段通过对应的实体组写入
+		if ff.Table.TableName() != drive {
+			return nil, e.New(e.ERROR_FIELD_READ_ONLY).WithField(p)
+		}
+		switch flat[p].(type) {
+		case []interface{}, map[string]interface{}:
+			return nil, e.Invalid("value.single").WithField(p)
+		}
+		v, err := ff.TableField.Coerce(flat[p], loc)
+		if err != nil {
+			return nil, e.AsInvalid(err).WithField(p)
+		}
+		if validator := ff.GetValidator(); validator != nil && v != nil {
+			if err := validator.Validate(v); err != nil {
+				return nil, e.AsInvalid(err).WithField(p)
+			}
+		}
+		// 如同一版本中的别名与原字段
+		if _, ok := columns[ff.TableField.Name]; ok {
+			return nil, e.Invalid("value.duplicate").WithField(p)
+		}
+		values[p] = v
+		columns[ff.TableField.Name] = v
+	}
+
+	if err := g.CheckRules(scene, values); err != nil {
+		return nil, err
+	}
+	// 值为null的限制字段同样需要检查, 避免将数据移出可以访问的范围
+	if err := g.CheckScopes(scene, values); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// matchedKeys locks and returns the primary keys of the rows matching filters in the transaction tx,
+// the same as a query of the group including its policies and scopes
+func matchedKeys(ctx *caller.Caller, tx *gorm.DB, g group.EntityGroup, loc *time.Location, filters map[string]interface{}) ([]interface{}, error) {
+	if len(filters) == 0 {
+		return nil, e.NewKey(e.ERROR_FILTER_REQUIRED, "write.filter")
+	}
+	conds, values, err := buildParams(nil, SceneQuery, loc, g, filters)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.CheckPolicies(values); err != nil {
+		return nil, err
+	}
+
+	pk := g.JoinDriveTable.PrimaryKey()
+	output := []*outputs.OutputField{{Table: g.JoinDriveTable.TableName(), TableField: pk, OutPut: pk}}
+	page := query.Pagination{Page: 1, PageSize: query.MaxPageSize}
+	rows, err := database.QueryForUpdate(ctx, tx, g, conds, output, &page)
+	if err != nil {
+		return nil, err
+	}
+	if page.TotalCount > query.MaxPageSize {
+		return nil, e.New(e.ERROR_WRITE_LIMIT, page.TotalCount, query.MaxPageSize)
+	}
+
+	keys := make([]interface{}, len(rows))
+	for i, r := range rows {
+		keys[i] = r[pk]
+	}
+	return keys, nil
+}
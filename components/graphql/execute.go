@@ -0,0 +1,921 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	ut "github.com/go-playground/universal-translator"
+
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/validators/query"
+)
+
+// Request is the body of a GraphQL request
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the result of a request, Data is absent when the request is rejected before execution
+type Response struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []*Error    `json:"errors,omitempty"`
+}
+
+// 展开片段后查询的限制, 内省查询的深度为13
+const (
+	MaxDepth      = 15   // 选择集的最大嵌套层数
+	MaxComplexity = 1000 // 选择的字段总数
+)
+
+// selectionSize is the number of fields and the depth of a selection set with its fragments expanded
+type selectionSize struct {
+	fields int
+	depth  int
+}
+
+// add counts the fields of other nested in a field, fields saturates above MaxComplexity
+func (s *selectionSize) add(other selectionSize) {
+	s.fields += 1 + other.fields
+	if s.fields > MaxComplexity {
+		s.fields = MaxComplexity + 1
+	}
+	if other.depth+1 > s.depth {
+		s.depth = other.depth + 1
+	}
+}
+
+// merge counts the fields of other at the same level, e.g. a fragment
+func (s *selectionSize) merge(other selectionSize) {
+	s.fields += other.fields
+	if s.fields > MaxComplexity {
+		s.fields = MaxComplexity + 1
+	}
+	if other.depth > s.depth {
+		s.depth = other.depth
+	}
+}
+
+type executor struct {
+	ctx    *gin.Context
+	schema *Schema
+	guard  entity.BatchGuard
+	doc    *document
+	trans  ut.Translator
+
+	varDefs map[string]*varDef
+	varType map[string]*Type
+	vars    map[string]interface{}
+	args    map[*fieldNode]map[string]interface{} // 校验时转换的各字段的参数
+	errors  []*Error
+
+	fragments map[string]selectionSize // 已校验的片段, 每个片段只校验一次
+}
+
+// Execute validates the request and resolves its root fields, the fields of the entity groups are queried
+// as a batch by entity.QueryBatch, so the permissions, policies and callbacks are the same as /list/:form.
+// guard is called before each query, see entity.BatchGuard. ok is false when the request is rejected before execution.
+func Execute(ctx *gin.Context, s *Schema, req Request, guard entity.BatchGuard) (resp *Response, ok bool) {
+	ex := newExecutor(ctx, s, guard)
+	op, err := ex.prepare(req)
+	if err != nil {
+		gerr, ok := err.(*Error)
+		if !ok {
+			gerr = &Error{Message: err.Error()}
+		}
+		gerr.Extensions = map[string]interface{}{"code": e.INVALID_PARAMS}
+		return &Response{Errors: []*Error{gerr}}, false
+	}
+
+	data := ex.execute(op)
+	return &Response{Data: data, Errors: ex.errors}, true
+}
+
+func newExecutor(ctx *gin.Context, s *Schema, guard entity.BatchGuard) *executor {
+	return &executor{
+		ctx:       ctx,
+		schema:    s,
+		guard:     guard,
+		trans:     e.Translator(ctx.GetHeader("Accept-Language")),
+		varDefs:   make(map[string]*varDef),
+		varType:   make(map[string]*Type),
+		vars:      make(map[string]interface{}),
+		args:      make(map[*fieldNode]map[string]interface{}),
+		fragments: make(map[string]selectionSize),
+	}
+}
+
+// prepare parses and validates the request, returns the operation to execute
+func (ex *executor) prepare(req Request) (*operation, error) {
+	if req.Query == "" {
+		return nil, &Error{Message: "the query is required"}
+	}
+	doc, err := parse(req.Query)
+	if err != nil {
+		return nil, err
+	}
+	ex.doc = doc
+
+	var op *operation
+	for _, o := range doc.operations {
+		if o.name == "" && len(doc.operations) > 1 {
+			return nil, newError(o.loc, "an anonymous operation must be the only operation of the document")
+		}
+		if req.OperationName == "" || o.name == req.OperationName {
+			if op != nil {
+				return nil, &Error{Message: "operationName is required for a document of several operations"}
+			}
+			op = o
+		}
+	}
+	if op == nil {
+		return nil, &Error{Message: fmt.Sprintf("unknown operation %s", req.OperationName)}
+	}
+	if op.kind != "query" {
+		return nil, newError(op.loc, "%s is not supported, the schema only provides queries", op.kind)
+	}
+
+	if err := ex.coerceVariables(op, req.Variables); err != nil {
+		return nil, err
+	}
+	if err := ex.checkDirectives(op.directives); err != nil {
+		return nil, err
+	}
+	size, err := ex.validate(ex.schema.Query, op.selections, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	if size.depth > MaxDepth {
+		return nil, newError(op.loc, "the query is nested %d levels deep, at most %d", size.depth, MaxDepth)
+	}
+	if size.fields > MaxComplexity {
+		return nil, newError(op.loc, "the query selects more than %d fields with its fragments expanded", MaxComplexity)
+	}
+	return op, nil
+}
+
+func (ex *executor) coerceVariables(op *operation, values map[string]interface{}) error {
+	for _, d := range op.vars {
+		if _, ok := ex.varDefs[d.name]; ok {
+			return newError(d.loc, "variable $%s is defined more than once", d.name)
+		}
+		t, err := ex.inputType(d.typ)
+		if err != nil {
+			return newError(d.loc, "variable $%s: %v", d.name, err)
+		}
+		ex.varDefs[d.name] = d
+		ex.varType[d.name] = t
+
+		raw, ok := values[d.name]
+		if !ok {
+			if d.def != nil {
+				c, err := coerceLiteral(d.def, t, nil)
+				if err != nil {
+					return newError(d.loc, "variable $%s: default value: %v", d.name, err)
+				}
+				ex.vars[d.name] = c
+			} else if t.Kind == KindNonNull {
+				return newError(d.loc, "variable $%s of type %s is required", d.name, t)
+			}
+			continue
+		}
+		c, err := coerceJSON(raw, t)
+		if err != nil {
+			return newError(d.loc, "variable $%s: %v", d.name, err)
+		}
+		ex.vars[d.name] = c
+	}
+	return nil
+}
+
+// inputType resolves the type of a variable, only scalars, enums and input objects can be inputs
+func (ex *executor) inputType(ref *typeRef) (*Type, error) {
+	var t *Type
+	if ref.elem != nil {
+		elem, err := ex.inputType(ref.elem)
+		if err != nil {
+			return nil, err
+		}
+		t = listOf(elem)
+	} else {
+		named, ok := ex.schema.Types[ref.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %s", ref.name)
+		}
+		if named.Kind == KindObject || named == jsonType {
+			return nil, fmt.Errorf("type %s can not be used as an input", ref.name)
+		}
+		t = named
+	}
+	if ref.nonNull {
+		t = nonNull(t)
+	}
+	return t, nil
+}
+
+// validate checks the fields of the selections on t and coerces their arguments, returns the size of the selections.
+// 片段的类型条件必须与所在的类型相同, 所以每个片段只需校验一次, 再次展开时使用其大小
+func (ex *executor) validate(t *Type, sels []selection, visiting map[string]bool) (selectionSize, error) {
+	var size selectionSize
+	for _, sel := range sels {
+		switch s := sel.(type) {
+		case *fieldNode:
+			if err := ex.checkDirectives(s.directives); err != nil {
+				return size, err
+			}
+			fd := ex.field(t, s.name)
+			if fd == nil {
+				return size, newError(s.loc, "field %s is not defined by type %s", s.name, t.Name)
+			}
+			args, err := ex.coerceArgs(fd.Args, s.args, s.loc)
+			if err != nil {
+				return size, err
+			}
+			ex.args[s] = args
+
+			nt := fd.Type.named()
+			if nt.Kind != KindObject {
+				if len(s.selections) > 0 {
+					return size, newError(s.loc, "field %s of type %s has no subfields", s.name, fd.Type)
+				}
+				size.add(selectionSize{})
+				continue
+			}
+			if len(s.selections) == 0 {
+				return size, newError(s.loc, "field %s of type %s must select subfields", s.name, fd.Type)
+			}
+			sub, err := ex.validate(nt, s.selections, visiting)
+			if err != nil {
+				return size, err
+			}
+			size.add(sub)
+		case *inlineFragment:
+			if err := ex.checkDirectives(s.directives); err != nil {
+				return size, err
+			}
+			if err := ex.checkTypeCondition(t, s.typeCondition, s.loc); err != nil {
+				return size, err
+			}
+			sub, err := ex.validate(t, s.selections, visiting)
+			if err != nil {
+				return size, err
+			}
+			size.merge(sub)
+		case *fragmentSpread:
+			if err := ex.checkDirectives(s.directives); err != nil {
+				return size, err
+			}
+			f, ok := ex.doc.fragments[s.name]
+			if !ok {
+				return size, newError(s.loc, "unknown fragment %s", s.name)
+			}
+			if visiting[s.name] {
+				return size, newError(s.loc, "fragment %s spreads itself", s.name)
+			}
+			if err := ex.checkTypeCondition(t, f.typeCondition, f.loc); err != nil {
+				return size, err
+			}
+			sub, ok := ex.fragments[s.name]
+			if !ok {
+				visiting[s.name] = true
+				var err error
+				sub, err = ex.validate(t, f.selections, visiting)
+				delete(visiting, s.name)
+				if err != nil {
+					return size, err
+				}
+				ex.fragments[s.name] = sub
+			}
+			size.merge(sub)
+		}
+	}
+	return size, nil
+}
+
+// 没有接口及联合类型, 片段的类型必须与所在的类型相同
+func (ex *executor) checkTypeCondition(t *Type, cond string, loc Location) error {
+	if cond == "" || cond == t.Name {
+		return nil
+	}
+	if _, ok := ex.schema.Types[cond]; !ok {
+		return newError(loc, "unknown type %s", cond)
+	}
+	return newError(loc, "a fragment on %s can not be spread on type %s", cond, t.Name)
+}
+
+// field returns the definition of a field of t including the meta fields
+func (ex *executor) field(t *Type, name string) *Field {
+	switch {
+	case name == typenameMetaField.Name:
+		return typenameMetaField
+	case t == ex.schema.Query && name == ex.schema.schemaField.Name:
+		return ex.schema.schemaField
+	case t == ex.schema.Query && name == ex.schema.typeField.Name:
+		return ex.schema.typeField
+	}
+	return t.Field(name)
+}
+
+func (ex *executor) coerceArgs(defs []*InputValue, given []*argument, loc Location) (map[string]interface{}, error) {
+	byName := make(map[string]*argument, len(given))
+	for _, a := range given {
+		if _, ok := byName[a.name]; ok {
+			return nil, newError(a.loc, "argument %s is given more than once", a.name)
+		}
+		found := false
+		for _, d := range defs {
+			if d.Name == a.name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, newError(a.loc, "unknown argument %s", a.name)
+		}
+		byName[a.name] = a
+	}
+
+	args := make(map[string]interface{}, len(defs))
+	for _, d := range defs {
+		a, ok := byName[d.Name]
+		if ok {
+			if err := ex.checkVariables(a.value, d.Type); err != nil {
+				return nil, newError(a.loc, "argument %s: %v", d.Name, err)
+			}
+			// 未提供的变量与未给出的参数相同
+			if a.value.kind == valueVariable {
+				if _, provided := ex.vars[a.value.raw]; !provided {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			if err := defaultValue(d, args); err != nil {
+				return nil, newError(loc, "argument %v", err)
+			}
+			continue
+		}
+		c, err := coerceLiteral(a.value, d.Type, ex.vars)
+		if err != nil {
+			return nil, newError(a.loc, "argument %s: %v", d.Name, err)
+		}
+		args[d.Name] = c
+	}
+	return args, nil
+}
+
+// checkVariables checks that the variables used in v are defined and of a type usable as t
+func (ex *executor) checkVariables(v *value, t *Type) error {
+	switch v.kind {
+	case valueVariable:
+		d, ok := ex.varDefs[v.raw]
+		if !ok {
+			return fmt.Errorf("variable $%s is not defined", v.raw)
+		}
+		if !compatible(ex.varType[v.raw], t, d.def != nil) {
+			return fmt.Errorf("variable $%s of type %s can not be used as %s", v.raw, ex.varType[v.raw], t)
+		}
+	case valueList:
+		if t.Kind == KindNonNull {
+			t = t.OfType
+		}
+		if t.Kind != KindList {
+			return nil
+		}
+		for _, item := range v.list {
+			if err := ex.checkVariables(item, t.OfType); err != nil {
+				return err
+			}
+		}
+	case valueObject:
+		t = t.named()
+		for _, f := range v.fields {
+			if ft := t.InputField(f.name); ft != nil {
+				if err := ex.checkVariables(f.value, ft.Type); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// compatible reports whether a variable of type v can be used where loc is expected,
+// a nullable variable with a default can be used as non null
+func compatible(v, loc *Type, hasDefault bool) bool {
+	if loc.Kind == KindNonNull {
+		if v.Kind != KindNonNull {
+			if !hasDefault {
+				return false
+			}
+			return compatible(v, loc.OfType, false)
+		}
+		return compatible(v.OfType, loc.OfType, false)
+	}
+	if v.Kind == KindNonNull {
+		return compatible(v.OfType, loc, false)
+	}
+	if loc.Kind == KindList {
+		return v.Kind == KindList && compatible(v.OfType, loc.OfType, false)
+	}
+	return v.Kind != KindList && v.Name == loc.Name
+}
+
+func (ex *executor) checkDirectives(ds []*directive) error {
+	for _, d := range ds {
+		if d.name != "skip" && d.name != "include" {
+			return newError(d.loc, "unknown directive @%s", d.name)
+		}
+		def := []*InputValue{{Name: "if", Type: nonNull(booleanType)}}
+		if _, err := ex.coerceArgs(def, d.args, d.loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// included evaluates @skip and @include
+func (ex *executor) included(ds []*directive) bool {
+	for _, d := range ds {
+		args, err := ex.coerceArgs([]*InputValue{{Name: "if", Type: nonNull(booleanType)}}, d.args, d.loc)
+		if err != nil {
+			continue
+		}
+		cond, _ := args["if"].(bool)
+		if d.name == "skip" && cond || d.name == "include" && !cond {
+			return false
+		}
+	}
+	return true
+}
+
+// collected is a field of the response, the nodes of the same response key are merged
+type collected struct {
+	key   string
+	name  string
+	nodes []*fieldNode
+}
+
+// collect returns the fields selected on t in order, see https://spec.graphql.org/October2021/#CollectFields()
+func (ex *executor) collect(t *Type, sels []selection) ([]*collected, error) {
+	var fields []*collected
+	index := make(map[string]*collected)
+	var walk func(sels []selection, visited map[string]bool) error
+	walk = func(sels []selection, visited map[string]bool) error {
+		for _, sel := range sels {
+			switch s := sel.(type) {
+			case *fieldNode:
+				if !ex.included(s.directives) {
+					continue
+				}
+				key := s.responseKey()
+				c, ok := index[key]
+				if !ok {
+					c = &collected{key: key, name: s.name}
+					index[key] = c
+					fields = append(fields, c)
+				} else if c.name != s.name || !reflect.DeepEqual(ex.args[c.nodes[0]], ex.args[s]) {
+					return newError(s.loc, "fields of the response key %s conflict, use different aliases", key)
+				}
+				c.nodes = append(c.nodes, s)
+			case *inlineFragment:
+				if ex.included(s.directives) && (s.typeCondition == "" || s.typeCondition == t.Name) {
+					if err := walk(s.selections, visited); err != nil {
+						return err
+					}
+				}
+			case *fragmentSpread:
+				f := ex.doc.fragments[s.name]
+				if visited[s.name] || !ex.included(s.directives) || f.typeCondition != t.Name {
+					continue
+				}
+				visited[s.name] = true
+				if err := walk(f.selections, visited); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return fields, walk(sels, make(map[string]bool))
+}
+
+// subselections merges the selections of the nodes of a field
+func subselections(nodes []*fieldNode) []selection {
+	if len(nodes) == 1 {
+		return nodes[0].selections
+	}
+	var sels []selection
+	for _, n := range nodes {
+		sels = append(sels, n.selections...)
+	}
+	return sels
+}
+
+// execute resolves the root fields, the queries of the entity groups run concurrently as a batch
+func (ex *executor) execute(op *operation) interface{} {
+	root := ex.schema.Query
+	fields, err := ex.collect(root, op.selections)
+	if err != nil {
+		ex.errors = append(ex.errors, err.(*Error))
+		return nil
+	}
+
+	var queries []entity.BatchQuery
+	planned := make(map[string]int)
+	failed := make(map[string]error)
+	for _, c := range fields {
+		fd := ex.field(root, c.name)
+		if fd.group == "" {
+			continue
+		}
+		q, err := ex.plan(c, fd)
+		if err != nil {
+			failed[c.key] = err
+			continue
+		}
+		planned[c.key] = len(queries)
+		queries = append(queries, q)
+	}
+	results := entity.QueryBatch(caller.FromGin(ex.ctx), entity.FieldsMap, queries, ex.guard)
+	for _, r := range results {
+		for _, k := range []string{"Deprecation", "Warning"} {
+			for _, v := range r.Header[k] {
+				ex.ctx.Writer.Header().Add(k, v)
+			}
+		}
+	}
+
+	data := make(object, 0, len(fields))
+	for _, c := range fields {
+		path := []interface{}{c.key}
+		fd := ex.field(root, c.name)
+		var v interface{}
+		switch {
+		case c.name == typenameMetaField.Name:
+			v = root.Name
+		case fd == ex.schema.schemaField:
+			v = ex.schema
+		case fd == ex.schema.typeField:
+			if t, ok := ex.schema.Types[ex.args[c.nodes[0]]["name"].(string)]; ok {
+				v = t
+			}
+		case failed[c.key] != nil:
+			ex.fieldError(failed[c.key], path)
+			data = append(data, member{c.key, nil})
+			continue
+		default:
+			r := results[planned[c.key]]
+			if r.Err != nil {
+				ex.fieldError(r.Err, path)
+				data = append(data, member{c.key, nil})
+				continue
+			}
+			if v, err = connection(r.Data); err != nil {
+				ex.fieldError(err, path)
+				data = append(data, member{c.key, nil})
+				continue
+			}
+		}
+		value, st := ex.complete(fd.Type, v, c.nodes, path)
+		if st == invalid {
+			// Query的字段除__schema外都可以为空, __schema不会出错
+			return nil
+		}
+		data = append(data, member{c.key, value})
+	}
+	return data
+}
+
+// plan builds the query document of a root field, the selected leaf fields of nodes are the returned fields
+func (ex *executor) plan(c *collected, fd *Field) (entity.BatchQuery, error) {
+	args := ex.args[c.nodes[0]]
+	doc := make(map[string]interface{})
+
+	for _, a := range fd.Args {
+		v, ok := args[a.Name]
+		if !ok || v == nil {
+			continue
+		}
+		switch a.Name {
+		case "filter":
+			if err := filterDocument(v.(map[string]interface{}), a.Type, doc, ""); err != nil {
+				return entity.BatchQuery{}, err
+			}
+		case "search":
+			doc["_search"] = v
+		case "orderBy":
+			var orders [][2]string
+			for _, o := range v.([]interface{}) {
+				m := o.(map[string]interface{})
+				d, _ := m["direction"].(string)
+				if d == "" {
+					d = query.Asc
+				}
+				orders = append(orders, [2]string{m["field"].(string), d})
+			}
+			doc["_order_by"] = orders
+		case "page":
+			n, err := strconv.ParseInt(string(v.(json.Number)), 10, 64)
+			if err != nil || n < 1 {
+				return entity.BatchQuery{}, e.New(e.INVALID_PARAMS, "page must be at least 1").WithField("page")
+			}
+			doc["_page"] = n
+		case "pageSize":
+			n, err := strconv.ParseInt(string(v.(json.Number)), 10, 64)
+			if err != nil || n < 1 || n > query.MaxPageSize {
+				return entity.BatchQuery{}, e.New(e.INVALID_PARAMS, fmt.Sprintf("pageSize must be between 1 and %d", query.MaxPageSize)).WithField("pageSize")
+			}
+			doc["_page_size"] = n
+		}
+	}
+	// 总是分页, 未给出页码时为第一页
+	if _, ok := doc["_page"]; !ok {
+		doc["_page"] = query.DefaultPage
+	}
+	if _, ok := doc["_page_size"]; !ok {
+		doc["_page_size"] = query.DefaultPageSize
+	}
+
+	conn := fd.Type.named()
+	node := conn.Field("nodes").Type.named()
+	var fields []string
+	sub, err := ex.collect(conn, subselections(c.nodes))
+	if err != nil {
+		return entity.BatchQuery{}, err
+	}
+	for _, s := range sub {
+		if s.name == "nodes" {
+			if fields, err = ex.paths(node, s.nodes, "", fields); err != nil {
+				return entity.BatchQuery{}, err
+			}
+		}
+	}
+	// 只查询总数时仍需要一个输出字段
+	if len(fields) == 0 {
+		fields = append(fields, firstLeaf(node, ""))
+	}
+	doc["fields"] = fields
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return entity.BatchQuery{}, err
+	}
+	return entity.BatchQuery{Name: c.key, Form: string(fd.group), Query: raw}, nil
+}
+
+// paths appends the paths of the leaf fields selected on t, e.g. class.name
+func (ex *executor) paths(t *Type, nodes []*fieldNode, prefix string, fields []string) ([]string, error) {
+	sub, err := ex.collect(t, subselections(nodes))
+	if err != nil {
+		return nil, err
+	}
+	n := len(fields)
+	for _, s := range sub {
+		if s.name == typenameMetaField.Name {
+			continue
+		}
+		ft := t.Field(s.name).Type.named()
+		if ft.Kind == KindObject {
+			if fields, err = ex.paths(ft, s.nodes, prefix+s.name+group.Separator, fields); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		fields = append(fields, prefix+s.name)
+	}
+	// 命名空间只选择了__typename时, 查询一个字段使其输出为对象
+	if len(fields) == n && prefix != "" {
+		fields = append(fields, firstLeaf(t, prefix))
+	}
+	return fields, nil
+}
+
+func firstLeaf(t *Type, prefix string) string {
+	f := t.Fields[0]
+	if nt := f.Type.named(); nt.Kind == KindObject {
+		return firstLeaf(nt, prefix+f.Name+group.Separator)
+	}
+	return prefix + f.Name
+}
+
+// filterDocument translates the filter argument to the conditions of a query document
+func filterDocument(m map[string]interface{}, t *Type, doc map[string]interface{}, prefix string) error {
+	t = t.named()
+	for _, f := range t.InputFields {
+		v, ok := m[f.Name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ft := f.Type.named()
+		if ft.InputFields[0].op == "" {
+			ns := make(map[string]interface{})
+			if err := filterDocument(v, ft, ns, prefix+f.Name+group.Separator); err != nil {
+				return err
+			}
+			if len(ns) > 0 {
+				doc[f.Name] = ns
+			}
+			continue
+		}
+
+		// 与查询文档相同: eq为单个值, in为列表, 其他操作符为对象
+		conds := make(map[string]interface{})
+		var eq, in interface{}
+		for _, op := range ft.InputFields {
+			c, ok := v[op.Name]
+			if !ok || c == nil {
+				continue
+			}
+			switch op.Name {
+			case "eq":
+				eq = c
+			case "in":
+				in = c
+			default:
+				conds[op.Name] = c
+			}
+		}
+		switch {
+		case eq != nil && (in != nil || len(conds) > 0), in != nil && len(conds) > 0:
+			return e.New(e.INVALID_PARAMS, "eq and in can not be combined with other conditions").WithField(prefix + f.Name)
+		case eq != nil:
+			doc[f.Name] = eq
+		case in != nil:
+			doc[f.Name] = in
+		case len(conds) > 0:
+			doc[f.Name] = conds
+		}
+	}
+	return nil
+}
+
+// connection converts the result of a query to the value of a connection
+func connection(data interface{}) (interface{}, error) {
+	// 结果转换为json的通用形式, 与批量查询的引用相同
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		List []interface{}    `json:"list"`
+		Page query.Pagination `json:"page_info"`
+	}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&result); err != nil {
+		return nil, err
+	}
+	p := result.Page
+	return map[string]interface{}{
+		"nodes": result.List,
+		"pageInfo": map[string]interface{}{
+			"page":        p.Page,
+			"pageSize":    p.PageSize,
+			"totalCount":  p.TotalCount,
+			"hasNextPage": uint64(p.Page)*uint64(p.PageSize) < uint64(p.TotalCount),
+		},
+		"totalCount": p.TotalCount,
+	}, nil
+}
+
+// 值的完成状态, 非空的字段为空时, 错误向上传递到最近的可以为空的位置
+const (
+	completed = iota
+	nulled    // 子字段出错, 已置为null
+	invalid   // 非空的位置为null, 需由上层置为null
+)
+
+// complete shapes v as t, objects are resolved by the selections of nodes
+func (ex *executor) complete(t *Type, v interface{}, nodes []*fieldNode, path []interface{}) (interface{}, int) {
+	if t.Kind == KindNonNull {
+		r, st := ex.complete(t.OfType, v, nodes, path)
+		if st != completed {
+			return nil, invalid
+		}
+		if r == nil {
+			ex.errors = append(ex.errors, &Error{Message: "null value of the non null field " + nodes[0].name, Path: path})
+			return nil, invalid
+		}
+		return r, completed
+	}
+	if isNil(v) {
+		return nil, completed
+	}
+
+	switch t.Kind {
+	case KindList:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			ex.errors = append(ex.errors, &Error{Message: "expected a list for the field " + nodes[0].name, Path: path})
+			return nil, nulled
+		}
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			r, st := ex.complete(t.OfType, rv.Index(i).Interface(), nodes, appendPath(path, i))
+			if st == invalid {
+				return nil, nulled
+			}
+			list[i] = r
+		}
+		return list, completed
+	case KindObject:
+		fields, err := ex.collect(t, subselections(nodes))
+		if err != nil {
+			ex.errors = append(ex.errors, err.(*Error))
+			return nil, nulled
+		}
+		obj := make(object, 0, len(fields))
+		for _, c := range fields {
+			if c.name == typenameMetaField.Name {
+				obj = append(obj, member{c.key, t.Name})
+				continue
+			}
+			fd := t.Field(c.name)
+			var fv interface{}
+			if m, ok := v.(map[string]interface{}); ok {
+				fv = m[c.name]
+			} else {
+				fv = introspect(v, c.name, ex.args[c.nodes[0]])
+			}
+			r, st := ex.complete(fd.Type, fv, c.nodes, appendPath(path, c.key))
+			if st == invalid {
+				return nil, nulled
+			}
+			obj = append(obj, member{c.key, r})
+		}
+		return obj, completed
+	}
+	return v, completed
+}
+
+func (ex *executor) fieldError(err error, path []interface{}) {
+	ee := e.As(err)
+	if ee.Status() >= 500 {
+		log.Printf("[ERROR] %s %s: field %v: %v", ex.ctx.Request.Method, ex.ctx.Request.URL.Path, path[0], ee)
+	}
+	r := ee.Render(ex.trans)
+	ext := map[string]interface{}{"code": r.Code, "status": ee.Status()}
+	if r.Field != "" {
+		ext["field"] = r.Field
+	}
+	ex.errors = append(ex.errors, &Error{Message: r.Message, Path: path, Extensions: ext})
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, key)
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// object is a JSON object keeping the order of the selected fields
+type object []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
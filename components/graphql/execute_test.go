@@ -0,0 +1,302 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testSchema is a schema of a recursive type, the fields of the groups are not resolved by the tests
+func testSchema() *Schema {
+	node := &Type{Kind: KindObject, Name: "Node"}
+	node.Fields = []*Field{
+		{Name: "id", Type: nonNull(intType)},
+		{Name: "name", Type: stringType},
+		{Name: "next", Type: node},
+		{Name: "children", Args: []*InputValue{{Name: "first", Type: intType, DefaultValue: "10"}}, Type: listOf(nonNull(node))},
+	}
+	filter := &Type{Kind: KindInputObject, Name: "NodeFilter", InputFields: []*InputValue{
+		{Name: "id", Type: intType},
+		{Name: "names", Type: listOf(nonNull(stringType))},
+		{Name: "after", Type: dateTimeType, DefaultValue: `"2020-01-01 00:00:00"`},
+	}}
+	s := &Schema{
+		Query: &Type{Kind: KindObject, Name: "Query", Fields: []*Field{
+			{Name: "node", Args: []*InputValue{{Name: "id", Type: nonNull(intType)}}, Type: node},
+			{Name: "nodes", Args: []*InputValue{{Name: "filter", Type: filter}, {Name: "ids", Type: listOf(nonNull(intType))}}, Type: listOf(nonNull(node))},
+		}},
+		Types: make(map[string]*Type),
+	}
+	for _, t := range []*Type{intType, floatType, stringType, booleanType, dateTimeType, jsonType, node, filter, s.Query} {
+		s.Types[t.Name] = t
+	}
+	addIntrospection(s)
+	return s
+}
+
+func prepare(query string, vars map[string]interface{}) (*executor, *operation, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/graphql", nil)
+	ex := newExecutor(c, testSchema(), nil)
+	op, err := ex.prepare(Request{Query: query, Variables: vars})
+	return ex, op, err
+}
+
+func TestValidateErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		msg   string
+	}{
+		{"unknown field", "{ node(id: 1) { age } }", "field age is not defined by type Node"},
+		{"missing subfields", "{ node(id: 1) }", "must select subfields"},
+		{"subfields of a scalar", "{ node(id: 1) { id { a } } }", "has no subfields"},
+		{"missing argument", "{ node { id } }", "field id of type Int! is required"},
+		{"unknown argument", "{ node(id: 1, age: 2) { id } }", "unknown argument age"},
+		{"unknown directive", "{ node(id: 1) @defer { id } }", "unknown directive @defer"},
+		{"unknown fragment", "{ node(id: 1) { ...F } }", "unknown fragment F"},
+		{"fragment on another type", "{ node(id: 1) { ...F } } fragment F on Query { node(id: 2) { id } }", "a fragment on Query can not be spread on type Node"},
+		{"fragment cycle", "{ node(id: 1) { ...A } } fragment A on Node { next { ...B } } fragment B on Node { children { ...A } }", "fragment A spreads itself"},
+		{"fragment spreads itself", "{ node(id: 1) { ...A } } fragment A on Node { id ...A }", "fragment A spreads itself"},
+		{"mutation", "mutation { node(id: 1) { id } }", "mutation is not supported"},
+		{"undefined variable", "{ node(id: $id) { id } }", "variable $id is not defined"},
+		{"variable of another type", "query($id: String = \"1\") { node(id: $id) { id } }", "variable $id of type String can not be used as Int!"},
+		{"nullable variable", "query($id: Int) { node(id: $id) { id } }", "variable $id of type Int can not be used as Int!"},
+		{"object variable", "query($n: Node) { node(id: 1) { id } }", "type Node can not be used as an input"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := prepare(c.query, nil)
+			if err == nil || !strings.Contains(err.Error(), c.msg) {
+				t.Errorf("prepare(%q) = %v, want %q", c.query, err, c.msg)
+			}
+		})
+	}
+}
+
+// 片段的每次展开不重新校验, 指数增长的展开次数由 MaxComplexity 限制
+func TestValidateFragmentChain(t *testing.T) {
+	const levels = 40
+	var b strings.Builder
+	b.WriteString("{ node(id: 1) { ...F0 } }\n")
+	for i := 0; i < levels; i++ {
+		b.WriteString("fragment F" + strconv.Itoa(i) + " on Node { a: next { ...F" + strconv.Itoa(i+1) + " } b: next { ...F" + strconv.Itoa(i+1) + " } }\n")
+	}
+	b.WriteString("fragment F" + strconv.Itoa(levels) + " on Node { id }\n")
+
+	start := time.Now()
+	ex, _, err := prepare(b.String(), nil)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("validation took %v", took)
+	}
+	if err == nil || !strings.Contains(err.Error(), "nested 42 levels deep") {
+		t.Fatalf("err = %v, want the depth limit", err)
+	}
+	if len(ex.fragments) > levels+1 {
+		t.Errorf("validated %d fragments", len(ex.fragments))
+	}
+
+	// 不嵌套的片段链: 深度不超过限制, 字段数超过
+	b.Reset()
+	b.WriteString("{ node(id: 1) { ...F0 } }\n")
+	for i := 0; i < levels; i++ {
+		b.WriteString("fragment F" + strconv.Itoa(i) + " on Node { ...F" + strconv.Itoa(i+1) + " ... on Node { ...F" + strconv.Itoa(i+1) + " } }\n")
+	}
+	b.WriteString("fragment F" + strconv.Itoa(levels) + " on Node { id name }\n")
+	start = time.Now()
+	_, _, err = prepare(b.String(), nil)
+	if took := time.Since(start); took > time.Second {
+		t.Errorf("validation took %v", took)
+	}
+	if err == nil || !strings.Contains(err.Error(), "more than 1000 fields") {
+		t.Errorf("err = %v, want the complexity limit", err)
+	}
+}
+
+func TestValidateLimits(t *testing.T) {
+	// 根字段及叶子字段各算一层
+	deep := "{ node(id: 1) { " + strings.Repeat("next { ", MaxDepth-2) + "id" + strings.Repeat(" }", MaxDepth-1) + " }"
+	if _, _, err := prepare(deep, nil); err != nil {
+		t.Errorf("depth %d: %v", MaxDepth, err)
+	}
+	deeper := "{ node(id: 1) { " + strings.Repeat("next { ", MaxDepth-1) + "id" + strings.Repeat(" }", MaxDepth) + " }"
+	_, _, err := prepare(deeper, nil)
+	if gerr, ok := err.(*Error); !ok || !strings.Contains(gerr.Message, "nested 16 levels deep") || gerr.Locations[0] != (Location{Line: 1, Column: 3}) {
+		t.Errorf("depth %d: %v", MaxDepth+1, err)
+	}
+
+	wide := "{ node(id: 1) { " + strings.Repeat("id ", MaxComplexity-1) + "} }"
+	if _, _, err := prepare(wide, nil); err != nil {
+		t.Errorf("%d fields: %v", MaxComplexity, err)
+	}
+	wider := "{ node(id: 1) { " + strings.Repeat("id ", MaxComplexity) + "} }"
+	if _, _, err := prepare(wider, nil); err == nil || !strings.Contains(err.Error(), "more than 1000 fields") {
+		t.Errorf("%d fields: %v", MaxComplexity+1, err)
+	}
+
+	if _, _, err := prepare(introspectionQuery, nil); err != nil {
+		t.Errorf("introspection: %v", err)
+	}
+}
+
+func TestCoerceVariables(t *testing.T) {
+	const q = `query($id: Int!, $ids: [Int!], $filter: NodeFilter, $first: Int = 3) {
+		node(id: $id) { children(first: $first) { id } }
+		nodes(ids: $ids, filter: $filter) { id }
+	}`
+	cases := []struct {
+		name string
+		vars map[string]interface{}
+		want map[string]interface{} // 转换后的变量
+		msg  string
+	}{
+		{
+			name: "integral float",
+			vars: map[string]interface{}{"id": json.Number("1.0"), "ids": []interface{}{json.Number("2"), 3e3}},
+			want: map[string]interface{}{"id": json.Number("1"), "ids": []interface{}{json.Number("2"), json.Number("3000")}, "first": json.Number("3")},
+		},
+		{
+			name: "single value as a list",
+			vars: map[string]interface{}{"id": json.Number("1"), "ids": json.Number("2")},
+			want: map[string]interface{}{"id": json.Number("1"), "ids": []interface{}{json.Number("2")}, "first": json.Number("3")},
+		},
+		{
+			name: "input object with defaults",
+			vars: map[string]interface{}{"id": json.Number("1"), "filter": map[string]interface{}{"names": "a"}, "first": nil},
+			want: map[string]interface{}{"id": json.Number("1"), "filter": map[string]interface{}{"names": []interface{}{"a"}, "after": "2020-01-01 00:00:00"}, "first": nil},
+		},
+		{name: "fraction", vars: map[string]interface{}{"id": json.Number("1.5")}, msg: "variable $id: expected an Int, got 1.5"},
+		{name: "string", vars: map[string]interface{}{"id": "1"}, msg: "variable $id: expected an Int, got 1"},
+		{name: "beyond 2^53", vars: map[string]interface{}{"id": json.Number("1e20")}, msg: "expected an Int"},
+		{name: "missing", vars: nil, msg: "variable $id of type Int! is required"},
+		{name: "null", vars: map[string]interface{}{"id": nil}, msg: "expected a value of type Int!, got null"},
+		{name: "null item", vars: map[string]interface{}{"id": json.Number("1"), "ids": []interface{}{nil}}, msg: "variable $ids: [0]: expected a value of type Int!, got null"},
+		{name: "unknown field", vars: map[string]interface{}{"id": json.Number("1"), "filter": map[string]interface{}{"age": 1}}, msg: "field age is not defined by type NodeFilter"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ex, _, err := prepare(q, c.vars)
+			if c.msg != "" {
+				if err == nil || !strings.Contains(err.Error(), c.msg) {
+					t.Errorf("err = %v, want %q", err, c.msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ex.vars, c.want) {
+				t.Errorf("vars = %#v, want %#v", ex.vars, c.want)
+			}
+		})
+	}
+}
+
+func TestCoerceArguments(t *testing.T) {
+	ex, op, err := prepare(`query($f: Int) { node(id: 1) { children(first: $f) { id } } nodes(ids: 2, filter: {id: 3}) { id } }`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := op.selections[0].(*fieldNode)
+	// 未提供的变量与未给出的参数相同, 使用默认值
+	children := node.selections[0].(*fieldNode)
+	if got := ex.args[children]; !reflect.DeepEqual(got, map[string]interface{}{"first": json.Number("10")}) {
+		t.Errorf("args of children = %#v", got)
+	}
+	nodes := op.selections[1].(*fieldNode)
+	want := map[string]interface{}{
+		"ids":    []interface{}{json.Number("2")},
+		"filter": map[string]interface{}{"id": json.Number("3"), "after": "2020-01-01 00:00:00"},
+	}
+	if got := ex.args[nodes]; !reflect.DeepEqual(got, want) {
+		t.Errorf("args of nodes = %#v, want %#v", got, want)
+	}
+
+	for q, msg := range map[string]string{
+		`{ node(id: 1.5) { id } }`:                        "argument id: expected an Int, got 1.5",
+		`{ node(id: "1") { id } }`:                        "argument id: expected an Int, got 1",
+		`{ node(id: null) { id } }`:                       "argument id: expected a value of type Int!, got null",
+		`{ nodes(filter: {after: 1}) { id } }`:            "argument filter: after: expected a DateTime, got 1",
+		`{ nodes(filter: {age: 1}) { id } }`:              "field age is not defined by type NodeFilter",
+		`{ node(id: 1) @skip(if: "yes") { id } }`:         "argument if: expected a Boolean",
+		`{ node(id: 1) { children(first: [1]) { id } } }`: "argument first: expected an Int",
+	} {
+		if _, _, err := prepare(q, nil); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("prepare(%q) = %v, want %q", q, err, msg)
+		}
+	}
+}
+
+func TestCollectMerge(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		keys  []string
+		msg   string
+	}{
+		{name: "same field", query: "{ node(id: 1) { id ...F id } } fragment F on Node { id name }", keys: []string{"id", "name"}},
+		{name: "aliases", query: "{ node(id: 1) { a: next { id } b: next { name } } }", keys: []string{"a", "b"}},
+		{name: "skipped", query: "{ node(id: 1) { id name @skip(if: true) ... @include(if: false) { next { id } } } }", keys: []string{"id"}},
+		{name: "different fields", query: "{ node(id: 1) { a: id a: name } }", msg: "fields of the response key a conflict"},
+		{name: "different arguments", query: "{ node(id: 1) { children(first: 1) { id } ...F } } fragment F on Node { children(first: 2) { id } }", msg: "fields of the response key children conflict"},
+		{name: "same arguments", query: "{ node(id: 1) { children { id } children(first: 10) { name } } }", keys: []string{"children"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ex, op, err := prepare(c.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			node := op.selections[0].(*fieldNode)
+			fields, err := ex.collect(ex.schema.Query.Field("node").Type, node.selections)
+			if c.msg != "" {
+				if err == nil || !strings.Contains(err.Error(), c.msg) {
+					t.Errorf("err = %v, want %q", err, c.msg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys := make([]string, len(fields))
+			for i, f := range fields {
+				keys[i] = f.key
+			}
+			if !reflect.DeepEqual(keys, c.keys) {
+				t.Errorf("keys = %v, want %v", keys, c.keys)
+			}
+		})
+	}
+}
+
+// introspectionQuery is the query of the GraphQL clients such as GraphiQL
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) { name description args { ...InputValue } type { ...TypeRef } isDeprecated deprecationReason }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}
+`
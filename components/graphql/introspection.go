@@ -0,0 +1,246 @@
+package graphql
+
+import (
+	"sort"
+)
+
+// 内省的类型, 见 https://spec.graphql.org/October2021/#sec-Schema-Introspection
+func addIntrospection(s *Schema) {
+	typeKind := &Type{Kind: KindEnum, Name: "__TypeKind"}
+	for _, k := range []string{KindScalar, "INTERFACE", "UNION", KindObject, KindEnum, KindInputObject, KindList, KindNonNull} {
+		typeKind.EnumValues = append(typeKind.EnumValues, &EnumValue{Name: k, value: k})
+	}
+	location := &Type{Kind: KindEnum, Name: "__DirectiveLocation"}
+	for _, l := range []string{"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD", "INLINE_FRAGMENT", "VARIABLE_DEFINITION",
+		"SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE", "UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION"} {
+		location.EnumValues = append(location.EnumValues, &EnumValue{Name: l, value: l})
+	}
+
+	includeDeprecated := func() []*InputValue {
+		return []*InputValue{{Name: "includeDeprecated", Type: booleanType, DefaultValue: "false"}}
+	}
+	typ := &Type{Kind: KindObject, Name: "__Type"}
+	field := &Type{Kind: KindObject, Name: "__Field"}
+	inputValue := &Type{Kind: KindObject, Name: "__InputValue"}
+	enumValue := &Type{Kind: KindObject, Name: "__EnumValue"}
+	directive := &Type{Kind: KindObject, Name: "__Directive"}
+	schema := &Type{
+		Kind: KindObject,
+		Name: "__Schema",
+		Fields: []*Field{
+			{Name: "description", Type: stringType},
+			{Name: "types", Type: nonNull(listOf(nonNull(typ)))},
+			{Name: "queryType", Type: nonNull(typ)},
+			{Name: "mutationType", Type: typ},
+			{Name: "subscriptionType", Type: typ},
+			{Name: "directives", Type: nonNull(listOf(nonNull(directive)))},
+		},
+	}
+	typ.Fields = []*Field{
+		{Name: "kind", Type: nonNull(typeKind)},
+		{Name: "name", Type: stringType},
+		{Name: "description", Type: stringType},
+		{Name: "specifiedByURL", Type: stringType},
+		{Name: "fields", Args: includeDeprecated(), Type: listOf(nonNull(field))},
+		{Name: "interfaces", Type: listOf(nonNull(typ))},
+		{Name: "possibleTypes", Type: listOf(nonNull(typ))},
+		{Name: "enumValues", Args: includeDeprecated(), Type: listOf(nonNull(enumValue))},
+		{Name: "inputFields", Args: includeDeprecated(), Type: listOf(nonNull(inputValue))},
+		{Name: "ofType", Type: typ},
+	}
+	field.Fields = []*Field{
+		{Name: "name", Type: nonNull(stringType)},
+		{Name: "description", Type: stringType},
+		{Name: "args", Args: includeDeprecated(), Type: nonNull(listOf(nonNull(inputValue)))},
+		{Name: "type", Type: nonNull(typ)},
+		{Name: "isDeprecated", Type: nonNull(booleanType)},
+		{Name: "deprecationReason", Type: stringType},
+	}
+	inputValue.Fields = []*Field{
+		{Name: "name", Type: nonNull(stringType)},
+		{Name: "description", Type: stringType},
+		{Name: "type", Type: nonNull(typ)},
+		{Name: "defaultValue", Type: stringType},
+		{Name: "isDeprecated", Type: nonNull(booleanType)},
+		{Name: "deprecationReason", Type: stringType},
+	}
+	enumValue.Fields = []*Field{
+		{Name: "name", Type: nonNull(stringType)},
+		{Name: "description", Type: stringType},
+		{Name: "isDeprecated", Type: nonNull(booleanType)},
+		{Name: "deprecationReason", Type: stringType},
+	}
+	directive.Fields = []*Field{
+		{Name: "name", Type: nonNull(stringType)},
+		{Name: "description", Type: stringType},
+		{Name: "locations", Type: nonNull(listOf(nonNull(location)))},
+		{Name: "args", Args: includeDeprecated(), Type: nonNull(listOf(nonNull(inputValue)))},
+		{Name: "isRepeatable", Type: nonNull(booleanType)},
+	}
+	for _, t := range []*Type{schema, typ, field, inputValue, enumValue, directive, typeKind, location} {
+		s.Types[t.Name] = t
+	}
+	// 查询根类型上的内省字段, 不出现在Query的字段列表中
+	s.schemaField = &Field{Name: "__schema", Type: nonNull(schema)}
+	s.typeField = &Field{Name: "__type", Args: []*InputValue{{Name: "name", Type: nonNull(stringType)}}, Type: typ}
+
+	condition := func() []*InputValue {
+		return []*InputValue{{Name: "if", Type: nonNull(booleanType)}}
+	}
+	s.Directives = []*Directive{
+		{
+			Name:        "include",
+			Description: "includes the field or fragment only when the argument is true",
+			Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+			Args:        condition(),
+		},
+		{
+			Name:        "skip",
+			Description: "skips the field or fragment when the argument is true",
+			Locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+			Args:        condition(),
+		},
+		{
+			Name:        "deprecated",
+			Description: "marks a field or an enum value as deprecated",
+			Locations:   []string{"FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INPUT_FIELD_DEFINITION", "ENUM_VALUE"},
+			Args:        []*InputValue{{Name: "reason", Type: stringType, DefaultValue: `"No longer supported"`}},
+		},
+	}
+}
+
+// typenameMetaField is the name of the object type, valid on every object
+var typenameMetaField = &Field{Name: "__typename", Type: nonNull(stringType)}
+
+// introspect resolves the field name of an introspection object
+func introspect(obj interface{}, name string, args map[string]interface{}) interface{} {
+	all, _ := args["includeDeprecated"].(bool)
+	switch o := obj.(type) {
+	case *Schema:
+		switch name {
+		case "types":
+			names := make([]string, 0, len(o.Types))
+			for k := range o.Types {
+				names = append(names, k)
+			}
+			sort.Strings(names)
+			types := make([]*Type, len(names))
+			for i, k := range names {
+				types[i] = o.Types[k]
+			}
+			return types
+		case "queryType":
+			return o.Query
+		case "directives":
+			return o.Directives
+		}
+	case *Type:
+		switch name {
+		case "kind":
+			return o.Kind
+		case "name":
+			return optional(o.Name)
+		case "description":
+			return optional(o.Description)
+		case "fields":
+			if o.Kind != KindObject {
+				return nil
+			}
+			fields := make([]*Field, 0, len(o.Fields))
+			for _, f := range o.Fields {
+				if all || f.DeprecationReason == "" {
+					fields = append(fields, f)
+				}
+			}
+			return fields
+		case "interfaces":
+			if o.Kind == KindObject {
+				return []*Type{}
+			}
+		case "enumValues":
+			if o.Kind != KindEnum {
+				return nil
+			}
+			values := make([]*EnumValue, 0, len(o.EnumValues))
+			for _, v := range o.EnumValues {
+				if all || v.DeprecationReason == "" {
+					values = append(values, v)
+				}
+			}
+			return values
+		case "inputFields":
+			if o.Kind != KindInputObject {
+				return nil
+			}
+			return o.InputFields
+		case "ofType":
+			if o.OfType != nil {
+				return o.OfType
+			}
+		}
+	case *Field:
+		switch name {
+		case "name":
+			return o.Name
+		case "description":
+			return optional(o.Description)
+		case "args":
+			if o.Args == nil {
+				return []*InputValue{}
+			}
+			return o.Args
+		case "type":
+			return o.Type
+		case "isDeprecated":
+			return o.DeprecationReason != ""
+		case "deprecationReason":
+			return optional(o.DeprecationReason)
+		}
+	case *InputValue:
+		switch name {
+		case "name":
+			return o.Name
+		case "description":
+			return optional(o.Description)
+		case "type":
+			return o.Type
+		case "defaultValue":
+			return optional(o.DefaultValue)
+		case "isDeprecated":
+			return false
+		}
+	case *EnumValue:
+		switch name {
+		case "name":
+			return o.Name
+		case "description":
+			return optional(o.Description)
+		case "isDeprecated":
+			return o.DeprecationReason != ""
+		case "deprecationReason":
+			return optional(o.DeprecationReason)
+		}
+	case *Directive:
+		switch name {
+		case "name":
+			return o.Name
+		case "description":
+			return optional(o.Description)
+		case "locations":
+			return o.Locations
+		case "args":
+			return o.Args
+		case "isRepeatable":
+			return false
+		}
+	}
+	return nil
+}
+
+// 空字符串输出为null
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMalformed(t *testing.T) {
+	cases := []struct {
+		name   string
+		src    string
+		msg    string
+		line   int
+		column int
+	}{
+		{"empty", "", "the document contains no operation", 1, 1},
+		{"unclosed selection set", "{ student { id }", "unexpected end of document", 1, 17},
+		{"empty selection set", "{ student { } }", `unexpected "}"`, 1, 13},
+		{"missing argument value", "{ student(id: ) { id } }", `unexpected ")"`, 1, 15},
+		{"unterminated string", `{ student(name: "a) { id } }`, "unterminated string", 1, 17},
+		{"variable in default", "query($a: Int = $b) { student { id } }", "variables are not allowed in constant values", 1, 17},
+		{"duplicate object field", "{ student(filter: {id: 1, id: 2}) { id } }", "field id is given more than once", 1, 27},
+		{"duplicate fragment", "{ ...F } fragment F on Query { a } fragment F on Query { b }", "fragment F is defined more than once", 1, 36},
+		{"type definition", "type Query { a: Int }", `unexpected "type"`, 1, 1},
+		{"nested too deeply", strings.Repeat("{ a ", maxNesting+1) + strings.Repeat("}", maxNesting+1), "nested more than", 1, 4*maxNesting + 1},
+		{"list nested too deeply", "{ a(b: " + strings.Repeat("[", maxNesting+1) + strings.Repeat("]", maxNesting+1) + ") }", "nested more than", 1, 7 + maxNesting},
+		{"type nested too deeply", "query($a: " + strings.Repeat("[", maxNesting+1) + "Int" + strings.Repeat("]", maxNesting+1) + ") { a }", "nested more than", 1, 11 + maxNesting},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parse(c.src)
+			gerr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("parse(%q) = %v, want an *Error", c.src, err)
+			}
+			if !strings.Contains(gerr.Message, c.msg) {
+				t.Errorf("message = %q, want it to contain %q", gerr.Message, c.msg)
+			}
+			if len(gerr.Locations) != 1 || gerr.Locations[0].Line != c.line || gerr.Locations[0].Column != c.column {
+				t.Errorf("locations = %v, want %d:%d", gerr.Locations, c.line, c.column)
+			}
+		})
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	doc, err := parse(`
+		query Q($id: Int! = 1, $names: [String!]) @include(if: true) {
+			s: student(id: $id, filter: {name: {in: $names}}) { id ...F }
+		}
+		fragment F on Student { class { ... on Class { name } } }
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.operations) != 1 || len(doc.fragments) != 1 {
+		t.Fatalf("got %d operations and %d fragments", len(doc.operations), len(doc.fragments))
+	}
+	op := doc.operations[0]
+	if op.name != "Q" || len(op.vars) != 2 || len(op.directives) != 1 {
+		t.Errorf("operation = %+v", op)
+	}
+	if got := op.vars[1].typ.String(); got != "[String!]" {
+		t.Errorf("type of $names = %s", got)
+	}
+	f := op.selections[0].(*fieldNode)
+	if f.responseKey() != "s" || f.name != "student" || len(f.args) != 2 || len(f.selections) != 2 {
+		t.Errorf("field = %+v", f)
+	}
+	if _, ok := f.selections[1].(*fragmentSpread); !ok {
+		t.Errorf("selection = %T, want a fragment spread", f.selections[1])
+	}
+	if frag := doc.fragments["F"]; frag.typeCondition != "Student" {
+		t.Errorf("type condition = %s", frag.typeCondition)
+	}
+}
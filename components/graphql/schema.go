@@ -0,0 +1,505 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-bread/components/database/condition"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/validators/query"
+)
+
+// 类型的种类, 与内省的 __TypeKind 相同
+const (
+	KindScalar      = "SCALAR"
+	KindObject      = "OBJECT"
+	KindEnum        = "ENUM"
+	KindInputObject = "INPUT_OBJECT"
+	KindList        = "LIST"
+	KindNonNull     = "NON_NULL"
+)
+
+// Schema is the GraphQL schema of the entity groups, see Build
+type Schema struct {
+	Query      *Type
+	Types      map[string]*Type
+	Directives []*Directive
+
+	schemaField, typeField *Field
+}
+
+// Type is a named type, or a list or non null wrapper of OfType
+type Type struct {
+	Kind        string
+	Name        string
+	Description string
+	Fields      []*Field      // OBJECT
+	InputFields []*InputValue // INPUT_OBJECT
+	EnumValues  []*EnumValue  // ENUM
+	OfType      *Type         // LIST, NON_NULL
+}
+
+// Field is a field of an object type
+type Field struct {
+	Name              string
+	Description       string
+	Args              []*InputValue
+	Type              *Type
+	DeprecationReason string
+	group             consts.EntityGroupName // 根字段查询的实体组
+}
+
+// InputValue is an argument or a field of an input object type
+type InputValue struct {
+	Name         string
+	Description  string
+	Type         *Type
+	DefaultValue string // 默认值的GraphQL文本, 为空表示没有默认值
+	op           string // 过滤条件的操作符, 如 >=
+}
+
+// EnumValue is a value of an enum type
+type EnumValue struct {
+	Name              string
+	Description       string
+	DeprecationReason string
+	value             string // 对应的查询文档中的值, 如字段路径或排序方向
+}
+
+// Directive is a directive supported by the executor
+type Directive struct {
+	Name        string
+	Description string
+	Locations   []string
+	Args        []*InputValue
+}
+
+// Field returns the field of an object type
+func (t *Type) Field(name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// InputField returns the field of an input object type
+func (t *Type) InputField(name string) *InputValue {
+	for _, f := range t.InputFields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// String is the type as written in a query, e.g. [Student!]!
+func (t *Type) String() string {
+	switch t.Kind {
+	case KindList:
+		return "[" + t.OfType.String() + "]"
+	case KindNonNull:
+		return t.OfType.String() + "!"
+	}
+	return t.Name
+}
+
+// named unwraps the list and non null wrappers
+func (t *Type) named() *Type {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t
+}
+
+func listOf(t *Type) *Type  { return &Type{Kind: KindList, OfType: t} }
+func nonNull(t *Type) *Type { return &Type{Kind: KindNonNull, OfType: t} }
+
+// 内置及实体组使用的标量
+var (
+	intType      = &Type{Kind: KindScalar, Name: "Int", Description: "integer, the values of the entity groups may exceed 32 bits"}
+	floatType    = &Type{Kind: KindScalar, Name: "Float"}
+	stringType   = &Type{Kind: KindScalar, Name: "String"}
+	booleanType  = &Type{Kind: KindScalar, Name: "Boolean"}
+	dateTimeType = &Type{Kind: KindScalar, Name: "DateTime", Description: "formatted as " + models.DatetimeLayouts[0] + `, filters also accept a range "from..to" or a time relative to now such as now-7d`}
+	jsonType     = &Type{Kind: KindScalar, Name: "JSON", Description: "any JSON value, e.g. the output of a field formatted by a callback"}
+)
+
+// filterKeys are the fields of the filter input types in order, eq and in are the plain and the list values of a query document
+var filterKeys = []struct{ key, op string }{
+	{"eq", condition.OpEqual},
+	{"in", condition.OpIn},
+	{condition.OperatorKeys["!="], "!="},
+	{condition.OperatorKeys[">"], ">"},
+	{condition.OperatorKeys[">="], ">="},
+	{condition.OperatorKeys["<"], "<"},
+	{condition.OperatorKeys["<="], "<="},
+	{condition.OperatorKeys["like"], "like"},
+}
+
+// 排序方向的枚举值, 与 query.Directions 对应
+var directionValues = map[string]string{
+	"ASC":              query.Asc,
+	"DESC":             query.Desc,
+	"ASC_NULLS_FIRST":  query.Asc + " nulls " + query.NullsFirst,
+	"ASC_NULLS_LAST":   query.Asc + " nulls " + query.NullsLast,
+	"DESC_NULLS_FIRST": query.Desc + " nulls " + query.NullsFirst,
+	"DESC_NULLS_LAST":  query.Desc + " nulls " + query.NullsLast,
+}
+
+var nameRegexp = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// 枚举值中字段路径的分隔符, GraphQL的名字不能包含 .
+const enumSeparator = "__"
+
+type builder struct {
+	schema *Schema
+}
+
+// Build generates the schema of the default version of the entity groups:
+// a root field per group returning a connection of its rows, namespaces are nested object types
+func Build(fieldsMap group.FieldsMap) (*Schema, error) {
+	s := &Schema{
+		Query: &Type{Kind: KindObject, Name: "Query"},
+		Types: make(map[string]*Type),
+	}
+	b := &builder{schema: s}
+	for _, t := range []*Type{intType, floatType, stringType, booleanType, dateTimeType, jsonType, pageInfoType(), directionType()} {
+		if err := b.add(t); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(fieldsMap))
+	for gn := range fieldsMap {
+		names = append(names, string(gn))
+	}
+	sort.Strings(names)
+	for _, gn := range names {
+		g := fieldsMap[consts.EntityGroupName(gn)]
+		f, err := b.root(gn, g.Default())
+		if err != nil {
+			return nil, fmt.Errorf("graphql: group %s: %v", gn, err)
+		}
+		s.Query.Fields = append(s.Query.Fields, f)
+	}
+
+	if err := b.add(s.Query); err != nil {
+		return nil, err
+	}
+	addIntrospection(s)
+	return s, nil
+}
+
+func (b *builder) add(t *Type) error {
+	if !nameRegexp.MatchString(t.Name) {
+		return fmt.Errorf("graphql: invalid type name %q", t.Name)
+	}
+	if _, ok := b.schema.Types[t.Name]; ok {
+		return fmt.Errorf("graphql: type %s is defined more than once", t.Name)
+	}
+	b.schema.Types[t.Name] = t
+	return nil
+}
+
+// root builds the types of a group and its field of Query
+func (b *builder) root(gn string, g group.EntityGroup) (*Field, error) {
+	if !nameRegexp.MatchString(gn) {
+		return nil, fmt.Errorf("invalid field name %q", gn)
+	}
+	prefix := typeName(gn)
+
+	node, err := b.object(prefix, &g, g.Entities, "")
+	if err != nil {
+		return nil, err
+	}
+	conn := &Type{
+		Kind:        KindObject,
+		Name:        prefix + "Connection",
+		Description: "a page of " + gn,
+		Fields: []*Field{
+			{Name: "nodes", Type: nonNull(listOf(nonNull(node)))},
+			{Name: "pageInfo", Type: nonNull(b.schema.Types["PageInfo"])},
+			{Name: "totalCount", Description: "number of the rows matching the filter", Type: nonNull(intType)},
+		},
+	}
+	if err := b.add(conn); err != nil {
+		return nil, err
+	}
+
+	// 根字段可以为空, 一个实体组的查询出错时不影响其他字段
+	f := &Field{Name: gn, Type: conn, group: consts.EntityGroupName(gn)}
+	filter, err := b.filter(prefix+"Filter", g.Entities, "")
+	if err != nil {
+		return nil, err
+	}
+	if filter != nil {
+		f.Args = append(f.Args, &InputValue{Name: "filter", Type: filter})
+	}
+	search, ok := g.SearchFields()
+	if ok {
+		f.Args = append(f.Args, &InputValue{
+			Name:        "search",
+			Description: "full text search in " + strings.Join(g.Search.Fields, ", "),
+			Type:        stringType,
+		})
+	}
+	order, err := b.order(prefix, g, len(search) > 0)
+	if err != nil {
+		return nil, err
+	}
+	if order != nil {
+		f.Args = append(f.Args, &InputValue{Name: "orderBy", Type: listOf(nonNull(order))})
+	}
+	f.Args = append(f.Args,
+		&InputValue{Name: "page", Type: intType, DefaultValue: fmt.Sprint(query.DefaultPage)},
+		&InputValue{Name: "pageSize", Description: fmt.Sprintf("at most %d", query.MaxPageSize), Type: intType, DefaultValue: fmt.Sprint(query.DefaultPageSize)},
+	)
+
+	// 查询策略写入描述, 与openapi文档相同
+	var policies []string
+	for _, p := range g.Policies {
+		filters := make([]string, len(p.Filters))
+		for i, pf := range p.Filters {
+			filters[i] = pf.String()
+		}
+		policies = append(policies, "must filter by one of: "+strings.Join(filters, ", "))
+	}
+	f.Description = strings.Join(policies, "; ")
+	return f, nil
+}
+
+// object builds the object type of the rows of a group or of a namespace
+func (b *builder) object(name string, g *group.EntityGroup, entities map[string]interface{}, prefix string) (*Type, error) {
+	t := &Type{Kind: KindObject, Name: name}
+	for _, k := range sortedKeys(entities) {
+		if !nameRegexp.MatchString(k) {
+			return nil, fmt.Errorf("invalid field name %q", prefix+k)
+		}
+		f := &Field{Name: k}
+		switch ent := entities[k].(type) {
+		case field.Field:
+			f.Type = outputType(ent)
+		case map[string]interface{}:
+			ns, err := b.object(name+typeName(k), g, ent, prefix+k+group.Separator)
+			if err != nil {
+				return nil, err
+			}
+			f.Type = ns
+		default:
+			continue
+		}
+		if msg, ok := g.Deprecation(prefix + k); ok {
+			f.DeprecationReason = msg
+		}
+		t.Fields = append(t.Fields, f)
+	}
+	return t, b.add(t)
+}
+
+// 回调的输出类型不确定, 脱敏后的值为字符串
+func outputType(f field.Field) *Type {
+	if f.Callback != nil {
+		return jsonType
+	}
+	if f.Mask != nil {
+		return stringType
+	}
+	if f.OutputKind() == reflect.String && f.TableField.IsTime {
+		return dateTimeType
+	}
+	return kindType(f.OutputKind())
+}
+
+func kindType(k reflect.Kind) *Type {
+	switch k {
+	case reflect.Bool:
+		return booleanType
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return intType
+	case reflect.Float32, reflect.Float64:
+		return floatType
+	case reflect.String:
+		return stringType
+	default:
+		return jsonType
+	}
+}
+
+// inputType is the type of a filter value of tf
+func inputType(tf models.TableField) *Type {
+	if tf.IsTime {
+		return dateTimeType
+	}
+	return kindType(tf.Type)
+}
+
+// filter builds the input type of the queryable fields of a namespace, nil if none
+func (b *builder) filter(name string, entities map[string]interface{}, prefix string) (*Type, error) {
+	t := &Type{Kind: KindInputObject, Name: name}
+	for _, k := range sortedKeys(entities) {
+		switch ent := entities[k].(type) {
+		case field.Field:
+			ops := ent.Operators()
+			if len(ops) == 0 {
+				continue
+			}
+			ft, err := b.operators(inputType(ent.TableField), ops)
+			if err != nil {
+				return nil, err
+			}
+			t.InputFields = append(t.InputFields, &InputValue{Name: k, Type: ft})
+		case map[string]interface{}:
+			ns, err := b.filter(name[:len(name)-len("Filter")]+typeName(k)+"Filter", ent, prefix+k+group.Separator)
+			if err != nil {
+				return nil, err
+			}
+			if ns != nil {
+				t.InputFields = append(t.InputFields, &InputValue{Name: k, Type: ns})
+			}
+		}
+	}
+	if len(t.InputFields) == 0 {
+		return nil, nil
+	}
+	return t, b.add(t)
+}
+
+// operators returns the input type of a field accepting ops, shared by the fields of the same scalar and operators,
+// e.g. IntEqInFilter { eq: Int, in: [Int!] }
+func (b *builder) operators(scalar *Type, ops []string) (*Type, error) {
+	allowed := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		allowed[op] = true
+	}
+	name := scalar.Name
+	var fields []*InputValue
+	for _, k := range filterKeys {
+		if !allowed[k.op] {
+			continue
+		}
+		name += typeName(k.key)
+		v := &InputValue{Name: k.key, Type: scalar, op: k.op}
+		switch k.op {
+		case condition.OpIn:
+			v.Type = listOf(nonNull(scalar))
+		case "like":
+			v.Type = stringType
+			v.Description = "contains the text"
+		}
+		fields = append(fields, v)
+	}
+	name += "Filter"
+	if t, ok := b.schema.Types[name]; ok {
+		return t, nil
+	}
+	t := &Type{
+		Kind:        KindInputObject,
+		Name:        name,
+		Description: "conditions of a field, combined by and. eq and in can not be combined with the other conditions",
+		InputFields: fields,
+	}
+	return t, b.add(t)
+}
+
+// order builds the input type of orderBy, nil if the group has no orderable field
+func (b *builder) order(prefix string, g group.EntityGroup, relevance bool) (*Type, error) {
+	fields := &Type{Kind: KindEnum, Name: prefix + "OrderField"}
+	var err error
+	g.Walk(func(path string, f field.Field) {
+		if !f.CanOrder || err != nil {
+			return
+		}
+		name := strings.Replace(path, group.Separator, enumSeparator, -1)
+		if !nameRegexp.MatchString(name) || name == "true" || name == "false" || name == "null" {
+			err = fmt.Errorf("invalid enum value %q", name)
+			return
+		}
+		v := &EnumValue{Name: name, value: path}
+		if msg, ok := g.Deprecation(path); ok {
+			v.DeprecationReason = msg
+		}
+		fields.EnumValues = append(fields.EnumValues, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if relevance {
+		fields.EnumValues = append(fields.EnumValues, &EnumValue{
+			Name:        group.RelevanceField,
+			Description: "relevance of search, only with the search argument",
+			value:       group.RelevanceField,
+		})
+	}
+	if len(fields.EnumValues) == 0 {
+		return nil, nil
+	}
+	if err := b.add(fields); err != nil {
+		return nil, err
+	}
+
+	t := &Type{
+		Kind: KindInputObject,
+		Name: prefix + "Order",
+		InputFields: []*InputValue{
+			{Name: "field", Type: nonNull(fields)},
+			{Name: "direction", Type: b.schema.Types["Direction"], DefaultValue: "ASC"},
+		},
+	}
+	return t, b.add(t)
+}
+
+func pageInfoType() *Type {
+	return &Type{
+		Kind: KindObject,
+		Name: "PageInfo",
+		Fields: []*Field{
+			{Name: "page", Type: nonNull(intType)},
+			{Name: "pageSize", Type: nonNull(intType)},
+			{Name: "totalCount", Type: nonNull(intType)},
+			{Name: "hasNextPage", Type: nonNull(booleanType)},
+		},
+	}
+}
+
+func directionType() *Type {
+	t := &Type{Kind: KindEnum, Name: "Direction"}
+	names := make([]string, 0, len(directionValues))
+	for k := range directionValues {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		t.EnumValues = append(t.EnumValues, &EnumValue{Name: k, value: directionValues[k]})
+	}
+	return t
+}
+
+// typeName converts a name such as student_class to StudentClass
+func typeName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,277 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 输入值转换后的形式: Int和Float为json.Number, 枚举为对应的查询文档中的值,
+// 输入对象为只包含给出的字段及默认值的map
+
+// coerceLiteral converts a literal of the query to a value of t, vars are the coerced variables
+func coerceLiteral(v *value, t *Type, vars map[string]interface{}) (interface{}, error) {
+	if v.kind == valueVariable {
+		val, ok := vars[v.raw]
+		if !ok || val == nil {
+			if t.Kind == KindNonNull {
+				return nil, fmt.Errorf("variable $%s of non null type %s is not provided", v.raw, t)
+			}
+			return nil, nil
+		}
+		// 变量的类型已按定义转换, 这里只检查可否用于该位置
+		return val, nil
+	}
+	if v.kind == valueNull {
+		if t.Kind == KindNonNull {
+			return nil, fmt.Errorf("expected a value of type %s, got null", t)
+		}
+		return nil, nil
+	}
+
+	switch t.Kind {
+	case KindNonNull:
+		return coerceLiteral(v, t.OfType, vars)
+	case KindList:
+		if v.kind != valueList {
+			item, err := coerceLiteral(v, t.OfType, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		list := make([]interface{}, 0, len(v.list))
+		for i, item := range v.list {
+			c, err := coerceLiteral(item, t.OfType, vars)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			list = append(list, c)
+		}
+		return list, nil
+	case KindInputObject:
+		if v.kind != valueObject {
+			return nil, fmt.Errorf("expected an object of type %s", t.Name)
+		}
+		given := make(map[string]*value, len(v.fields))
+		for _, f := range v.fields {
+			if t.InputField(f.name) == nil {
+				return nil, fmt.Errorf("field %s is not defined by type %s", f.name, t.Name)
+			}
+			given[f.name] = f.value
+		}
+		m := make(map[string]interface{}, len(v.fields))
+		for _, f := range t.InputFields {
+			item, ok := given[f.Name]
+			// 未给出的变量与未给出的字段相同
+			if ok && item.kind == valueVariable {
+				if _, provided := vars[item.raw]; !provided {
+					ok = false
+				}
+			}
+			if !ok {
+				if err := defaultValue(f, m); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			c, err := coerceLiteral(item, f.Type, vars)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f.Name, err)
+			}
+			m[f.Name] = c
+		}
+		return m, nil
+	case KindEnum:
+		if v.kind != valueEnum {
+			return nil, fmt.Errorf("expected a value of enum %s", t.Name)
+		}
+		return enumValue(t, v.raw)
+	}
+
+	switch t.Name {
+	case intType.Name:
+		if v.kind != valueInt {
+			return nil, fmt.Errorf("expected an Int, got %s", v.raw)
+		}
+		return json.Number(v.raw), nil
+	case floatType.Name:
+		if v.kind != valueInt && v.kind != valueFloat {
+			return nil, fmt.Errorf("expected a Float, got %s", v.raw)
+		}
+		return json.Number(v.raw), nil
+	case stringType.Name, dateTimeType.Name:
+		if v.kind != valueString {
+			return nil, fmt.Errorf("expected a %s, got %s", t.Name, v.raw)
+		}
+		return v.raw, nil
+	case booleanType.Name:
+		if v.kind != valueBoolean {
+			return nil, fmt.Errorf("expected a Boolean, got %s", v.raw)
+		}
+		return v.raw == "true", nil
+	}
+	return nil, fmt.Errorf("type %s can not be used as an input", t)
+}
+
+// coerceJSON converts the JSON value of a variable to a value of t
+func coerceJSON(v interface{}, t *Type) (interface{}, error) {
+	if v == nil {
+		if t.Kind == KindNonNull {
+			return nil, fmt.Errorf("expected a value of type %s, got null", t)
+		}
+		return nil, nil
+	}
+
+	switch t.Kind {
+	case KindNonNull:
+		return coerceJSON(v, t.OfType)
+	case KindList:
+		list, ok := v.([]interface{})
+		if !ok {
+			item, err := coerceJSON(v, t.OfType)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+		out := make([]interface{}, 0, len(list))
+		for i, item := range list {
+			c, err := coerceJSON(item, t.OfType)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			out = append(out, c)
+		}
+		return out, nil
+	case KindInputObject:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type %s", t.Name)
+		}
+		for k := range obj {
+			if t.InputField(k) == nil {
+				return nil, fmt.Errorf("field %s is not defined by type %s", k, t.Name)
+			}
+		}
+		m := make(map[string]interface{}, len(obj))
+		for _, f := range t.InputFields {
+			item, ok := obj[f.Name]
+			if !ok {
+				if err := defaultValue(f, m); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			c, err := coerceJSON(item, f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", f.Name, err)
+			}
+			m[f.Name] = c
+		}
+		return m, nil
+	case KindEnum:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a value of enum %s", t.Name)
+		}
+		return enumValue(t, s)
+	}
+
+	switch t.Name {
+	case intType.Name:
+		n, ok := jsonNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("expected an Int, got %v", v)
+		}
+		if _, err := strconv.ParseInt(string(n), 10, 64); err != nil {
+			// 1.0 及 1e3 等整数值的浮点数
+			f, err := n.Float64()
+			if err != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+				return nil, fmt.Errorf("expected an Int, got %s", n)
+			}
+			return json.Number(strconv.FormatInt(int64(f), 10)), nil
+		}
+		return n, nil
+	case floatType.Name:
+		n, ok := jsonNumber(v)
+		if !ok {
+			return nil, fmt.Errorf("expected a Float, got %v", v)
+		}
+		return n, nil
+	case stringType.Name, dateTimeType.Name:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a %s, got %v", t.Name, v)
+		}
+		return s, nil
+	case booleanType.Name:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a Boolean, got %v", v)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("type %s can not be used as an input", t)
+}
+
+func jsonNumber(v interface{}) (json.Number, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return n, true
+	case float64:
+		return json.Number(strconv.FormatFloat(n, 'g', -1, 64)), true
+	}
+	return "", false
+}
+
+func enumValue(t *Type, name string) (interface{}, error) {
+	for _, ev := range t.EnumValues {
+		if ev.Name == name {
+			return ev.value, nil
+		}
+	}
+	names := make([]string, len(t.EnumValues))
+	for i, ev := range t.EnumValues {
+		names[i] = ev.Name
+	}
+	return nil, fmt.Errorf("%s is not a value of enum %s, expected one of %s", name, t.Name, strings.Join(names, ", "))
+}
+
+// defaultValue sets the default of f in m, or reports a missing non null field
+func defaultValue(f *InputValue, m map[string]interface{}) error {
+	if f.DefaultValue != "" {
+		v, err := parseValue(f.DefaultValue)
+		if err != nil {
+			return err
+		}
+		c, err := coerceLiteral(v, f.Type, nil)
+		if err != nil {
+			return err
+		}
+		m[f.Name] = c
+		return nil
+	}
+	if f.Type.Kind == KindNonNull {
+		return fmt.Errorf("field %s of type %s is required", f.Name, f.Type)
+	}
+	return nil
+}
+
+// parseValue parses a constant value such as a default
+func parseValue(src string) (*value, error) {
+	p := &parser{lex: &lexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	v, err := p.value(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return v, nil
+}
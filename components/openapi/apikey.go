@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"github.com/go-bread/pkg/apikey"
+)
+
+const apiKeyAuth = "apiKeyAuth"
+
+// entitySecurity accepts the access token of a user or an API key scoped to the group
+func entitySecurity() []map[string][]string {
+	return []map[string][]string{{bearerAuth: {}}, {apiKeyAuth: {}}}
+}
+
+// addAPIKeyPaths documents the management of the API keys, only users may manage their keys
+func addAPIKeyPaths(doc *Document) {
+	operations := make([]interface{}, len(apikey.Operations))
+	for i, op := range apikey.Operations {
+		operations[i] = op
+	}
+	create := keyOperation("createAPIKey", "Create an API key, the key is only returned once")
+	create.RequestBody = &RequestBody{Required: true, Content: jsonContent(&Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":       {Type: "string"},
+			"groups":     {Type: "array", Items: &Schema{Type: "string"}, MinItems: integer(1)},
+			"operations": {Type: "array", Items: &Schema{Type: "string", Enum: operations}, MinItems: integer(1)},
+			"expires_in": {Type: "integer", Description: "lifetime of the key in seconds, 0 never expires"},
+		},
+		Required: []string{"name", "groups", "operations"},
+	})}
+	create.Responses["201"] = &Response{Description: "Created", Content: jsonContent(ref("APIKey"))}
+	create.Responses["400"] = &Response{Description: "Invalid request", Content: jsonContent(ref("Error"))}
+	create.Responses["404"] = &Response{Description: "Entity group not found", Content: jsonContent(ref("Error"))}
+
+	list := keyOperation("listAPIKeys", "List the API keys of the logged in user")
+	list.Responses["200"] = &Response{Description: "OK", Content: jsonContent(&Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"list": {Type: "array", Items: ref("APIKey")}},
+	})}
+	doc.Paths["/auth/keys"] = &PathItem{Get: list, Post: create}
+
+	revoke := keyOperation("revokeAPIKey", "Revoke an API key of the logged in user")
+	revoke.Parameters = []*Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer"}}}
+	revoke.Responses["204"] = &Response{Description: "Revoked"}
+	revoke.Responses["404"] = &Response{Description: "API key not found or already revoked", Content: jsonContent(ref("Error"))}
+	doc.Paths["/auth/keys/{id}"] = &PathItem{Delete: revoke}
+}
+
+func keyOperation(id, summary string) *Operation {
+	return &Operation{
+		Tags:        []string{"auth"},
+		Summary:     summary,
+		OperationID: id,
+		Responses: map[string]*Response{
+			"401": {Description: "Missing or invalid access token", Content: jsonContent(ref("Error"))},
+			"403": {Description: "API keys can not manage API keys", Content: jsonContent(ref("Error"))},
+		},
+		Security: []map[string][]string{{bearerAuth: {}}},
+	}
+}
+
+func apiKeySchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":           {Type: "integer"},
+			"key":          {Type: "string", Description: "only returned on creation"},
+			"name":         {Type: "string"},
+			"prefix":       {Type: "string", Description: "beginning of the key to recognize it"},
+			"groups":       {Type: "array", Items: &Schema{Type: "string"}},
+			"operations":   {Type: "array", Items: &Schema{Type: "string"}},
+			"created_at":   {Type: "integer", Description: "unix time"},
+			"expires_at":   {Type: "integer", Description: "unix time, absent if the key never expires"},
+			"last_used_at": {Type: "integer", Description: "unix time, updated at most once a minute"},
+			"revoked_at":   {Type: "integer", Description: "unix time, absent if the key is active"},
+		},
+		Required: []string{"id", "name", "prefix", "groups", "operations", "created_at"},
+	}
+}
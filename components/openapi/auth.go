@@ -0,0 +1,65 @@
+package openapi
+
+const bearerAuth = "bearerAuth"
+
+// addAuthPaths documents the login, refresh and password endpoints, login and refresh are the only ones without a token
+func addAuthPaths(doc *Document) {
+	doc.Paths["/auth/login"] = &PathItem{Post: authOperation("login", "Log in with username and password", &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"username": {Type: "string"},
+			"password": {Type: "string"},
+		},
+		Required: []string{"username", "password"},
+	})}
+	refresh := authOperation("refresh", "Exchange a refresh token for new tokens", &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"refresh_token": {Type: "string"},
+		},
+		Required: []string{"refresh_token"},
+	})
+	delete(refresh.Responses, "429")
+	doc.Paths["/auth/refresh"] = &PathItem{Post: refresh}
+
+	op := authOperation("changePassword", "Change the password of the logged in user, the tokens issued before and the API keys of the user are revoked", &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"old_password": {Type: "string"},
+			"new_password": {Type: "string", Description: "8 to 72 characters and at most 72 bytes, different from old_password"},
+		},
+		Required: []string{"old_password", "new_password"},
+	})
+	op.Responses["200"] = &Response{Description: "Password changed, the new tokens of the user", Content: jsonContent(ref("Tokens"))}
+	op.Responses["403"] = &Response{Description: "API keys can not change passwords", Content: jsonContent(ref("Error"))}
+	op.Security = []map[string][]string{{bearerAuth: {}}}
+	doc.Paths["/auth/password"] = &PathItem{Post: op}
+}
+
+func authOperation(id, summary string, body *Schema) *Operation {
+	return &Operation{
+		Tags:        []string{"auth"},
+		Summary:     summary,
+		OperationID: id,
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(body)},
+		Responses: map[string]*Response{
+			"200": {Description: "OK", Content: jsonContent(ref("Tokens"))},
+			"400": {Description: "Invalid request", Content: jsonContent(ref("Error"))},
+			"401": {Description: "Invalid credentials or token", Content: jsonContent(ref("Error"))},
+			"429": {Description: "Account locked after repeated failures, or too many login attempts from the client IP", Content: jsonContent(ref("Error"))},
+		},
+	}
+}
+
+func tokensSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"access_token":  {Type: "string"},
+			"refresh_token": {Type: "string"},
+			"token_type":    {Type: "string", Enum: []interface{}{"Bearer"}},
+			"expires_in":    {Type: "integer", Description: "lifetime of the access token in seconds"},
+		},
+		Required: []string{"access_token", "refresh_token", "token_type", "expires_in"},
+	}
+}
@@ -0,0 +1,51 @@
+package openapi
+
+// addBatchPath documents /batch, the queries are documented by the list operations of their groups
+func addBatchPath(doc *Document) {
+	query := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":    {Type: "string", Description: "unique name of the query, the key of its result"},
+			"form":    {Type: "string", Description: "entity group"},
+			"version": {Type: "string"},
+			"query": {
+				Type:        "object",
+				Description: `query document of the group, a value {"$ref": "name.path"} is taken from an earlier query, e.g. classes.list.*.id`,
+			},
+		},
+		Required: []string{"name", "form", "query"},
+	}
+	result := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"status": {Type: "integer", Description: "HTTP status of the query"},
+			"data":   {Type: "object", Description: "result of the query, same as the list operation of the group"},
+			"error":  ref("Error"),
+		},
+		Required: []string{"status"},
+	}
+
+	doc.Paths["/batch"] = &PathItem{Post: &Operation{
+		Tags:        []string{"batch"},
+		Summary:     "Run several named queries concurrently, each with its own result or error",
+		OperationID: "batch",
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(&Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"queries": {Type: "array", Items: query, MinItems: integer(1), MaxItems: integer(20)},
+			},
+			Required: []string{"queries"},
+		})},
+		Responses: map[string]*Response{
+			"200": {Description: "OK", Content: jsonContent(&Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"results": {Type: "object", Description: "results keyed by the names of the queries", AdditionalProperties: result},
+				},
+			})},
+			"400": {Description: "Invalid request", Content: jsonContent(ref("Error"))},
+			"401": {Description: "Missing or invalid access token or API key", Content: jsonContent(ref("Error"))},
+		},
+		Security: entitySecurity(),
+	}}
+}
@@ -0,0 +1,48 @@
+package openapi
+
+import (
+	"fmt"
+
+	"github.com/go-bread/components/graphql"
+)
+
+// addGraphQLPath documents /graphql, the schema itself is available by introspection
+func addGraphQLPath(doc *Document) {
+	graphQLError := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"message":    {Type: "string"},
+			"locations":  {Type: "array", Items: &Schema{Type: "object"}},
+			"path":       {Type: "array", Items: &Schema{}},
+			"extensions": {Type: "object", Description: "code and status of the error, and the field of the query when known"},
+		},
+	}
+	result := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"data":   {Type: "object", Description: "a root field per entity group, null when its query failed"},
+			"errors": {Type: "array", Items: graphQLError},
+		},
+	}
+
+	doc.Paths["/graphql"] = &PathItem{Post: &Operation{
+		Tags:        []string{"graphql"},
+		Summary:     "Query the entity groups with GraphQL, the root fields are queried as a batch",
+		OperationID: "graphql",
+		RequestBody: &RequestBody{Required: true, Content: jsonContent(&Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"query":         {Type: "string"},
+				"operationName": {Type: "string"},
+				"variables":     {Type: "object"},
+			},
+			Required: []string{"query"},
+		})},
+		Responses: map[string]*Response{
+			"200": {Description: "OK, the errors of the root fields are listed in errors", Content: jsonContent(result)},
+			"400": {Description: fmt.Sprintf("Invalid or unsupported GraphQL document, or nested more than %d levels or selecting more than %d fields with its fragments expanded", graphql.MaxDepth, graphql.MaxComplexity), Content: jsonContent(result)},
+			"401": {Description: "Missing or invalid access token or API key", Content: jsonContent(ref("Error"))},
+		},
+		Security: entitySecurity(),
+	}}
+}
@@ -0,0 +1,44 @@
+package openapi
+
+// metaOperation documents /meta/:form, the fields depend on the permissions of the caller
+func metaOperation(gn, prefix string) *Operation {
+	return &Operation{
+		Tags:        []string{gn},
+		Summary:     "Describe the fields of " + gn + " the caller may use",
+		OperationID: "meta" + prefix,
+		Responses: map[string]*Response{
+			"200": {Description: "OK", Content: jsonContent(ref("Meta"))},
+			"401": {Description: "Missing or invalid access token or API key", Content: jsonContent(ref("Error"))},
+			"403": {Description: "The API key is not scoped to the group", Content: jsonContent(ref("Error"))},
+			"404": {Description: "Entity group not found", Content: jsonContent(ref("Error"))},
+		},
+		Security: entitySecurity(),
+	}
+}
+
+func metaSchema() *Schema {
+	field := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":       {Type: "string"},
+			"type":       {Type: "string", Enum: []interface{}{"integer", "number", "string", "boolean", "datetime"}},
+			"filterable": {Type: "boolean"},
+			"operators":  {Type: "array", Items: &Schema{Type: "string"}},
+			"orderable":  {Type: "boolean"},
+			"writable":   {Type: "boolean"},
+			"masked":     {Type: "boolean", Description: "the values are masked, the field can only be filtered by exact values"},
+			"deprecated": {Type: "string", Description: "deprecation message of the field"},
+		},
+		Required: []string{"name", "type", "filterable", "orderable", "writable"},
+	}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":       {Type: "string"},
+			"version":    {Type: "string"},
+			"searchable": {Type: "boolean", Description: "whether _search may be used"},
+			"fields":     {Type: "array", Items: field, Description: "readable fields of the caller"},
+		},
+		Required: []string{"name", "searchable", "fields"},
+	}
+}
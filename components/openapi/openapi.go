@@ -0,0 +1,343 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-bread/components/database/condition"
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/components/entity/models"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/timezone"
+	"github.com/go-bread/validators/query"
+)
+
+const Version = "3.0.3"
+
+var DefaultInfo = Info{
+	Title:       "bread",
+	Description: "Entity query API generated from the registered entity groups",
+	Version:     "1.0",
+}
+
+// Build generates the OpenAPI document of all the entity groups in fieldsMap
+func Build(fieldsMap group.FieldsMap) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    DefaultInfo,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				"Pagination": paginationSchema(),
+				"Error":      errorSchema(),
+				"Tokens":     tokensSchema(),
+				"Meta":       metaSchema(),
+				"APIKey":     apiKeySchema(),
+			},
+			SecuritySchemes: map[string]*SecurityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				apiKeyAuth: {Type: "apiKey", Name: apikey.Header, In: "header"},
+			},
+		},
+	}
+	addAuthPaths(doc)
+	addAPIKeyPaths(doc)
+	addBatchPath(doc)
+	addGraphQLPath(doc)
+
+	names := make([]string, 0, len(fieldsMap))
+	for gn := range fieldsMap {
+		names = append(names, string(gn))
+	}
+	sort.Strings(names)
+
+	for _, gn := range names {
+		g := fieldsMap[consts.EntityGroupName(gn)]
+		g = g.Default() // 文档只描述默认版本的字段
+		prefix := schemaPrefix(gn)
+
+		doc.Components.Schemas[prefix+"Query"] = querySchema(g)
+		doc.Components.Schemas[prefix+"Row"] = rowSchema(g)
+		doc.Components.Schemas[prefix+"List"] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"list":      {Type: "array", Items: ref(prefix + "Row")},
+				"page_info": ref("Pagination"),
+			},
+		}
+		op := listOperation(gn, prefix)
+		if len(g.Versions) > 0 {
+			op.Parameters = append(op.Parameters, versionParameters(g)...)
+		}
+		doc.Paths["/list/"+gn] = &PathItem{Get: op, Post: postListOperation(op, prefix)}
+		doc.Paths["/meta/"+gn] = &PathItem{Get: metaOperation(gn, prefix)}
+	}
+
+	return doc
+}
+
+func listOperation(gn, prefix string) *Operation {
+	return &Operation{
+		Tags:        []string{gn},
+		Summary:     "List " + gn,
+		OperationID: "list" + prefix,
+		Parameters: []*Parameter{
+			{
+				Name:        "query",
+				In:          "query",
+				Description: "JSON encoded query document",
+				Required:    true,
+				Content:     jsonContent(ref(prefix + "Query")),
+			},
+			{
+				Name:        timezone.Header,
+				In:          "header",
+				Description: "IANA timezone of the datetime values in the query and the response, e.g. Asia/Shanghai",
+				Schema:      &Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]*Response{
+			"200": {Description: "OK", Content: jsonContent(ref(prefix + "List"))},
+			"400": {Description: "Invalid query", Content: jsonContent(ref("Error"))},
+			"401": {Description: "Missing or invalid access token or API key", Content: jsonContent(ref("Error"))},
+			"403": {Description: "No permission to use a field or to access the data, or the API key is not scoped to the group", Content: jsonContent(ref("Error"))},
+			"404": {Description: "Entity group not found", Content: jsonContent(ref("Error"))},
+			"429": {Description: "Too many requests or rows returned, retry after the Retry-After header; a query returning more rows than the limit allows at once must use pages", Content: jsonContent(ref("Error"))},
+			"500": {Description: "Internal error", Content: jsonContent(ref("Error"))},
+		},
+		Security: entitySecurity(),
+	}
+}
+
+// postListOperation is op with the query document as the JSON body instead of the query parameter
+func postListOperation(op *Operation, prefix string) *Operation {
+	post := *op
+	post.OperationID = "post" + op.OperationID
+	post.Parameters = op.Parameters[1:]
+	post.RequestBody = &RequestBody{Required: true, Content: jsonContent(ref(prefix + "Query"))}
+	return &post
+}
+
+func versionParameters(g group.EntityGroup) []*Parameter {
+	var versions []string
+	for v := range g.Versions {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	enum := make([]interface{}, 0, len(versions))
+	for _, v := range versions {
+		enum = append(enum, v)
+	}
+
+	desc := "version of the entity group, defaults to " + g.DefaultVersion
+	return []*Parameter{
+		{Name: "version", In: "query", Description: desc, Schema: &Schema{Type: "string", Enum: enum}},
+		{Name: query.VersionHeader, In: "header", Description: desc, Schema: &Schema{Type: "string", Enum: enum}},
+	}
+}
+
+func querySchema(g group.EntityGroup) *Schema {
+	var outputs, orderable []interface{}
+	props := make(map[string]*Schema)
+	namespaces := make(map[string]bool)
+	g.Walk(func(name string, f field.Field) {
+		// 命名空间输出其所有字段
+		for i := strings.Index(name, group.Separator); i >= 0; i = next(name, i) {
+			if ns := name[:i]; !namespaces[ns] {
+				namespaces[ns] = true
+				outputs = append(outputs, ns)
+			}
+		}
+		outputs = append(outputs, name)
+		if f.CanOrder {
+			orderable = append(orderable, name)
+		}
+		if f.CanQuery {
+			props[name] = conditionSchema(f)
+		}
+	})
+
+	if fields, ok := g.SearchFields(); ok {
+		props["_search"] = &Schema{
+			Type:        "string",
+			Description: "full text search in " + strings.Join(g.Search.Fields, ", ") + ", results can be ordered by " + group.RelevanceField,
+		}
+		if len(fields) > 0 {
+			orderable = append(orderable, group.RelevanceField)
+		}
+	}
+
+	props["fields"] = &Schema{
+		Type:        "array",
+		Description: "fields to return",
+		MinItems:    integer(1),
+		Items:       &Schema{Type: "string", Enum: outputs},
+	}
+	props["_page"] = &Schema{Type: "integer", Minimum: float(1)}
+	props["_page_size"] = &Schema{Type: "integer", Minimum: float(1), Maximum: float(query.MaxPageSize)}
+	if len(orderable) > 0 {
+		props["_order_by"] = &Schema{
+			Type:        "array",
+			Description: "list of [field, direction] pairs, direction is asc|desc optionally followed by nulls first|last",
+			Items: &Schema{
+				Type:     "array",
+				MinItems: integer(2),
+				MaxItems: integer(2),
+				Items:    &Schema{Type: "string", Enum: append(orderable, directions()...)},
+			},
+		}
+	}
+
+	// 查询策略写入描述, 无法用json schema表达
+	var policies []string
+	for _, p := range g.Policies {
+		filters := make([]string, len(p.Filters))
+		for i, f := range p.Filters {
+			filters[i] = f.String()
+		}
+		policies = append(policies, "must filter by one of: "+strings.Join(filters, ", "))
+	}
+
+	return &Schema{
+		Type:        "object",
+		Description: strings.Join(policies, "; "),
+		Properties:  props,
+		Required:    []string{"fields"},
+	}
+}
+
+// 默认条件: 单值为等于, 数组为in; 对象的键为字段允许的其他操作符, 如 {"gte": 1, "lt": 10}
+func conditionSchema(f field.Field) *Schema {
+	s := inputSchema(f.TableField)
+	oneOf := []*Schema{s, {Type: "array", Items: inputSchema(f.TableField)}}
+
+	props := make(map[string]*Schema)
+	for _, op := range f.Operators() {
+		key, ok := condition.OperatorKeys[op]
+		if !ok {
+			continue
+		}
+		if op == "like" {
+			props[key] = &Schema{Type: "string", Description: "contains the string"}
+		} else {
+			props[key] = inputSchema(f.TableField)
+		}
+	}
+	if len(props) > 0 {
+		oneOf = append(oneOf, &Schema{Type: "object", Properties: props, Description: "all the operators must match"})
+	}
+	return &Schema{OneOf: oneOf}
+}
+
+// inputSchema is the schema of a condition value of tf, times are strings
+func inputSchema(tf models.TableField) *Schema {
+	if tf.IsTime {
+		return &Schema{Type: "string", Description: "formatted as " + models.DatetimeLayouts[0] + `, a range "from..to" or relative to now such as now-7d`}
+	}
+	return kindSchema(tf.Type)
+}
+
+func rowSchema(g group.EntityGroup) *Schema {
+	props := make(map[string]*Schema)
+	g.Walk(func(name string, f field.Field) {
+		if f.Callback != nil {
+			setProperty(props, name, &Schema{Description: "formatted by callback", Nullable: true})
+			return
+		}
+		s := kindSchema(f.OutputKind())
+		s.Nullable = true
+		setProperty(props, name, s)
+	})
+
+	return &Schema{Type: "object", Properties: props}
+}
+
+// setProperty describes the fields of namespaces as nested objects
+func setProperty(props map[string]*Schema, path string, s *Schema) {
+	names := strings.Split(path, group.Separator)
+	for _, name := range names[:len(names)-1] {
+		ns, ok := props[name]
+		if !ok {
+			ns = &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			props[name] = ns
+		}
+		props = ns.Properties
+	}
+	props[names[len(names)-1]] = s
+}
+
+// next returns the index of the separator after i, -1 if none
+func next(name string, i int) int {
+	j := strings.Index(name[i+1:], group.Separator)
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+func kindSchema(k reflect.Kind) *Schema {
+	switch k {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Minimum: float(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	default:
+		return &Schema{}
+	}
+}
+
+func paginationSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"page":         {Type: "integer"},
+			"page_size":    {Type: "integer"},
+			"total_number": {Type: "integer"},
+		},
+	}
+}
+
+func errorSchema() *Schema {
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"code":    {Type: "integer"},
+			"message": {Type: "string"},
+			"field":   {Type: "string", Description: "path of the invalid field, e.g. class.id or id[1]"},
+		},
+		Required: []string{"code", "message"},
+	}
+}
+
+// student_score -> StudentScore
+func schemaPrefix(gn string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(gn, "_") {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func directions() []interface{} {
+	d := make([]interface{}, len(query.Directions))
+	for i, v := range query.Directions {
+		d[i] = v
+	}
+	return d
+}
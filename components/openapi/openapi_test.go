@@ -0,0 +1,51 @@
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/go-bread/components/entity/field"
+	"github.com/go-bread/components/entity/models"
+)
+
+func TestConditionSchema(t *testing.T) {
+	cases := []struct {
+		name string
+		f    field.Field
+		keys []string // 对象形式的键, nil时没有对象形式
+	}{
+		{"plain", field.Field{TableField: models.Class.Id, CanQuery: true}, nil},
+		{"time", field.Field{TableField: models.Class.CreateTime, CanQuery: true}, []string{"gt", "gte", "lt", "lte"}},
+		{"string rule", field.Field{TableField: models.Class.ClassName, CanQuery: true, Rule: "string,max=20"}, []string{"like", "ne"}},
+		{"int rule", field.Field{TableField: models.Class.Id, CanQuery: true, Rule: "int,min=1"}, []string{"gt", "gte", "lt", "lte", "ne"}},
+		{"template", field.Field{TableField: models.Class.ClassName, CanQuery: true, Template: "t"}, nil},
+	}
+	for _, c := range cases {
+		s := conditionSchema(c.f)
+		var object *Schema
+		for _, o := range s.OneOf {
+			if o.Type == "object" {
+				object = o
+			}
+		}
+		if c.keys == nil {
+			if object != nil {
+				t.Errorf("%s: unexpected object form %v", c.name, object.Properties)
+			}
+			continue
+		}
+		if object == nil {
+			t.Errorf("%s: no object form", c.name)
+			continue
+		}
+		keys := make([]string, 0, len(object.Properties))
+		for k := range object.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		if !reflect.DeepEqual(keys, c.keys) {
+			t.Errorf("%s: keys %v, want %v", c.name, keys, c.keys)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-bread/components/codegen"
+	"github.com/go-bread/components/entity"
+)
+
+func gen(args []string) {
+	if len(args) == 0 || args[0] != "client" {
+		usage()
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("gen client", flag.ExitOnError)
+	out := fs.String("out", "client", "output directory")
+	langs := fs.String("lang", "ts,go", "comma separated list of languages: ts, go")
+	pkg := fs.String("package", "bread", "name of the generated go package")
+	_ = fs.Parse(args[1:])
+	entity.InitGroups(entity.FieldsMap)
+
+	for _, lang := range strings.Split(*langs, ",") {
+		var (
+			src  []byte
+			path string
+			err  error
+		)
+		switch strings.TrimSpace(lang) {
+		case "ts":
+			src, err = codegen.TypeScript(entity.FieldsMap)
+			path = filepath.Join(*out, "bread.ts")
+		case "go":
+			src, err = codegen.Go(entity.FieldsMap, *pkg)
+			path = filepath.Join(*out, *pkg, *pkg+".go")
+		default:
+			log.Fatalf("gen client: unsupported language %q", lang)
+		}
+		if err != nil {
+			log.Fatalf("gen client: %s: %v", lang, err)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Fatalf("gen client: %v", err)
+		}
+		if err := ioutil.WriteFile(path, src, 0644); err != nil {
+			log.Fatalf("gen client: %v", err)
+		}
+		log.Printf("[info] generated %s", path)
+	}
+}
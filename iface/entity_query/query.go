@@ -3,6 +3,7 @@ package entity_query
 
 import "github.com/gin-gonic/gin"
 
+// CallbackFunc formats the value of a field, the context is nil for the gRPC calls
 type CallbackFunc func(*gin.Context, interface{}, map[string]interface{}, *LocalStorage) interface{}
 
 type LocalStorage struct {
@@ -1,5 +1,7 @@
 package validator
 
+import "time"
+
 type Validator interface {
 	Validate(v interface{}) error
 	TransferCondition(table, field string, v interface{}, params map[string]interface{}) []Condition
@@ -13,3 +15,14 @@ type Condition interface {
 	GetSql() string
 	ConditionValue() []interface{}
 }
+
+// OperatorLister is implemented by validators which restrict the condition operators of a field
+type OperatorLister interface {
+	Operators() []string
+}
+
+// Localizer is implemented by validators whose values are wall clock times, e.g. dates:
+// InLocation returns the validator parsing them in the timezone of the request
+type Localizer interface {
+	InLocation(loc *time.Location) Validator
+}
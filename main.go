@@ -1,20 +1,27 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
 
 	"github.com/gin-gonic/gin"
 
+	outputs "github.com/go-bread/components/database/output"
+	"github.com/go-bread/components/entity"
 	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/ratelimit"
 	"github.com/go-bread/pkg/setting"
+	"github.com/go-bread/pkg/timezone"
 	"github.com/go-bread/routers"
+	"github.com/go-bread/routers/rpc"
 )
 
 func init() {
-	setting.Setup()
-	models.Setup()
 	//logging.Setup()
 	//util.Setup()
 }
@@ -26,6 +33,46 @@ func init() {
 // @license.name MIT
 // @license.url https://github.com/go-bread/blob/master/LICENSE
 func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "", "serve":
+		serve()
+	case "gen":
+		gen(flag.Args()[1:])
+	case "migrate":
+		migrateSchema(flag.Args()[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: bread <command> [arguments]
+
+commands:
+	serve         start the http server, and the grpc server when GrpcPort is set (default)
+	gen client    generate typed clients from the entity groups
+	migrate       print (and with -apply run) the DDL syncing the database with the entity models
+`)
+}
+
+func serve() {
+	setting.Setup()
+	auth.Setup()
+	models.Setup()
+	auth.SetVersionFunc(models.GetTokenVersion)
+	timezone.Setup()
+	ratelimit.Setup()
+	if setting.EntitySetting.MaskKey != "" {
+		outputs.SetMaskKey([]byte(setting.EntitySetting.MaskKey))
+	}
+	if setting.EntitySetting.Check {
+		checkEntities(setting.EntitySetting.Strict)
+	}
+
 	gin.SetMode(setting.ServerSetting.RunMode)
 
 	routersInit := routers.InitRouter()
@@ -42,6 +89,10 @@ func main() {
 		MaxHeaderBytes: maxHeaderBytes,
 	}
 
+	if setting.ServerSetting.GrpcPort != 0 {
+		go serveGrpc(fmt.Sprintf(":%d", setting.ServerSetting.GrpcPort))
+	}
+
 	log.Printf("[info] start http server listening %s", endPoint)
 
 	server.ListenAndServe()
@@ -60,3 +111,15 @@ func main() {
 	//	log.Printf("Server err: %v", err)
 	//}
 }
+
+// serveGrpc serves the entity service with server reflection
+func serveGrpc(endPoint string) {
+	lis, err := net.Listen("tcp", endPoint)
+	if err != nil {
+		log.Fatalf("grpc listen %s: %v", endPoint, err)
+	}
+	log.Printf("[info] start grpc server listening %s", endPoint)
+	if err := rpc.NewServer(entity.FieldsMap).Serve(lis); err != nil {
+		log.Fatalf("grpc serve: %v", err)
+	}
+}
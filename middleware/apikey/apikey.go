@@ -0,0 +1,116 @@
+package apikey
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/tenant"
+	"github.com/go-bread/pkg/timezone"
+)
+
+// APIKey authenticates the requests carrying the X-Api-Key header as the owner of the key,
+// other requests are passed to next, e.g. jwt.JWT().
+// 用户的角色和权限每次请求时重新加载, 字段权限, 数据范围及租户与用户的请求相同
+func APIKey(next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(apikey.Header)
+		if key == "" {
+			next(c)
+			return
+		}
+
+		identity, scope, err := Identify(key)
+		if err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		auth.SetIdentity(c, identity)
+		apikey.SetScope(c, scope)
+		if identity.TenantID != 0 {
+			tenant.SetUser(c, identity.TenantID)
+		}
+		if loc := timezone.User(identity.Timezone); loc != nil {
+			timezone.SetUser(c, loc)
+		}
+		c.Next()
+	}
+}
+
+// Identify returns the identity of the owner of key and the scope of key, e.g. for a gRPC call
+func Identify(key string) (*auth.Identity, *apikey.Scope, error) {
+	now := time.Now()
+	k, a, err := authenticate(key, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 最后使用时间只用于审计, 更新失败不影响请求
+	if err := models.TouchAPIKey(k.ID, now); err != nil {
+		log.Printf("apikey: touch %d: %v", k.ID, err)
+	}
+
+	identity := auth.NewIdentity(a.ID, a.Username, a.Roles, a.Permissions, a.Timezone)
+	identity.TenantID = a.TenantID
+	return &identity, apikey.NewScope(k.ID, k.Groups, k.Operations), nil
+}
+
+func authenticate(key string, now time.Time) (*models.APIKey, *models.Auth, error) {
+	k, err := models.GetAPIKeyByHash(apikey.Hash(key))
+	if err != nil {
+		return nil, nil, e.Wrap(e.ERROR_DATABASE, err)
+	}
+	if k == nil || !k.Active(now) {
+		return nil, nil, e.New(e.ERROR_AUTH_API_KEY).WithField(apikey.Header)
+	}
+	a, err := models.GetAuthByID(k.AuthID)
+	if err != nil {
+		return nil, nil, e.Wrap(e.ERROR_DATABASE, err)
+	}
+	if a == nil {
+		return nil, nil, e.New(e.ERROR_AUTH_API_KEY).WithField(apikey.Header)
+	}
+	return k, a, nil
+}
+
+// Require limits the API keys to the entity group of the :form parameter and any of ops,
+// no ops only checks the group. 用户的请求不受限制
+func Require(ops ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, _ := apikey.GetScope(c)
+		if err := Check(s, c.Param("form"), ops...); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Check checks the scope s of an API key on the group form, e.g. for the queries of a batch; a nil s is a user and always allowed
+func Check(s *apikey.Scope, form string, ops ...string) error {
+	if s == nil || s.Allows(form, ops...) {
+		return nil
+	}
+	if len(ops) > 0 && s.Allows(form) {
+		return e.NewKey(e.ERROR_FORBIDDEN_API_KEY, "api_key.operation", form, ops[0])
+	}
+	return e.New(e.ERROR_FORBIDDEN_API_KEY, form)
+}
+
+// UserOnly rejects the requests authenticated by API keys, e.g. changing passwords and managing keys
+func UserOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := apikey.GetScope(c); ok {
+			_ = c.Error(e.NewKey(e.ERROR_FORBIDDEN_API_KEY, "api_key.user_only"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
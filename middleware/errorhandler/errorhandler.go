@@ -0,0 +1,36 @@
+package errorhandler
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/pkg/e"
+)
+
+// ErrorHandler renders the last error of the context and recovers from panics,
+// messages are translated by the Accept-Language header
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				render(c, e.Wrap(e.ERROR, fmt.Errorf("panic: %v", r)))
+			}
+		}()
+
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		render(c, e.As(c.Errors.Last().Err))
+	}
+}
+
+func render(c *gin.Context, err *e.Error) {
+	if err.Status() >= 500 {
+		log.Printf("[ERROR] %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	trans := e.Translator(c.GetHeader("Accept-Language"))
+	c.AbortWithStatusJSON(err.Status(), err.Render(trans))
+}
@@ -0,0 +1,45 @@
+package jwt
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/tenant"
+	"github.com/go-bread/pkg/timezone"
+)
+
+// JWT authenticates the request by the bearer access token of the Authorization header
+// and injects the identity of the token into the context
+func JWT() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := Authenticate(c.GetHeader("Authorization"))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="bread"`)
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+
+		auth.SetIdentity(c, &claims.Identity)
+		if claims.TenantID != 0 {
+			tenant.SetUser(c, claims.TenantID)
+		}
+		// 用户时区无效时使用默认时区, 不影响请求
+		if loc := timezone.User(claims.Timezone); loc != nil {
+			timezone.SetUser(c, loc)
+		}
+		c.Next()
+	}
+}
+
+// Authenticate returns the claims of the bearer access token of the Authorization header value, e.g. of a gRPC call
+func Authenticate(header string) (*auth.Claims, error) {
+	const prefix = "Bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return nil, e.New(e.ERROR_AUTH_TOKEN).WithField("Authorization")
+	}
+	return auth.ParseToken(strings.TrimSpace(header[len(prefix):]), auth.TokenAccess)
+}
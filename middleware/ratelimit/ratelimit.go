@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/ratelimit"
+	"github.com/go-bread/pkg/tenant"
+)
+
+// RateLimit limits the requests and the returned rows of each user on the entity group of the :form parameter,
+// the rows are reserved from the budget set on the context before each query, see ratelimit.Budget.
+// 存储出错时不限制, 只记录日志
+func RateLimit(fieldsMap group.FieldsMap) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, _ := auth.GetIdentity(c)
+		scope, _ := apikey.GetScope(c)
+		// 租户无效的请求在查询多租户的表时返回错误, 此处不计入任何租户
+		id, _ := tenant.Resolve(c)
+		budget, wait := acquire(id, subject(identity, scope, c.ClientIP()), fieldsMap, c.Param("form"))
+		if wait > 0 {
+			seconds := ratelimit.RetryAfter(wait)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			_ = c.Error(e.New(e.ERROR_RATE_LIMITED, seconds))
+			c.Abort()
+			return
+		}
+		if budget != nil {
+			ratelimit.SetBudget(c, budget)
+		}
+		c.Next()
+	}
+}
+
+// Login limits the login attempts of each client IP to ratelimit.Login,
+// 每次登录都要计算密码哈希, 未登录的请求只能按IP限制
+func Login() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l := ratelimit.Login
+		if !l.Enabled() {
+			c.Next()
+			return
+		}
+		key := "login:ip:" + c.ClientIP()
+		wait, err := ratelimit.GetStore().Take(key, l, 1, false)
+		if err != nil {
+			log.Printf("ratelimit: %s: %v", key, err)
+		} else if wait > 0 {
+			seconds := ratelimit.RetryAfter(wait)
+			c.Header("Retry-After", strconv.Itoa(seconds))
+			_ = c.Error(e.New(e.ERROR_RATE_LIMITED, seconds))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Acquire applies the limits of the group form to c, e.g. a query of a batch or a gRPC call, and sets the row budget of c
+func Acquire(c *caller.Caller, fieldsMap group.FieldsMap, form string) error {
+	id, _ := c.Tenant()
+	budget, wait := acquire(id, subject(c.Identity, c.Scope, c.IP), fieldsMap, form)
+	if wait > 0 {
+		seconds := ratelimit.RetryAfter(wait)
+		c.Header.Set("Retry-After", strconv.Itoa(seconds))
+		return e.New(e.ERROR_RATE_LIMITED, seconds)
+	}
+	c.Budget = budget
+	return nil
+}
+
+// acquire takes a request of the group form in the buckets of the tenant, returns the row budget when the rows are limited
+func acquire(tenantID int64, subject string, fieldsMap group.FieldsMap, form string) (*ratelimit.Budget, time.Duration) {
+	limits := ratelimit.Default
+	g, ok := fieldsMap[consts.EntityGroupName(form)]
+	if !ok {
+		// 不存在的实体组共用一个桶
+		form = "_"
+	} else if g.RateLimit != nil {
+		limits = *g.RateLimit
+	}
+	key := subject + ":" + form
+	store := ratelimit.GetStore()
+
+	if limits.Requests.Enabled() {
+		requests := tenant.CacheKey(tenantID, "requests:"+key)
+		wait, err := store.Take(requests, limits.Requests, 1, false)
+		if err != nil {
+			log.Printf("ratelimit: %s: %v", requests, err)
+		} else if wait > 0 {
+			return nil, wait
+		}
+	}
+	// 行数在查询前从额度中预留
+	if limits.Rows.Enabled() {
+		return ratelimit.NewBudget(store, tenant.CacheKey(tenantID, "rows:"+key), limits.Rows), 0
+	}
+	return nil, 0
+}
+
+// API key 与所属用户分开计数
+func subject(identity *auth.Identity, scope *apikey.Scope, ip string) string {
+	if scope != nil {
+		return fmt.Sprintf("key:%d", scope.ID)
+	}
+	if identity != nil {
+		return fmt.Sprintf("user:%d", identity.ID)
+	}
+	return "ip:" + ip
+}
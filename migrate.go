@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/go-bread/components/database/migrate"
+	"github.com/go-bread/components/database/schema"
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/setting"
+)
+
+func migrateSchema(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	apply := fs.Bool("apply", false, "apply the statements instead of only printing them")
+	_ = fs.Parse(args)
+
+	setting.Setup()
+	models.Setup()
+
+	db := models.GetDb()
+	tables, err := schema.Inspect(db)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	stmts, err := migrate.Diff(db.Dialect().GetName(), entity.FieldsMap, tables)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	// auth 不属于任何实体组, 按gorm模型补齐登录需要的列
+	authStmts, err := migrate.DiffModels(db, tables, &models.Auth{})
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	stmts = append(stmts, authStmts...)
+	if len(stmts) == 0 {
+		log.Printf("[info] schema is up to date")
+		return
+	}
+
+	for _, stmt := range stmts {
+		fmt.Printf("%s;\n\n", stmt)
+	}
+	if !*apply {
+		return
+	}
+
+	version, err := migrate.Apply(db, stmts)
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	log.Printf("[info] applied migration %s", version)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 最后使用时间的更新间隔, 避免每个请求都写数据库
+const apiKeyTouchInterval = time.Minute
+
+// APIKey is a long-lived credential of a user for service-to-service access, only its hash is stored.
+// 请求以所属用户的身份执行, 并且只能访问Groups中的实体组及执行Operations中的操作
+type APIKey struct {
+	ID         int    `gorm:"primary_key" json:"id"`
+	AuthID     int    `json:"auth_id"` // 所属的用户
+	Name       string `json:"name"`
+	Prefix     string `json:"prefix"` // key的开头部分, 用于辨认
+	KeyHash    string `json:"-"`
+	Groups     string `json:"groups"`     // 逗号分隔的实体组
+	Operations string `json:"operations"` // 逗号分隔的操作, 见 apikey.Operations
+	CreatedAt  int64  `json:"created_at"`
+	ExpiresAt  int64  `json:"expires_at"`   // 过期的unix时间, 0表示不过期
+	LastUsedAt int64  `json:"last_used_at"` // 最后使用的unix时间, 精确到分钟
+	RevokedAt  int64  `json:"revoked_at"`   // 吊销的unix时间, 0表示未吊销
+}
+
+// Active reports whether the key can be used at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == 0 && (k.ExpiresAt == 0 || k.ExpiresAt > now.Unix())
+}
+
+// CreateAPIKey stores a new key
+func CreateAPIKey(k *APIKey) error {
+	return db.Create(k).Error
+}
+
+// GetAPIKeyByHash gets the key of hash, nil if not exists
+func GetAPIKeyByHash(hash string) (*APIKey, error) {
+	var k APIKey
+	err := db.Where("key_hash = ?", hash).First(&k).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// GetAPIKeys lists the keys of user authID, including the revoked ones
+func GetAPIKeys(authID int) ([]APIKey, error) {
+	var keys []APIKey
+	err := db.Where("auth_id = ?", authID).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeAPIKey revokes the key id of user authID, false if the user has no such active key
+func RevokeAPIKey(id, authID int, now time.Time) (bool, error) {
+	res := db.Model(&APIKey{}).Where("id = ? AND auth_id = ? AND revoked_at = 0", id, authID).Update("revoked_at", now.Unix())
+	return res.RowsAffected > 0, res.Error
+}
+
+// TouchAPIKey records the use of key id, at most once per apiKeyTouchInterval
+func TouchAPIKey(id int, now time.Time) error {
+	return db.Model(&APIKey{}).
+		Where("id = ? AND last_used_at < ?", id, now.Add(-apiKeyTouchInterval).Unix()).
+		Update("last_used_at", now.Unix()).Error
+}
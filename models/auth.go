@@ -1,24 +1,169 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/go-bread/pkg/password"
+	"github.com/go-bread/pkg/setting"
+)
+
+// ErrAccountLocked is returned by Authenticate during the lockout after repeated failures
+var ErrAccountLocked = errors.New("account is locked")
 
 type Auth struct {
-	ID       int    `gorm:"primary_key" json:"id"`
-	Username string `json:"username"`
-	Password string `json:"password"`
+	ID             int    `gorm:"primary_key" json:"id"`
+	Username       string `json:"username"`
+	Password       string `json:"-"`           // password.Hash 的结果, 旧数据可能为明文
+	Roles          string `json:"roles"`       // 逗号分隔的角色
+	Permissions    string `json:"permissions"` // 逗号分隔的权限
+	Timezone       string `json:"timezone"`
+	TenantID       int64  `json:"tenant_id"` // 所属的租户, 0表示不属于任何租户
+	FailedAttempts int    `json:"-"`         // 连续登录失败次数
+	LockedUntil    int64  `json:"-"`         // 锁定截止的unix时间
+	TokenVersion   int    `json:"-"`         // 修改密码时递增, 之前签发的token失效
 }
 
 // CheckAuth checks if authentication information exists
 func CheckAuth(username, password string) (bool, error) {
-	var auth Auth
-	err := db.Select("id").Where(Auth{Username: username, Password: password}).First(&auth).Error
-	if err != nil && err != gorm.ErrRecordNotFound {
+	auth, err := Authenticate(username, password)
+	if err == ErrAccountLocked {
+		return false, nil
+	}
+	if err != nil {
 		return false, err
 	}
 
-	if auth.ID > 0 {
-		return true, nil
+	return auth != nil, nil
+}
+
+// Authenticate verifies the password of username, nil if the username or password is wrong.
+// 密码在Go中校验, 明文密码校验通过后替换为哈希;
+// 连续失败 AuthSetting.MaxLoginFailures 次后锁定 AuthSetting.LockDuration, 锁定期间返回ErrAccountLocked
+func Authenticate(username, pwd string) (*Auth, error) {
+	var auth Auth
+	err := db.Where("username = ?", username).First(&auth).Error
+	if err == gorm.ErrRecordNotFound {
+		// 用户不存在时同样计算一次哈希, 避免通过响应时间判断用户名是否存在
+		_, _, _ = password.Check(dummyHash(), pwd)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if auth.LockedUntil > now.Unix() {
+		return &auth, ErrAccountLocked
+	}
+
+	ok, needsRehash, err := password.Check(auth.Password, pwd)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, recordLoginFailure(&auth, now)
+	}
+
+	updates := map[string]interface{}{}
+	if auth.FailedAttempts > 0 || auth.LockedUntil > 0 {
+		updates["failed_attempts"] = 0
+		updates["locked_until"] = 0
+	}
+	if needsRehash {
+		hash, err := password.Hash(pwd)
+		if err != nil {
+			return nil, err
+		}
+		updates["password"] = hash
+	}
+	if len(updates) > 0 {
+		if err := db.Model(&Auth{}).Where("id = ?", auth.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &auth, nil
+}
+
+var (
+	dummyOnce sync.Once
+	dummy     string
+)
+
+func dummyHash() string {
+	dummyOnce.Do(func() {
+		dummy, _ = password.Hash("")
+	})
+	return dummy
+}
+
+func recordLoginFailure(auth *Auth, now time.Time) error {
+	return db.Model(&Auth{}).Where("id = ?", auth.ID).Updates(failureUpdates(auth.FailedAttempts, now)).Error
+}
+
+// failureUpdates returns the columns updated by a failed login after failed earlier ones, the last allowed failure locks the account
+func failureUpdates(failed int, now time.Time) map[string]interface{} {
+	updates := map[string]interface{}{"failed_attempts": gorm.Expr("failed_attempts + 1")}
+	if max := setting.AuthSetting.MaxLoginFailures; max > 0 && failed+1 >= max {
+		updates["failed_attempts"] = 0
+		updates["locked_until"] = now.Add(setting.AuthSetting.LockDuration).Unix()
+	}
+	return updates
+}
+
+// UpdateAuthPassword stores the hash of the new password of user id and returns the new token version,
+// 之前签发的token失效, 用户的API key全部吊销
+func UpdateAuthPassword(id int, pwd string, now time.Time) (int, error) {
+	hash, err := password.Hash(pwd)
+	if err != nil {
+		return 0, err
+	}
+	var version int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"password": hash, "token_version": gorm.Expr("token_version + 1")}
+		if err := tx.Model(&Auth{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&APIKey{}).Where("auth_id = ? AND revoked_at = 0", id).Update("revoked_at", now.Unix()).Error; err != nil {
+			return err
+		}
+		var a Auth
+		if err := tx.Select("token_version").Where("id = ?", id).First(&a).Error; err != nil {
+			return err
+		}
+		version = a.TokenVersion
+		return nil
+	})
+	return version, err
+}
+
+// GetTokenVersion returns the token version of user id, -1 if not exists
+func GetTokenVersion(id int) (int, error) {
+	var a Auth
+	err := db.Select("token_version").Where("id = ?", id).First(&a).Error
+	if err == gorm.ErrRecordNotFound {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return a.TokenVersion, nil
+}
+
+// GetAuthByID gets the user of id, nil if not exists
+func GetAuthByID(id int) (*Auth, error) {
+	var auth Auth
+	err := db.Where("id = ?", id).First(&auth).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return false, nil
+	return &auth, nil
 }
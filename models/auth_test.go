@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-bread/pkg/setting"
+)
+
+func TestFailureUpdates(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	defer func(max int, d time.Duration) {
+		setting.AuthSetting.MaxLoginFailures, setting.AuthSetting.LockDuration = max, d
+	}(setting.AuthSetting.MaxLoginFailures, setting.AuthSetting.LockDuration)
+	setting.AuthSetting.LockDuration = 15 * time.Minute
+
+	cases := []struct {
+		name   string
+		max    int
+		failed int
+		lock   bool
+	}{
+		{"first failure", 5, 0, false},
+		{"before the last", 5, 3, false},
+		{"last failure", 5, 4, true},
+		{"failures counted before a lower max", 3, 7, true},
+		{"single failure allowed", 1, 0, true},
+		{"lockout disabled", 0, 100, false},
+	}
+	for _, c := range cases {
+		setting.AuthSetting.MaxLoginFailures = c.max
+		updates := failureUpdates(c.failed, now)
+		locked, ok := updates["locked_until"]
+		if ok != c.lock {
+			t.Errorf("%s: locked %v, want %v", c.name, ok, c.lock)
+			continue
+		}
+		if !c.lock {
+			continue
+		}
+		if locked != now.Add(15*time.Minute).Unix() || updates["failed_attempts"] != 0 {
+			t.Errorf("%s: %v", c.name, updates)
+		}
+	}
+}
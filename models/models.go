@@ -3,6 +3,7 @@ package models
 import (
 	"fmt"
 	"log"
+	"net/url"
 
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"
@@ -27,11 +28,16 @@ func  GetDb() *gorm.DB {
 // Setup initializes the database instance
 func Setup() {
 	var err error
-	db, err = gorm.Open(setting.DatabaseSetting.Type, fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=Local",
+	loc := setting.DatabaseSetting.Timezone
+	if loc == "" {
+		loc = "Local"
+	}
+	db, err = gorm.Open(setting.DatabaseSetting.Type, fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=%s",
 		setting.DatabaseSetting.User,
 		setting.DatabaseSetting.Password,
 		setting.DatabaseSetting.Host,
-		setting.DatabaseSetting.Name))
+		setting.DatabaseSetting.Name,
+		url.QueryEscape(loc)))
 
 	if err != nil {
 		log.Fatalf("models.Setup err: %v", err)
@@ -0,0 +1,14 @@
+package models
+
+// TeacherClass records a class taught by a user
+type TeacherClass struct {
+	AuthID  int `json:"auth_id"`
+	ClassID int `json:"class_id"`
+}
+
+// GetTeacherClassIDs returns the ids of the classes taught by the user authID
+func GetTeacherClassIDs(authID int) ([]int, error) {
+	var ids []int
+	err := db.Model(&TeacherClass{}).Where("auth_id = ?", authID).Pluck("class_id", &ids).Error
+	return ids, err
+}
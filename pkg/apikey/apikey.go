@@ -0,0 +1,121 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Header carries the API key of service-to-service requests
+const Header = "X-Api-Key"
+
+// 操作, API key 只能执行声明的操作
+const (
+	OpList   = "list"
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpExport = "export"
+)
+
+// Operations lists the operations a key can be scoped to
+var Operations = []string{OpList, OpCreate, OpUpdate, OpDelete, OpExport}
+
+const (
+	keyPrefix  = "bread_"
+	contextKey = "bread.apikey"
+	// PrefixLength is the length of the stored prefix used to recognize a key
+	PrefixLength = len(keyPrefix) + 6
+)
+
+// Generate returns a new random key and its hash, only the hash is stored and the key is shown once
+func Generate() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = keyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, Hash(key), nil
+}
+
+// Hash returns the stored hash of key, the key is random so a plain sha256 is enough
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidOperation reports whether op is one of Operations
+func ValidOperation(op string) bool {
+	for _, o := range Operations {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+// Scope is the entity groups and operations of the key authenticating a request
+type Scope struct {
+	ID         int
+	Groups     []string
+	Operations []string
+}
+
+// NewScope builds the scope of a key, groups and operations are comma separated
+func NewScope(id int, groups, operations string) *Scope {
+	return &Scope{ID: id, Groups: splitList(groups), Operations: splitList(operations)}
+}
+
+// Allows reports whether the key may access the group with any of ops, no ops only checks the group
+func (s *Scope) Allows(group string, ops ...string) bool {
+	if !contains(s.Groups, group) {
+		return false
+	}
+	if len(ops) == 0 {
+		return true
+	}
+	for _, op := range ops {
+		if contains(s.Operations, op) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetScope injects the scope of the key into the request context
+func SetScope(ctx *gin.Context, s *Scope) {
+	ctx.Set(contextKey, s)
+}
+
+// GetScope returns the scope of the key of the request, false for the requests of users
+func GetScope(ctx *gin.Context) (*Scope, bool) {
+	v, ok := ctx.Get(contextKey)
+	if !ok {
+		return nil, false
+	}
+	s, ok := v.(*Scope)
+	return s, ok
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
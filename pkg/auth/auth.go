@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const contextKey = "bread.identity"
+
+// Identity is the authenticated user of a request
+type Identity struct {
+	ID          int      `json:"id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Timezone    string   `json:"timezone,omitempty"`  // 用户配置的时区
+	TenantID    int64    `json:"tenant_id,omitempty"` // 用户所属的租户, 0表示不属于任何租户
+}
+
+// NewIdentity builds the identity of a user, roles and permissions are comma separated
+func NewIdentity(id int, username, roles, permissions, timezone string) Identity {
+	return Identity{
+		ID:          id,
+		Username:    username,
+		Roles:       splitList(roles),
+		Permissions: splitList(permissions),
+		Timezone:    timezone,
+	}
+}
+
+func (i *Identity) HasRole(role string) bool {
+	return contains(i.Roles, role)
+}
+
+func (i *Identity) HasPermission(permission string) bool {
+	return contains(i.Permissions, permission)
+}
+
+// SetIdentity injects the identity into the request context
+func SetIdentity(ctx *gin.Context, identity *Identity) {
+	ctx.Set(contextKey, identity)
+}
+
+// GetIdentity returns the identity of the request, false for anonymous requests
+func GetIdentity(ctx *gin.Context) (*Identity, bool) {
+	v, ok := ctx.Get(contextKey)
+	if !ok {
+		return nil, false
+	}
+	identity, ok := v.(*Identity)
+	return identity, ok
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RolePrefix marks the roles in a list of required permissions, e.g. role:admin
+const RolePrefix = "role:"
+
+// Allowed reports whether the identity has any of required, an empty list allows everyone
+func (i *Identity) Allowed(required []string) bool {
+	if len(required) == 0 {
+		return true
+	}
+	if i == nil {
+		return false
+	}
+	for _, r := range required {
+		if strings.HasPrefix(r, RolePrefix) {
+			if i.HasRole(r[len(RolePrefix):]) {
+				return true
+			}
+		} else if i.HasPermission(r) {
+			return true
+		}
+	}
+	return false
+}
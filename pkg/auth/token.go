@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/setting"
+)
+
+// token类型, 刷新token不能用于访问接口
+const (
+	TokenAccess  = "access"
+	TokenRefresh = "refresh"
+)
+
+var (
+	secret     []byte
+	issuer     = "bread"
+	accessTTL  = 15 * time.Minute
+	refreshTTL = 7 * 24 * time.Hour
+)
+
+// SecretEnv is the environment variable of the signing secret, it takes precedence over [auth]
+const SecretEnv = "BREAD_JWT_SECRET"
+
+// 早期版本配置文件中的示例密钥, 不能用于签名
+const sampleSecret = "23347$040412"
+
+// Setup loads the signing secret and token lifetimes from [auth]
+func Setup() {
+	s, err := loadSecret()
+	if err != nil {
+		log.Fatalf("auth.Setup err: %v", err)
+	}
+	SetSecret(s)
+	if setting.AuthSetting.Issuer != "" {
+		issuer = setting.AuthSetting.Issuer
+	}
+	if setting.AuthSetting.AccessTokenTTL > 0 {
+		accessTTL = setting.AuthSetting.AccessTokenTTL
+	}
+	if setting.AuthSetting.RefreshTokenTTL > 0 {
+		refreshTTL = setting.AuthSetting.RefreshTokenTTL
+	}
+}
+
+// loadSecret reads the secret from SecretEnv, the file JwtSecretFile or JwtSecret, in that order
+func loadSecret() (string, error) {
+	s, from := os.Getenv(SecretEnv), SecretEnv
+	if s == "" && setting.AuthSetting.JwtSecretFile != "" {
+		b, err := ioutil.ReadFile(setting.AuthSetting.JwtSecretFile)
+		if err != nil {
+			return "", fmt.Errorf("[auth] JwtSecretFile: %v", err)
+		}
+		s, from = strings.TrimSpace(string(b)), setting.AuthSetting.JwtSecretFile
+	}
+	if s == "" {
+		s, from = setting.AuthSetting.JwtSecret, "[auth] JwtSecret"
+	}
+	switch s {
+	case "":
+		return "", fmt.Errorf("the signing secret is required, set %s, [auth] JwtSecretFile or JwtSecret", SecretEnv)
+	case sampleSecret:
+		return "", fmt.Errorf("the secret of %s is the sample of the configuration, generate a random one", from)
+	}
+	return s, nil
+}
+
+// SetSecret sets the HMAC secret signing the tokens
+func SetSecret(s string) {
+	secret = []byte(s)
+}
+
+type Claims struct {
+	Identity
+	Type    string `json:"typ"`
+	Version int    `json:"ver"` // 签发时用户的token版本, 见 SetVersionFunc
+	jwt.StandardClaims
+}
+
+// VersionFunc returns the current token version of user id, -1 when the user does not exist.
+// 版本在修改密码时递增, 之前签发的token随之失效
+type VersionFunc func(id int) (int, error)
+
+var versionOf VersionFunc
+
+// SetVersionFunc sets the lookup of the token versions checked by ParseToken, nil skips the check
+func SetVersionFunc(f VersionFunc) {
+	versionOf = f
+}
+
+// Tokens is the response of login and refresh
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // access token 的有效秒数
+}
+
+// GenerateTokens signs an access token carrying the identity and a refresh token carrying only the user,
+// roles and permissions are reloaded when refreshing. version is the token version of the user
+func GenerateTokens(identity Identity, version int) (*Tokens, error) {
+	now := time.Now()
+	access, err := sign(identity, version, TokenAccess, now, accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := sign(Identity{ID: identity.ID, Username: identity.Username}, version, TokenRefresh, now, refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessTTL / time.Second),
+	}, nil
+}
+
+func sign(identity Identity, version int, typ string, now time.Time, ttl time.Duration) (string, error) {
+	claims := Claims{
+		Identity: identity,
+		Type:     typ,
+		Version:  version,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   fmt.Sprint(identity.ID),
+			Issuer:    issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
+// ParseToken validates the signature, expiry, type and version of token
+func ParseToken(token, typ string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil {
+		if ve, ok := err.(*jwt.ValidationError); ok && ve.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, e.New(e.ERROR_AUTH_TOKEN_EXPIRED)
+		}
+		return nil, e.New(e.ERROR_AUTH_TOKEN)
+	}
+	if claims.Type != typ || claims.Issuer != issuer {
+		return nil, e.New(e.ERROR_AUTH_TOKEN)
+	}
+	if versionOf != nil {
+		version, err := versionOf(claims.ID)
+		if err != nil {
+			return nil, e.Wrap(e.ERROR_DATABASE, err)
+		}
+		if claims.Version != version {
+			return nil, e.New(e.ERROR_AUTH_TOKEN)
+		}
+	}
+	return claims, nil
+}
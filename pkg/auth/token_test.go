@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-bread/pkg/e"
+)
+
+func TestTokenVersion(t *testing.T) {
+	SetSecret("test secret")
+	versions := map[int]int{1: 2, 3: 0}
+	SetVersionFunc(func(id int) (int, error) {
+		if id == 4 {
+			return 0, errors.New("db down")
+		}
+		if v, ok := versions[id]; ok {
+			return v, nil
+		}
+		return -1, nil
+	})
+	defer SetVersionFunc(nil)
+
+	cases := []struct {
+		name    string
+		id      int
+		version int
+		typ     string // 解析时要求的类型
+		code    int    // 0为有效
+	}{
+		{"current", 1, 2, TokenAccess, 0},
+		{"refresh", 1, 2, TokenRefresh, 0},
+		{"password changed", 1, 1, TokenAccess, e.ERROR_AUTH_TOKEN},
+		{"refresh of a changed password", 1, 1, TokenRefresh, e.ERROR_AUTH_TOKEN},
+		{"first version", 3, 0, TokenAccess, 0},
+		{"deleted user", 2, 0, TokenAccess, e.ERROR_AUTH_TOKEN},
+		{"lookup error", 4, 0, TokenAccess, e.ERROR_DATABASE},
+	}
+	for _, c := range cases {
+		tokens, err := GenerateTokens(Identity{ID: c.id, Username: "u"}, c.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := tokens.AccessToken
+		if c.typ == TokenRefresh {
+			token = tokens.RefreshToken
+		}
+		claims, err := ParseToken(token, c.typ)
+		if c.code == 0 {
+			if err != nil || claims.ID != c.id {
+				t.Errorf("%s: %v %v", c.name, claims, err)
+			}
+			continue
+		}
+		if err == nil || e.As(err).Code != c.code {
+			t.Errorf("%s: got %v, want code %d", c.name, err, c.code)
+		}
+	}
+}
+
+func TestParseTokenErrors(t *testing.T) {
+	SetSecret("test secret")
+	tokens, err := GenerateTokens(Identity{ID: 1}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := sign(Identity{ID: 1}, 0, TokenAccess, time.Now().Add(-time.Hour), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		typ   string
+		code  int
+	}{
+		{"refresh as access", tokens.RefreshToken, TokenAccess, e.ERROR_AUTH_TOKEN},
+		{"access as refresh", tokens.AccessToken, TokenRefresh, e.ERROR_AUTH_TOKEN},
+		{"expired", expired, TokenAccess, e.ERROR_AUTH_TOKEN_EXPIRED},
+		{"tampered", tokens.AccessToken + "x", TokenAccess, e.ERROR_AUTH_TOKEN},
+		{"garbage", "a.b.c", TokenAccess, e.ERROR_AUTH_TOKEN},
+	}
+	for _, c := range cases {
+		_, err := ParseToken(c.token, c.typ)
+		if err == nil || e.As(err).Code != c.code {
+			t.Errorf("%s: got %v, want code %d", c.name, err, c.code)
+		}
+	}
+
+	SetSecret("other secret")
+	if _, err := ParseToken(tokens.AccessToken, TokenAccess); err == nil {
+		t.Error("a token of another secret is valid")
+	}
+}
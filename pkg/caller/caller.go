@@ -0,0 +1,90 @@
+package caller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/ratelimit"
+	"github.com/go-bread/pkg/tenant"
+	"github.com/go-bread/pkg/timezone"
+	"github.com/go-bread/validators/query"
+)
+
+// Caller is the user of an entity operation and the state of its request,
+// built by FromGin for the HTTP API and by New for the gRPC calls.
+// 租户及时区在构建时解析, 出错时只在使用到时返回错误
+type Caller struct {
+	Identity *auth.Identity    // 登录的用户, 匿名请求为nil
+	Scope    *apikey.Scope     // API key的范围, 用户的请求为nil
+	Budget   *ratelimit.Budget // 返回行数的额度, 不限制时为nil
+	IP       string            // 客户端的IP, 匿名请求按IP限流
+	Version  string            // 请求的版本, 见 query.VersionHeader
+	Header   http.Header       // 响应头, 如 Deprecation, Warning 及 Retry-After
+	Context  *gin.Context      // 传给字段的回调函数, gRPC调用时为nil
+
+	tenant      int64
+	tenantErr   error
+	location    *time.Location
+	locationErr error
+}
+
+// New builds the caller of identity and scope, the tenant and the timezone are chosen from the headers of the request
+func New(identity *auth.Identity, scope *apikey.Scope, ip string, header http.Header) *Caller {
+	c := &Caller{
+		Identity: identity,
+		Scope:    scope,
+		IP:       ip,
+		Version:  header.Get(query.VersionHeader),
+		Header:   make(http.Header),
+	}
+	var userTenant int64
+	var userLocation *time.Location
+	if identity != nil {
+		userTenant = identity.TenantID
+		userLocation = timezone.User(identity.Timezone)
+	}
+	c.tenant, c.tenantErr = tenant.Choose(userTenant, header.Get(tenant.Header))
+	c.location, c.locationErr = timezone.Choose(header.Get(timezone.Header), userLocation)
+	return c
+}
+
+// FromGin builds the caller of the HTTP request of ctx as set up by the middlewares, the headers are written to the response
+func FromGin(ctx *gin.Context) *Caller {
+	c := &Caller{
+		Budget:  ratelimit.GetBudget(ctx),
+		IP:      ctx.ClientIP(),
+		Version: query.RequestVersion(ctx),
+		Header:  ctx.Writer.Header(),
+		Context: ctx,
+	}
+	c.Identity, _ = auth.GetIdentity(ctx)
+	c.Scope, _ = apikey.GetScope(ctx)
+	c.tenant, c.tenantErr = tenant.Resolve(ctx)
+	c.location, c.locationErr = timezone.Resolve(ctx)
+	return c
+}
+
+// Copy returns a copy of c with its own response headers, e.g. for a query of a batch run concurrently
+func (c *Caller) Copy() *Caller {
+	cp := *c
+	cp.Header = make(http.Header)
+	// gin.Context不能并发使用
+	if c.Context != nil {
+		cp.Context = c.Context.Copy()
+	}
+	return &cp
+}
+
+// Tenant returns the tenant of the caller, 0 when the caller belongs to no tenant
+func (c *Caller) Tenant() (int64, error) {
+	return c.tenant, c.tenantErr
+}
+
+// Location returns the timezone the times of the caller are parsed and formatted in
+func (c *Caller) Location() (*time.Location, error) {
+	return c.location, c.locationErr
+}
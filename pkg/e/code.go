@@ -0,0 +1,76 @@
+package e
+
+import "net/http"
+
+const (
+	SUCCESS        = 200
+	ERROR          = 500
+	INVALID_PARAMS = 400
+
+	ERROR_QUERY_REQUIRED       = 10001
+	ERROR_NOT_EXIST_GROUP      = 10002
+	ERROR_INVALID_VERSION      = 10003
+	ERROR_NOT_EXIST_FIELD      = 10004
+	ERROR_FIELD_NOT_QUERYABLE  = 10005
+	ERROR_FIELD_NOT_ORDERABLE  = 10006
+	ERROR_INVALID_ORDER        = 10007
+	ERROR_INVALID_VALUE        = 10008
+	ERROR_INVALID_TIMEZONE     = 10009
+	ERROR_RULE                 = 10010
+	ERROR_SEARCH_NOT_SUPPORTED = 10011
+	ERROR_FILTER_REQUIRED      = 10012
+	ERROR_FIELD_READ_ONLY      = 10013
+	ERROR_NOT_EXIST_API_KEY    = 10014
+	ERROR_BATCH_DEPENDENCY     = 10015
+	ERROR_GROUP_READ_ONLY      = 10016
+	ERROR_WRITE_LIMIT          = 10017
+
+	ERROR_DATABASE = 20001
+
+	ERROR_AUTH               = 30001
+	ERROR_AUTH_TOKEN         = 30002
+	ERROR_AUTH_TOKEN_EXPIRED = 30003
+	ERROR_AUTH_LOCKED        = 30004
+	ERROR_FORBIDDEN_FIELD    = 30005
+	ERROR_FORBIDDEN_ROW      = 30006
+	ERROR_TENANT_REQUIRED    = 30007
+	ERROR_INVALID_TENANT     = 30008
+	ERROR_FIELD_MASKED       = 30009
+	ERROR_AUTH_API_KEY       = 30010
+	ERROR_FORBIDDEN_API_KEY  = 30011
+
+	ERROR_RATE_LIMITED = 40001
+	ERROR_ROW_QUOTA    = 40002
+)
+
+// HTTP状态码, 未列出的错误码为400
+var statusFlags = map[int]int{
+	SUCCESS:                 http.StatusOK,
+	ERROR:                   http.StatusInternalServerError,
+	ERROR_NOT_EXIST_GROUP:   http.StatusNotFound,
+	ERROR_NOT_EXIST_API_KEY: http.StatusNotFound,
+	ERROR_BATCH_DEPENDENCY:  http.StatusFailedDependency,
+	ERROR_DATABASE:          http.StatusInternalServerError,
+
+	ERROR_AUTH:               http.StatusUnauthorized,
+	ERROR_AUTH_TOKEN:         http.StatusUnauthorized,
+	ERROR_AUTH_TOKEN_EXPIRED: http.StatusUnauthorized,
+	ERROR_AUTH_LOCKED:        http.StatusTooManyRequests,
+	ERROR_FORBIDDEN_FIELD:    http.StatusForbidden,
+	ERROR_FORBIDDEN_ROW:      http.StatusForbidden,
+	ERROR_TENANT_REQUIRED:    http.StatusForbidden,
+	ERROR_FIELD_MASKED:       http.StatusForbidden,
+	ERROR_AUTH_API_KEY:       http.StatusUnauthorized,
+	ERROR_FORBIDDEN_API_KEY:  http.StatusForbidden,
+
+	ERROR_RATE_LIMITED: http.StatusTooManyRequests,
+	ERROR_ROW_QUOTA:    http.StatusTooManyRequests,
+}
+
+// GetStatus returns the HTTP status of code
+func GetStatus(code int) int {
+	if s, ok := statusFlags[code]; ok {
+		return s
+	}
+	return http.StatusBadRequest
+}
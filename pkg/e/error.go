@@ -0,0 +1,161 @@
+package e
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	ut "github.com/go-playground/universal-translator"
+)
+
+// 消息最多使用的参数个数
+const maxParams = 2
+
+// Error is the error returned by the api, its message is translated when rendered
+type Error struct {
+	Code   int
+	Key    string   // 消息key, 为空时使用错误码的默认消息
+	Params []string // 消息参数
+	Field  string   // 出错的字段路径, 如 class.id, id[1]
+	cause  error
+}
+
+// New returns an error with the default message of code
+func New(code int, params ...interface{}) *Error {
+	return &Error{Code: code, Params: toStrings(params)}
+}
+
+// NewKey returns an error of code with the message of key
+func NewKey(code int, key string, params ...interface{}) *Error {
+	return &Error{Code: code, Key: key, Params: toStrings(params)}
+}
+
+// Invalid returns an ERROR_INVALID_VALUE error with the message of key
+func Invalid(key string, params ...interface{}) *Error {
+	return NewKey(ERROR_INVALID_VALUE, key, params...)
+}
+
+// Wrap keeps err as the cause, the cause is logged but never rendered
+func Wrap(code int, err error) *Error {
+	return &Error{Code: code, cause: err}
+}
+
+// As converts err into *Error, errors of other types are treated as internal errors
+func As(err error) *Error {
+	var ae *Error
+	if errors.As(err, &ae) {
+		return ae
+	}
+	return Wrap(ERROR, err)
+}
+
+// AsInvalid converts err into *Error, errors of other types are treated as invalid values
+func AsInvalid(err error) *Error {
+	var ae *Error
+	if errors.As(err, &ae) {
+		return ae
+	}
+	return Invalid("invalid_value", err.Error())
+}
+
+// WithField prefixes the field path of the error
+func (err *Error) WithField(field string) *Error {
+	c := *err
+	switch {
+	case c.Field == "":
+		c.Field = field
+	case strings.HasPrefix(c.Field, "["):
+		c.Field = field + c.Field
+	default:
+		c.Field = field + "." + c.Field
+	}
+	return &c
+}
+
+func (err *Error) Status() int {
+	return GetStatus(err.Code)
+}
+
+func (err *Error) Unwrap() error {
+	return err.cause
+}
+
+func (err *Error) Error() string {
+	msg := err.Message(uni.GetFallback())
+	if err.cause != nil {
+		msg += ": " + err.cause.Error()
+	}
+	return msg
+}
+
+// Message translates the error, the field path is prepended to the messages of invalid values
+func (err *Error) Message(trans ut.Translator) string {
+	key := err.Key
+	if key == "" {
+		key = codeKeys[err.Code]
+	}
+	params := err.Params
+	// 字段相关的消息默认使用字段路径作为参数
+	if len(params) == 0 && err.Field != "" {
+		params = []string{err.Field}
+	}
+	msg := translate(trans, key, params...)
+	if err.Code == ERROR_INVALID_VALUE && err.Field != "" {
+		msg = translate(trans, "field", err.Field, msg)
+	}
+	return msg
+}
+
+// Response is the body of an error response
+type Response struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+func (err *Error) Render(trans ut.Translator) Response {
+	return Response{Code: err.Code, Message: err.Message(trans), Field: err.Field}
+}
+
+func translate(trans ut.Translator, key string, params ...string) string {
+	// 参数少于消息中的占位符时T会panic
+	for len(params) < maxParams {
+		params = append(params, "")
+	}
+	msg, err := trans.T(key, params...)
+	if err != nil {
+		if msg, err = uni.GetFallback().T(key, params...); err != nil {
+			return key
+		}
+	}
+	return msg
+}
+
+func toStrings(params []interface{}) []string {
+	s := make([]string, len(params))
+	for i, p := range params {
+		s[i] = fmt.Sprint(p)
+	}
+	return s
+}
+
+// TypeName names the json type of v in messages
+func TypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
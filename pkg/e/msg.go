@@ -0,0 +1,322 @@
+package e
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/zh"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// 错误码对应的默认消息
+var codeKeys = map[int]string{
+	SUCCESS:                    "ok",
+	ERROR:                      "error",
+	INVALID_PARAMS:             "invalid_params",
+	ERROR_QUERY_REQUIRED:       "query_required",
+	ERROR_NOT_EXIST_GROUP:      "not_exist_group",
+	ERROR_INVALID_VERSION:      "invalid_version",
+	ERROR_NOT_EXIST_FIELD:      "not_exist_field",
+	ERROR_FIELD_NOT_QUERYABLE:  "field_not_queryable",
+	ERROR_FIELD_NOT_ORDERABLE:  "field_not_orderable",
+	ERROR_INVALID_ORDER:        "invalid_order",
+	ERROR_INVALID_VALUE:        "invalid_value",
+	ERROR_INVALID_TIMEZONE:     "invalid_timezone",
+	ERROR_RULE:                 "rule",
+	ERROR_SEARCH_NOT_SUPPORTED: "search_not_supported",
+	ERROR_FILTER_REQUIRED:      "filter_required",
+	ERROR_FIELD_READ_ONLY:      "field_read_only",
+	ERROR_NOT_EXIST_API_KEY:    "not_exist_api_key",
+	ERROR_BATCH_DEPENDENCY:     "batch_dependency",
+	ERROR_GROUP_READ_ONLY:      "group_read_only",
+	ERROR_WRITE_LIMIT:          "write_limit",
+	ERROR_DATABASE:             "database",
+	ERROR_AUTH:                 "auth",
+	ERROR_AUTH_TOKEN:           "auth_token",
+	ERROR_AUTH_TOKEN_EXPIRED:   "auth_token_expired",
+	ERROR_AUTH_LOCKED:          "auth_locked",
+	ERROR_FORBIDDEN_FIELD:      "forbidden_field",
+	ERROR_FORBIDDEN_ROW:        "forbidden_row",
+	ERROR_TENANT_REQUIRED:      "tenant_required",
+	ERROR_INVALID_TENANT:       "invalid_tenant",
+	ERROR_FIELD_MASKED:         "field_masked",
+	ERROR_AUTH_API_KEY:         "auth_api_key",
+	ERROR_FORBIDDEN_API_KEY:    "forbidden_api_key",
+	ERROR_RATE_LIMITED:         "rate_limited",
+	ERROR_ROW_QUOTA:            "row_quota",
+}
+
+// 占位符必须按{0}, {1}的顺序出现, 否则翻译时会panic
+var zhMessages = map[string]string{
+	"ok":                   "ok",
+	"error":                "服务器内部错误",
+	"invalid_params":       "请求参数错误: {0}",
+	"query_required":       "缺少query参数",
+	"not_exist_group":      "实体{0}不存在",
+	"invalid_version":      "版本{0}不存在",
+	"not_exist_field":      "字段{0}不存在",
+	"field_not_queryable":  "字段{0}不能作为查询条件",
+	"field_not_orderable":  "不能使用字段{0}进行排序",
+	"invalid_order":        "排序参数不正确, 格式为[[字段, asc|desc [nulls first|last]]]",
+	"invalid_value":        "值不合法: {0}",
+	"invalid_timezone":     "时区{0}不存在",
+	"rule":                 "参数组合不正确",
+	"search_not_supported": "实体{0}不支持全文搜索",
+	"filter_required":      "缺少必需的查询条件",
+	"field_read_only":      "字段{0}为只读字段",
+	"not_exist_api_key":    "API key {0}不存在或已吊销",
+	"batch_dependency":     "引用的查询{0}失败",
+	"group_read_only":      "实体{0}不支持写入",
+	"write_limit":          "条件匹配了{0}行, 一次最多更新或删除{1}行",
+	"database":             "数据库错误",
+	"auth":                 "用户名或密码错误",
+	"auth_token":           "token无效",
+	"auth_token_expired":   "token已过期",
+	"auth_locked":          "登录失败次数过多, 请{0}分钟后重试",
+	"forbidden_field":      "没有使用字段{0}的权限",
+	"forbidden_row":        "{0}超出了可以访问的数据范围",
+	"tenant_required":      "没有所属的租户, 不能访问{0}",
+	"invalid_tenant":       "租户{0}不合法",
+	"field_masked":         "字段{0}已脱敏, 只能按完整的值查询, 不能排序或搜索",
+	"auth_api_key":         "API key无效, 已过期或已吊销",
+	"forbidden_api_key":    "API key不能访问{0}",
+	"rate_limited":         "请求过于频繁, 请{0}秒后重试",
+	"row_quota":            "返回的数据量超出限制, 请{0}秒后重试",
+	"field":                "字段{0}: {1}",
+
+	"order.relevance": "按{0}排序时必须提供_search",
+
+	"query.body": "请求体必须为JSON格式的查询",
+
+	"row_quota.page": "返回的数据超过{0}行的限制, 请分页查询",
+
+	"batch.duplicate": "查询名{0}重复",
+	"batch.ref":       "{0}只能引用之前的查询",
+	"batch.ref_path":  "引用的值{0}不存在",
+
+	"policy.required":  "查询必须包含以下条件之一: {0}",
+	"policy.exact":     "{0}必须为等值或in条件",
+	"policy.unbounded": "{0}必须同时提供开始和结束时间",
+	"policy.range":     "{0}的范围不能超过{1}",
+
+	"scope.required": "写入时必须提供{0}",
+
+	"write.values": "没有要写入的字段",
+	"write.filter": "更新及删除必须提供查询条件",
+	"write.delete": "没有删除{0}的权限",
+
+	"api_key.operation": "API key不能对{0}执行{1}",
+	"api_key.user_only": "API key不能用于修改密码及管理API key",
+
+	"rule.after":         "{0}必须晚于{1}",
+	"rule.required_with": "{0}在提供{1}时为必填字段",
+	"rule.at_most_one":   "{0}最多只能提供一个",
+
+	"validate.required": "{0}为必填字段",
+	"validate.len":      "{0}的长度必须为{1}",
+	"validate.min":      "{0}不能小于{1}",
+	"validate.max":      "{0}不能大于{1}",
+
+	"value.operator":         "不支持的操作符{0}",
+	"value.empty_condition":  "条件不能为空",
+	"value.range":            "范围必须包含开始和结束两个值",
+	"value.multiple":         "不支持多个值",
+	"value.single":           "必须为单个值",
+	"value.empty_list":       "列表不能为空",
+	"value.list":             "必须为列表",
+	"value.object":           "必须为对象, 实际为{0}",
+	"value.duplicate":        "条件重复",
+	"value.like":             "模糊查询的值必须为字符串",
+	"value.integer":          "必须为整数, 实际为{0}",
+	"value.unsigned":         "必须为非负整数, 实际为{0}",
+	"value.number":           "必须为数字, 实际为{0}",
+	"value.string":           "必须为字符串, 实际为{0}",
+	"value.string_or_number": "必须为字符串或数字, 实际为{0}",
+	"value.boolean":          "必须为布尔值, 实际为{0}",
+	"value.time":             "必须为时间字符串, 实际为{0}",
+	"value.not_integer":      "{0}不是整数",
+	"value.not_number":       "{0}不是合法的数字",
+	"value.negative":         "{0}不能为负数",
+	"value.overflow":         "超出{0}的取值范围",
+	"value.time_format":      "{0}不是合法的时间, 格式为{1}",
+	"value.time_range":       "{0}不是合法的时间范围, 格式为开始..结束",
+	"value.min":              "不能小于{0}",
+	"value.max":              "不能大于{0}",
+	"value.min_length":       "长度不能小于{0}",
+	"value.max_length":       "长度不能大于{0}",
+	"value.min_items":        "元素个数不能小于{0}",
+	"value.max_items":        "元素个数不能大于{0}",
+	"value.before":           "不能早于{0}",
+	"value.after":            "不能晚于{0}",
+	"value.pattern":          "格式不正确",
+	"value.enum":             "必须为{0}之一",
+	"value.id":               "id必须为正整数",
+	"value.template":         "不支持该形式的值",
+}
+
+var enMessages = map[string]string{
+	"ok":                   "ok",
+	"error":                "internal server error",
+	"invalid_params":       "invalid parameters: {0}",
+	"query_required":       "query parameter is required",
+	"not_exist_group":      "entity {0} does not exist",
+	"invalid_version":      "version {0} does not exist",
+	"not_exist_field":      "field {0} does not exist",
+	"field_not_queryable":  "field {0} can not be used as a filter",
+	"field_not_orderable":  "field {0} can not be used for ordering",
+	"invalid_order":        "invalid order by, expected [[field, asc|desc [nulls first|last]]]",
+	"invalid_value":        "invalid value: {0}",
+	"invalid_timezone":     "unknown timezone {0}",
+	"rule":                 "invalid combination of parameters",
+	"search_not_supported": "entity {0} does not support _search",
+	"filter_required":      "a required filter is missing",
+	"field_read_only":      "field {0} is read only",
+	"not_exist_api_key":    "API key {0} does not exist or is revoked",
+	"batch_dependency":     "the referenced query {0} failed",
+	"group_read_only":      "entity {0} does not support writes",
+	"write_limit":          "the conditions match {0} rows, at most {1} rows can be updated or deleted at once",
+	"database":             "database error",
+	"auth":                 "invalid username or password",
+	"auth_token":           "invalid token",
+	"auth_token_expired":   "token has expired",
+	"auth_locked":          "too many failed logins, try again in {0} minutes",
+	"forbidden_field":      "no permission to use field {0}",
+	"forbidden_row":        "{0} is out of the data you may access",
+	"tenant_required":      "{0} can only be accessed by the users of a tenant",
+	"invalid_tenant":       "invalid tenant {0}",
+	"field_masked":         "field {0} is masked, it can only be filtered by exact values and not be ordered or searched",
+	"auth_api_key":         "invalid, expired or revoked API key",
+	"forbidden_api_key":    "the API key is not allowed to access {0}",
+	"rate_limited":         "too many requests, try again in {0} seconds",
+	"row_quota":            "too many rows returned, try again in {0} seconds",
+	"field":                "field {0}: {1}",
+
+	"order.relevance": "ordering by {0} requires _search",
+
+	"query.body": "the body must be a JSON query document",
+
+	"row_quota.page": "the query returns more than the limit of {0} rows, query it by pages",
+
+	"batch.duplicate": "query name {0} is used more than once",
+	"batch.ref":       "{0} can only reference an earlier query",
+	"batch.ref_path":  "the referenced value {0} does not exist",
+
+	"policy.required":  "the query must filter by one of: {0}",
+	"policy.exact":     "{0} must be an equality or in condition",
+	"policy.unbounded": "{0} must be a range with both a start and an end",
+	"policy.range":     "the range of {0} must not be wider than {1}",
+
+	"scope.required": "{0} is required to check the data you may access",
+
+	"write.values": "there are no fields to write",
+	"write.filter": "updates and deletes require a filter",
+	"write.delete": "no permission to delete {0}",
+
+	"api_key.operation": "the API key is not allowed to access {0} by {1}",
+	"api_key.user_only": "API keys can not change passwords or manage API keys",
+
+	"rule.after":         "{0} must be after {1}",
+	"rule.required_with": "{0} is required when {1} is given",
+	"rule.at_most_one":   "at most one of {0} may be given",
+
+	"validate.required": "{0} is required",
+	"validate.len":      "{0} must have a length of {1}",
+	"validate.min":      "{0} must be at least {1}",
+	"validate.max":      "{0} must be at most {1}",
+
+	"value.operator":         "operator {0} is not supported",
+	"value.empty_condition":  "condition must not be empty",
+	"value.range":            "range must contain a start and an end value",
+	"value.multiple":         "multiple values are not supported",
+	"value.single":           "must be a single value",
+	"value.empty_list":       "list must not be empty",
+	"value.list":             "must be a list",
+	"value.object":           "must be an object, got {0}",
+	"value.duplicate":        "is given more than once",
+	"value.like":             "value of like must be a string",
+	"value.integer":          "must be an integer, got {0}",
+	"value.unsigned":         "must be a non-negative integer, got {0}",
+	"value.number":           "must be a number, got {0}",
+	"value.string":           "must be a string, got {0}",
+	"value.string_or_number": "must be a string or a number, got {0}",
+	"value.boolean":          "must be a boolean, got {0}",
+	"value.time":             "must be a time string, got {0}",
+	"value.not_integer":      "{0} is not an integer",
+	"value.not_number":       "{0} is not a valid number",
+	"value.negative":         "{0} must not be negative",
+	"value.overflow":         "out of the range of {0}",
+	"value.time_format":      "{0} is not a valid time, expected format {1}",
+	"value.time_range":       "{0} is not a valid time range, expected start..end",
+	"value.min":              "must not be less than {0}",
+	"value.max":              "must not be greater than {0}",
+	"value.min_length":       "length must not be less than {0}",
+	"value.max_length":       "length must not be greater than {0}",
+	"value.min_items":        "must contain at least {0} elements",
+	"value.max_items":        "must contain at most {0} elements",
+	"value.before":           "must not be before {0}",
+	"value.after":            "must not be after {0}",
+	"value.pattern":          "has an invalid format",
+	"value.enum":             "must be one of {0}",
+	"value.id":               "id must be a positive integer",
+	"value.template":         "this form of value is not supported",
+}
+
+var uni *ut.UniversalTranslator
+
+// en只用于翻译消息, 复用zh的数字及日期格式
+type enLocale struct {
+	locales.Translator
+}
+
+func (enLocale) Locale() string {
+	return "en"
+}
+
+func init() {
+	zht := zh.New()
+	uni = ut.New(zht, zht, enLocale{zht})
+	for locale, messages := range map[string]map[string]string{"zh": zhMessages, "en": enMessages} {
+		trans, _ := uni.GetTranslator(locale)
+		for k, v := range messages {
+			if err := trans.Add(k, v, false); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// Translator chooses the translator by the Accept-Language header, zh by default
+func Translator(acceptLanguage string) ut.Translator {
+	type tag struct {
+		name string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		t := tag{name: strings.TrimSpace(fields[0]), q: 1}
+		if t.name == "" {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if f = strings.TrimSpace(f); strings.HasPrefix(f, "q=") {
+				if q, err := strconv.ParseFloat(f[2:], 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		tags = append(tags, t)
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	var names []string
+	for _, t := range tags {
+		// zh-CN, en_US 等使用语言部分
+		name := strings.Replace(t.name, "-", "_", -1)
+		names = append(names, name, strings.SplitN(name, "_", 2)[0])
+	}
+	trans, _ := uni.FindTranslator(names...)
+	return trans
+}
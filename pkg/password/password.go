@@ -0,0 +1,54 @@
+package password
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cost is the bcrypt cost of the new hashes, the hashes of a lower cost are rehashed on login
+const Cost = 12
+
+// MaxLength is the maximum length of a password in bytes, bcrypt ignores the bytes beyond
+const MaxLength = 72
+
+// ErrTooLong is returned by Hash for a password longer than MaxLength
+var ErrTooLong = errors.New("password: longer than 72 bytes")
+
+// Hash returns the bcrypt hash of password
+func Hash(password string) (string, error) {
+	if len(password) > MaxLength {
+		return "", ErrTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Check compares password with the stored value in constant time.
+// 不是哈希格式的值视为旧的明文密码, 校验通过时needsRehash为true; cost低于Cost的哈希同样需要重新哈希
+func Check(stored, password string) (ok, needsRehash bool, err error) {
+	if !IsHash(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, true, nil
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(stored), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("password: %v", err)
+	}
+	cost, err := bcrypt.Cost([]byte(stored))
+	return true, err == nil && cost < Cost, nil
+}
+
+// IsHash reports whether stored is a hash produced by Hash
+func IsHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
@@ -0,0 +1,61 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheck(t *testing.T) {
+	hash, err := Hash("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name        string
+		stored      string
+		password    string
+		ok          bool
+		needsRehash bool
+	}{
+		{"hash", hash, "secret", true, false},
+		{"wrong password", hash, "Secret", false, false},
+		{"plaintext", "secret", "secret", true, true},
+		{"wrong plaintext", "secret", "secre", false, true},
+		{"empty plaintext", "", "", true, true},
+		{"lower cost", string(weak), "secret", true, true},
+		{"wrong password of lower cost", string(weak), "other", false, false},
+	}
+	for _, c := range cases {
+		ok, needsRehash, err := Check(c.stored, c.password)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if ok != c.ok || needsRehash != c.needsRehash {
+			t.Errorf("%s: ok %v rehash %v, want %v %v", c.name, ok, needsRehash, c.ok, c.needsRehash)
+		}
+	}
+}
+
+func TestHash(t *testing.T) {
+	hash, err := Hash(strings.Repeat("a", MaxLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsHash(hash) || IsHash("secret") {
+		t.Error("IsHash does not tell hashes from plaintext")
+	}
+	if cost, _ := bcrypt.Cost([]byte(hash)); cost != Cost {
+		t.Errorf("cost %d, want %d", cost, Cost)
+	}
+	if _, err := Hash(strings.Repeat("a", MaxLength+1)); err != ErrTooLong {
+		t.Errorf("got %v, want ErrTooLong", err)
+	}
+}
@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 清理已回满的桶的间隔
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	limit  Limit
+}
+
+// refill returns the tokens of the bucket at now
+func (b *bucket) refill(now time.Time) float64 {
+	return math.Min(b.limit.Burst, b.tokens+now.Sub(b.at).Seconds()*b.limit.Rate)
+}
+
+// MemoryStore keeps the buckets in the memory of a single node
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(key string, l Limit, cost float64, force bool) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b := s.bucket(key, l, now)
+	tokens := b.refill(now)
+	if tokens < cost && !force {
+		return waitFor(cost-tokens, l), nil
+	}
+	b.tokens, b.at = math.Min(l.Burst, tokens-cost), now
+	return 0, nil
+}
+
+func (s *MemoryStore) Reserve(key string, l Limit, max float64) (float64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	b := s.bucket(key, l, now)
+	tokens := b.refill(now)
+	if max <= 0 || max > l.Burst {
+		max = l.Burst
+	}
+	var wait time.Duration
+	if tokens < max {
+		wait = waitFor(max-tokens, l)
+	}
+	n := math.Min(math.Floor(tokens), max)
+	if n < 1 {
+		return 0, wait, nil
+	}
+	b.tokens, b.at = tokens-n, now
+	return n, wait, nil
+}
+
+// bucket returns the bucket of key, a new bucket is full
+func (s *MemoryStore) bucket(key string, l Limit, now time.Time) *bucket {
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.Burst, at: now}
+		s.buckets[key] = b
+	}
+	b.limit = l
+	return b
+}
+
+// 回满的桶与新建的桶相同, 可以删除
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < sweepInterval {
+		return
+	}
+	s.swept = now
+	for k, b := range s.buckets {
+		if b.refill(now) >= b.limit.Burst {
+			delete(s.buckets, k)
+		}
+	}
+}
+
+func waitFor(tokens float64, l Limit) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.Rate * float64(time.Second)))
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// slow refills a token per hour, the tests run long before the next one
+var slow = Limit{Rate: 1.0 / 3600, Burst: 10}
+
+func TestMemoryReserve(t *testing.T) {
+	s := NewMemoryStore()
+	cases := []struct {
+		name string
+		max  float64
+		n    float64
+		wait bool
+	}{
+		{"page", 4, 4, false},
+		{"more than left", 8, 6, true},
+		{"empty", 1, 0, true},
+	}
+	for _, c := range cases {
+		n, wait, err := s.Reserve("k", slow, c.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != c.n || (wait > 0) != c.wait {
+			t.Errorf("%s: %v rows wait %v, want %v rows wait %v", c.name, n, wait, c.n, c.wait)
+		}
+	}
+
+	// 归还的行可以再次预留, 最多回到Burst
+	if _, err := s.Take("k", slow, -3, true); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, _ := s.Reserve("k", slow, 0); n != 3 {
+		t.Errorf("%v rows after releasing 3", n)
+	}
+	if _, err := s.Take("k", slow, -100, true); err != nil {
+		t.Fatal(err)
+	}
+	if n, wait, _ := s.Reserve("k", slow, 0); n != slow.Burst || wait != 0 {
+		t.Errorf("%v rows wait %v after releasing more than the burst", n, wait)
+	}
+	if n, _, _ := s.Reserve("other", slow, 20); n != slow.Burst {
+		t.Errorf("a new bucket reserves %v rows", n)
+	}
+}
+
+func TestMemoryTake(t *testing.T) {
+	s := NewMemoryStore()
+	if wait, _ := s.Take("k", slow, 10, false); wait != 0 {
+		t.Errorf("the full bucket waits %v", wait)
+	}
+	wait, _ := s.Take("k", slow, 1, false)
+	if wait < 59*time.Minute || wait > time.Hour {
+		t.Errorf("waits %v for a token refilled each hour", wait)
+	}
+	// force扣除后令牌为负, 需要等待更久
+	if wait, _ := s.Take("k", slow, 1, true); wait != 0 {
+		t.Errorf("forced take waits %v", wait)
+	}
+	if wait, _ := s.Take("k", slow, 1, false); wait < 119*time.Minute {
+		t.Errorf("waits %v after a forced take", wait)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := NewBudget(NewMemoryStore(), "rows", slow)
+	n, _, err := b.Reserve(6)
+	if err != nil || n != 6 {
+		t.Fatalf("reserved %d, %v", n, err)
+	}
+	if err := b.Release(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Release(-5); err != nil {
+		t.Fatal(err)
+	}
+	if n, _, _ := b.Reserve(0); n != 6 {
+		t.Errorf("%d rows left, want 6", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := map[time.Duration]int{0: 0, time.Millisecond: 1, time.Second: 1, 1500 * time.Millisecond: 2}
+	for wait, want := range cases {
+		if got := RetryAfter(wait); got != want {
+			t.Errorf("%v: %d, want %d", wait, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/go-bread/pkg/setting"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second up to Burst tokens
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// PerMinute returns the limit of n per minute allowing burst at once
+func PerMinute(n, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: float64(burst)}
+}
+
+// Enabled reports whether the limit limits anything
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Limits are the limits of a user on an entity group
+type Limits struct {
+	Requests Limit // 请求数
+	Rows     Limit // 返回的行数, 查询前预留, 未返回的行归还
+}
+
+// Store keeps the token buckets
+type Store interface {
+	// Take removes cost tokens from the bucket of key and returns 0.
+	// 不足cost时不扣除, 返回可用前需要等待的时间; force为true时总是扣除, 桶中的令牌可以为负.
+	// cost为负时归还令牌, 最多回到Burst
+	Take(key string, l Limit, cost float64, force bool) (time.Duration, error)
+	// Reserve removes up to max whole tokens from the bucket of key, max is at most Burst, and returns the number removed.
+	// 不足max时返回max可用前需要等待的时间, 不足一个令牌时不扣除
+	Reserve(key string, l Limit, max float64) (float64, time.Duration, error)
+}
+
+const budgetContextKey = "bread.ratelimit.budget"
+
+var (
+	// Default is used for the entity groups without their own limits, read from [ratelimit]
+	Default Limits
+	// Login limits the login attempts of each client IP
+	Login Limit
+	store   Store = NewMemoryStore()
+)
+
+// Setup loads the default limits and the store from [ratelimit]
+func Setup() {
+	s := setting.RateLimitSetting
+	burst := s.RequestBurst
+	if burst <= 0 {
+		burst = s.Requests
+	}
+	Default = Limits{
+		Requests: PerMinute(s.Requests, burst),
+		Rows:     PerMinute(s.Rows, s.Rows),
+	}
+	Login = PerMinute(s.Logins, s.Logins)
+
+	switch s.Store {
+	case "", "memory":
+		SetStore(NewMemoryStore())
+	case "redis":
+		SetStore(NewRedisStore(newPool(s.RedisHost, s.RedisPassword)))
+	default:
+		log.Fatalf("ratelimit.Setup err: unknown store %s", s.Store)
+	}
+}
+
+// SetStore replaces the store of the buckets
+func SetStore(s Store) {
+	store = s
+}
+
+// GetStore returns the store of the buckets
+func GetStore() Store {
+	return store
+}
+
+// Budget is the row limit of a user on an entity group, the rows of a query are reserved before it runs
+type Budget struct {
+	store Store
+	key   string
+	limit Limit
+}
+
+// NewBudget returns the budget of the bucket key in store
+func NewBudget(store Store, key string, l Limit) *Budget {
+	return &Budget{store: store, key: key, limit: l}
+}
+
+// Reserve takes up to max rows from the budget, the rows left up to Burst when max is 0.
+// n为0表示额度已用完, wait为max行可用前需要等待的时间
+func (b *Budget) Reserve(max int) (n int, wait time.Duration, err error) {
+	tokens, wait, err := b.store.Reserve(b.key, b.limit, float64(max))
+	return int(tokens), wait, err
+}
+
+// Release returns n reserved rows which were not returned
+func (b *Budget) Release(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	_, err := b.store.Take(b.key, b.limit, -float64(n), true)
+	return err
+}
+
+// SetBudget sets the row budget of the request
+func SetBudget(ctx *gin.Context, b *Budget) {
+	ctx.Set(budgetContextKey, b)
+}
+
+// GetBudget returns the row budget of the request, nil when the rows are not limited
+func GetBudget(ctx *gin.Context) *Budget {
+	b, _ := ctx.Value(budgetContextKey).(*Budget)
+	return b
+}
+
+// RetryAfter is the value of the Retry-After header of wait, in whole seconds
+func RetryAfter(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
+
+func newPool(host, password string) *redis.Pool {
+	return &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			c, err := redis.Dial("tcp", host)
+			if err != nil {
+				return nil, err
+			}
+			if password != "" {
+				if _, err := c.Do("AUTH", password); err != nil {
+					c.Close()
+					return nil, err
+				}
+			}
+			return c, nil
+		},
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			if time.Since(t) < time.Minute {
+				return nil
+			}
+			_, err := c.Do("PING")
+			return err
+		},
+	}
+}
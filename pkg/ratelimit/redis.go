@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// KeyPrefix prefixes the keys of the buckets in redis
+const KeyPrefix = "bread:ratelimit:"
+
+// 与MemoryStore.Take相同, 在redis中原子执行; 时间由调用方传入, 各节点的时钟需要同步
+var takeScript = redis.NewScript(1, `
+local rate, burst, cost, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens, at = tonumber(state[1]), tonumber(state[2])
+if tokens == nil then
+	tokens, at = burst, now
+end
+tokens = math.min(burst, tokens + math.max(0, now - at) / 1000 * rate)
+if tokens < cost and ARGV[5] ~= "1" then
+	return tostring(math.ceil((cost - tokens) / rate * 1000))
+end
+tokens = math.min(burst, tokens - cost)
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "at", ARGV[4])
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return "0"
+`)
+
+// 与MemoryStore.Reserve相同, 返回扣除的令牌数及等待的毫秒数
+var reserveScript = redis.NewScript(1, `
+local rate, burst, max, now = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local state = redis.call("HMGET", KEYS[1], "tokens", "at")
+local tokens, at = tonumber(state[1]), tonumber(state[2])
+if tokens == nil then
+	tokens, at = burst, now
+end
+tokens = math.min(burst, tokens + math.max(0, now - at) / 1000 * rate)
+if max <= 0 or max > burst then
+	max = burst
+end
+local wait = 0
+if tokens < max then
+	wait = math.ceil((max - tokens) / rate * 1000)
+end
+local n = math.min(math.floor(tokens), max)
+if n < 1 then
+	return {0, wait}
+end
+tokens = tokens - n
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "at", ARGV[4])
+redis.call("PEXPIRE", KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {n, wait}
+`)
+
+// RedisStore shares the buckets between the nodes of a cluster
+type RedisStore struct {
+	pool *redis.Pool
+}
+
+func NewRedisStore(pool *redis.Pool) *RedisStore {
+	return &RedisStore{pool: pool}
+}
+
+func (s *RedisStore) Take(key string, l Limit, cost float64, force bool) (time.Duration, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	f := "0"
+	if force {
+		f = "1"
+	}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	wait, err := redis.Int64(takeScript.Do(conn, KeyPrefix+key,
+		formatFloat(l.Rate), formatFloat(l.Burst), formatFloat(cost), now, f))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+func (s *RedisStore) Reserve(key string, l Limit, max float64) (float64, time.Duration, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	r, err := redis.Int64s(reserveScript.Do(conn, KeyPrefix+key,
+		formatFloat(l.Rate), formatFloat(l.Burst), formatFloat(max), now))
+	if err != nil {
+		return 0, 0, err
+	}
+	return float64(r[0]), time.Duration(r[1]) * time.Millisecond, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -10,8 +10,10 @@ import (
 type Server struct {
 	RunMode      string
 	HttpPort     int
+	GrpcPort     int // gRPC接口的端口, 为0时不启动
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	Timezone     string // 请求未指定时区时使用的时区
 }
 
 var ServerSetting = &Server{}
@@ -23,10 +25,50 @@ type Database struct {
 	Host        string
 	Name        string
 	TablePrefix string
+	Timezone    string // 数据库中时间的时区, 即DSN的loc
 }
 
 var DatabaseSetting = &Database{}
 
+type Entity struct {
+	Check   bool
+	Strict  bool
+	MaskKey string
+}
+
+var EntitySetting = &Entity{}
+
+type Auth struct {
+	JwtSecret       string
+	JwtSecretFile   string // 保存密钥的文件, 优先于JwtSecret
+	Issuer          string
+	AccessTokenTTL  time.Duration // 分钟
+	RefreshTokenTTL time.Duration // 分钟
+	// 连续登录失败MaxLoginFailures次后锁定LockDuration分钟, 为0时不锁定
+	MaxLoginFailures int
+	LockDuration     time.Duration
+}
+
+var AuthSetting = &Auth{}
+
+type Tenant struct {
+	TrustHeader bool // 是否信任网关设置的租户请求头
+}
+
+var TenantSetting = &Tenant{}
+
+type RateLimit struct {
+	Requests      int // 每分钟请求数, 为0时不限制
+	RequestBurst  int // 允许的突发请求数
+	Rows          int // 每分钟返回的行数, 为0时不限制
+	Logins        int // 每个IP每分钟的登录次数, 为0时不限制
+	Store         string
+	RedisHost     string
+	RedisPassword string
+}
+
+var RateLimitSetting = &RateLimit{}
+
 var cfg *ini.File
 
 func Setup() {
@@ -38,9 +80,16 @@ func Setup() {
 
 	mapTo("server", ServerSetting)
 	mapTo("database", DatabaseSetting)
+	mapTo("entity", EntitySetting)
+	mapTo("auth", AuthSetting)
+	mapTo("tenant", TenantSetting)
+	mapTo("ratelimit", RateLimitSetting)
 
 	ServerSetting.ReadTimeout = ServerSetting.ReadTimeout * time.Second
 	ServerSetting.WriteTimeout = ServerSetting.WriteTimeout * time.Second
+	AuthSetting.AccessTokenTTL = AuthSetting.AccessTokenTTL * time.Minute
+	AuthSetting.RefreshTokenTTL = AuthSetting.RefreshTokenTTL * time.Minute
+	AuthSetting.LockDuration = AuthSetting.LockDuration * time.Minute
 }
 
 // mapTo map section
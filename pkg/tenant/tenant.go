@@ -0,0 +1,64 @@
+package tenant
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/setting"
+)
+
+// Header carries the tenant id of requests forwarded by a trusted gateway, see [tenant] TrustHeader
+const Header = "X-Tenant-Id"
+
+const (
+	contextKey     = "bread.tenant"
+	userContextKey = "bread.user_tenant"
+)
+
+// SetUser sets the tenant of the authenticated user, it takes precedence over the header
+func SetUser(ctx *gin.Context, id int64) {
+	ctx.Set(userContextKey, id)
+}
+
+// Resolve returns the tenant id of the request, 0 when the request belongs to no tenant.
+// 优先使用token中的租户, 仅在信任网关时读取请求头
+func Resolve(ctx *gin.Context) (int64, error) {
+	if v, ok := ctx.Get(contextKey); ok {
+		return v.(int64), nil
+	}
+
+	var user int64
+	if v, ok := ctx.Get(userContextKey); ok {
+		user = v.(int64)
+	}
+	id, err := Choose(user, ctx.GetHeader(Header))
+	if err != nil {
+		return 0, err
+	}
+	ctx.Set(contextKey, id)
+	return id, nil
+}
+
+// Choose returns the tenant of the user, or the tenant of the header value when the user belongs to no tenant
+func Choose(user int64, header string) (int64, error) {
+	if user != 0 || header == "" || !setting.TenantSetting.TrustHeader {
+		return user, nil
+	}
+	id, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, e.New(e.ERROR_INVALID_TENANT, header).WithField(Header)
+	}
+	return id, nil
+}
+
+// CacheKey prefixes key with the tenant id so that cached data and counters are never shared between tenants,
+// the keys of requests belonging to no tenant are unchanged
+func CacheKey(id int64, key string) string {
+	if id == 0 {
+		return key
+	}
+	return fmt.Sprintf("tenant:%d:%s", id, key)
+}
@@ -0,0 +1,93 @@
+package timezone
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/setting"
+)
+
+// Header selects the timezone of the request by IANA name, e.g. Asia/Shanghai
+const Header = "X-Timezone"
+
+const (
+	contextKey     = "bread.timezone"
+	userContextKey = "bread.user_timezone"
+)
+
+// Default is the timezone of requests without a timezone, read from [server] Timezone
+var Default = time.Local
+
+// Setup loads the default timezone
+func Setup() {
+	if setting.ServerSetting.Timezone == "" {
+		return
+	}
+	loc, err := time.LoadLocation(setting.ServerSetting.Timezone)
+	if err != nil {
+		log.Fatalf("timezone.Setup err: %v", err)
+	}
+	Default = loc
+}
+
+// SetUser sets the timezone of the user profile, the header still takes precedence
+func SetUser(ctx *gin.Context, loc *time.Location) {
+	ctx.Set(userContextKey, loc)
+}
+
+// Resolve chooses the timezone of the request: the header, then the user profile, then Default
+func Resolve(ctx *gin.Context) (*time.Location, error) {
+	if v, ok := ctx.Get(contextKey); ok {
+		return v.(*time.Location), nil
+	}
+
+	var user *time.Location
+	if v, ok := ctx.Get(userContextKey); ok {
+		user = v.(*time.Location)
+	}
+	loc, err := Choose(ctx.GetHeader(Header), user)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Set(contextKey, loc)
+	return loc, nil
+}
+
+// Choose returns the timezone of the header value, then user, then Default
+func Choose(header string, user *time.Location) (*time.Location, error) {
+	if header != "" {
+		loc, err := time.LoadLocation(header)
+		if err != nil {
+			return nil, e.New(e.ERROR_INVALID_TIMEZONE, header).WithField(Header)
+		}
+		return loc, nil
+	}
+	if user != nil {
+		return user, nil
+	}
+	return Default, nil
+}
+
+// User returns the timezone of the user profile, nil when it is empty or invalid
+func User(name string) *time.Location {
+	if name == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// Get returns the resolved timezone, Default when it is invalid
+func Get(ctx *gin.Context) *time.Location {
+	loc, err := Resolve(ctx)
+	if err != nil {
+		return Default
+	}
+	return loc
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/e"
+)
+
+type apiKeyForm struct {
+	Name       string   `json:"name" validate:"required,max=100"`
+	Groups     []string `json:"groups" validate:"required,min=1,dive,required"`
+	Operations []string `json:"operations" validate:"required,min=1,dive,oneof=list create update delete export"`
+	ExpiresIn  int64    `json:"expires_in" validate:"min=0"` // 有效秒数, 0表示不过期
+}
+
+// apiKeyView is a stored key, the key itself is only returned once by CreateAPIKey
+type apiKeyView struct {
+	ID         int      `json:"id"`
+	Key        string   `json:"key,omitempty"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Groups     []string `json:"groups"`
+	Operations []string `json:"operations"`
+	CreatedAt  int64    `json:"created_at"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"`
+	LastUsedAt int64    `json:"last_used_at,omitempty"`
+	RevokedAt  int64    `json:"revoked_at,omitempty"`
+}
+
+// CreateAPIKey creates a key of the logged in user scoped to entity groups and operations
+func CreateAPIKey(c *gin.Context) {
+	var form apiKeyForm
+	if err := bindForm(c, &form); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	identity, ok := auth.GetIdentity(c)
+	if !ok {
+		_ = c.Error(e.New(e.ERROR_AUTH_TOKEN))
+		return
+	}
+	groups := unique(form.Groups)
+	for _, g := range groups {
+		if _, ok := entity.FieldsMap[consts.EntityGroupName(g)]; !ok {
+			_ = c.Error(e.New(e.ERROR_NOT_EXIST_GROUP, g).WithField("groups"))
+			return
+		}
+	}
+
+	key, hash, err := apikey.Generate()
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	now := time.Now()
+	k := &models.APIKey{
+		AuthID:     identity.ID,
+		Name:       form.Name,
+		Prefix:     key[:apikey.PrefixLength],
+		KeyHash:    hash,
+		Groups:     strings.Join(groups, ","),
+		Operations: strings.Join(unique(form.Operations), ","),
+		CreatedAt:  now.Unix(),
+	}
+	if form.ExpiresIn > 0 {
+		k.ExpiresAt = now.Add(time.Duration(form.ExpiresIn) * time.Second).Unix()
+	}
+	if err := models.CreateAPIKey(k); err != nil {
+		_ = c.Error(e.Wrap(e.ERROR_DATABASE, err))
+		return
+	}
+
+	view := newAPIKeyView(k)
+	view.Key = key
+	c.JSON(http.StatusCreated, view)
+}
+
+// ListAPIKeys lists the keys of the logged in user
+func ListAPIKeys(c *gin.Context) {
+	identity, ok := auth.GetIdentity(c)
+	if !ok {
+		_ = c.Error(e.New(e.ERROR_AUTH_TOKEN))
+		return
+	}
+	keys, err := models.GetAPIKeys(identity.ID)
+	if err != nil {
+		_ = c.Error(e.Wrap(e.ERROR_DATABASE, err))
+		return
+	}
+
+	views := make([]apiKeyView, 0, len(keys))
+	for i := range keys {
+		views = append(views, newAPIKeyView(&keys[i]))
+	}
+	c.JSON(http.StatusOK, gin.H{"list": views})
+}
+
+// RevokeAPIKey revokes a key of the logged in user, the key is rejected from the next request on
+func RevokeAPIKey(c *gin.Context) {
+	identity, ok := auth.GetIdentity(c)
+	if !ok {
+		_ = c.Error(e.New(e.ERROR_AUTH_TOKEN))
+		return
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		_ = c.Error(e.New(e.ERROR_NOT_EXIST_API_KEY, c.Param("id")).WithField("id"))
+		return
+	}
+	ok, err = models.RevokeAPIKey(id, identity.ID, time.Now())
+	if err != nil {
+		_ = c.Error(e.Wrap(e.ERROR_DATABASE, err))
+		return
+	}
+	if !ok {
+		_ = c.Error(e.New(e.ERROR_NOT_EXIST_API_KEY, id).WithField("id"))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func newAPIKeyView(k *models.APIKey) apiKeyView {
+	s := apikey.NewScope(k.ID, k.Groups, k.Operations)
+	return apiKeyView{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Groups:     s.Groups,
+		Operations: s.Operations,
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func unique(list []string) []string {
+	seen := make(map[string]struct{}, len(list))
+	var out []string
+	for _, v := range list {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/models"
+	"github.com/go-bread/pkg/auth"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/pkg/password"
+	"github.com/go-bread/utils/validate"
+)
+
+type loginForm struct {
+	Username string `json:"username" validate:"required,max=50"`
+	Password string `json:"password" validate:"required,max=128"`
+}
+
+type passwordForm struct {
+	OldPassword string `json:"old_password" validate:"required,max=128"`
+	NewPassword string `json:"new_password" validate:"required,min=8,max=72,nefield=OldPassword"`
+}
+
+type refreshForm struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// Login issues the access and refresh tokens of a user
+func Login(c *gin.Context) {
+	var form loginForm
+	if err := bindForm(c, &form); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	a, err := authenticate(c, form.Username, form.Password)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	issueTokens(c, a)
+}
+
+// ChangePassword changes the password of the logged in user, the old password is checked the same way as login.
+// 之前签发的token及用户的API key失效, 响应中返回新的token
+func ChangePassword(c *gin.Context) {
+	var form passwordForm
+	if err := bindForm(c, &form); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	identity, ok := auth.GetIdentity(c)
+	if !ok {
+		_ = c.Error(e.New(e.ERROR_AUTH_TOKEN))
+		return
+	}
+	a, err := models.GetAuthByID(identity.ID)
+	if err != nil {
+		_ = c.Error(e.Wrap(e.ERROR_DATABASE, err))
+		return
+	}
+	if a == nil {
+		_ = c.Error(e.New(e.ERROR_AUTH_TOKEN))
+		return
+	}
+
+	if _, err := authenticate(c, a.Username, form.OldPassword); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	// bcrypt只使用前72个字节, 多字节字符的密码按字节数检查
+	if len(form.NewPassword) > password.MaxLength {
+		_ = c.Error(e.Invalid("value.max_length", password.MaxLength).WithField("new_password"))
+		return
+	}
+	version, err := models.UpdateAuthPassword(a.ID, form.NewPassword, time.Now())
+	if err != nil {
+		_ = c.Error(e.Wrap(e.ERROR_DATABASE, err))
+		return
+	}
+	a.TokenVersion = version
+
+	issueTokens(c, a)
+}
+
+func authenticate(c *gin.Context, username, password string) (*models.Auth, error) {
+	a, err := models.Authenticate(username, password)
+	if err == models.ErrAccountLocked {
+		retry := time.Until(time.Unix(a.LockedUntil, 0))
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		return nil, e.New(e.ERROR_AUTH_LOCKED, int(math.Ceil(retry.Minutes())))
+	}
+	if err != nil {
+		return nil, e.Wrap(e.ERROR_DATABASE, err)
+	}
+	if a == nil {
+		return nil, e.New(e.ERROR_AUTH)
+	}
+	return a, nil
+}
+
+// Refresh exchanges a refresh token for new tokens, the user is reloaded so that role changes take effect.
+// 修改密码前签发的token已由ParseToken拒绝, 这里再按重新加载的用户检查一次版本
+func Refresh(c *gin.Context) {
+	var form refreshForm
+	if err := bindForm(c, &form); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	claims, err := auth.ParseToken(form.RefreshToken, auth.TokenRefresh)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	a, err := models.GetAuthByID(claims.ID)
+	if err != nil {
+		_ = c.Error(e.Wrap(e.ERROR_DATABASE, err))
+		return
+	}
+	if a == nil || a.TokenVersion != claims.Version {
+		_ = c.Error(e.New(e.ERROR_AUTH_TOKEN))
+		return
+	}
+
+	issueTokens(c, a)
+}
+
+func issueTokens(c *gin.Context, a *models.Auth) {
+	identity := auth.NewIdentity(a.ID, a.Username, a.Roles, a.Permissions, a.Timezone)
+	identity.TenantID = a.TenantID
+	tokens, err := auth.GenerateTokens(identity, a.TokenVersion)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func bindForm(c *gin.Context, form interface{}) error {
+	if err := c.ShouldBindJSON(form); err != nil {
+		return e.New(e.INVALID_PARAMS, err.Error())
+	}
+	return validate.StructParam(form)
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/components/entity"
+	apikeyAuth "github.com/go-bread/middleware/apikey"
+	"github.com/go-bread/middleware/ratelimit"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+)
+
+type batchForm struct {
+	Queries []entity.BatchQuery `json:"queries" validate:"required,min=1,max=20,dive"`
+}
+
+// batchResult is the result of a query of the batch, either data or error is set
+type batchResult struct {
+	Status int         `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Error  *e.Response `json:"error,omitempty"`
+}
+
+// Batch runs several named queries in one request, each query has its own result or error keyed by its name.
+// 每个查询与 /list/:form 相同, 分别检查API key的范围及限流
+func Batch(c *gin.Context) {
+	var form batchForm
+	if err := bindForm(c, &form); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	names := make(map[string]struct{}, len(form.Queries))
+	for i, q := range form.Queries {
+		if _, ok := names[q.Name]; ok {
+			_ = c.Error(e.NewKey(e.INVALID_PARAMS, "batch.duplicate", q.Name).WithField(fmt.Sprintf("queries[%d].name", i)))
+			return
+		}
+		names[q.Name] = struct{}{}
+	}
+
+	results := entity.QueryBatch(caller.FromGin(c), entity.FieldsMap, form.Queries, batchGuard)
+
+	trans := e.Translator(c.GetHeader("Accept-Language"))
+	resp := make(map[string]batchResult, len(results))
+	for i, r := range results {
+		for _, k := range []string{"Deprecation", "Warning"} {
+			for _, v := range r.Header[k] {
+				c.Writer.Header().Add(k, v)
+			}
+		}
+		if r.Err == nil {
+			resp[form.Queries[i].Name] = batchResult{Status: http.StatusOK, Data: r.Data}
+			continue
+		}
+		err := e.As(r.Err)
+		if err.Status() >= 500 {
+			log.Printf("[ERROR] %s %s: query %s: %v", c.Request.Method, c.Request.URL.Path, form.Queries[i].Name, err)
+		}
+		rendered := err.Render(trans)
+		resp[form.Queries[i].Name] = batchResult{Status: err.Status(), Error: &rendered}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": resp})
+}
+
+func batchGuard(c *caller.Caller, q entity.BatchQuery) error {
+	if err := apikeyAuth.Check(c.Scope, q.Form, apikey.OpList); err != nil {
+		return err
+	}
+	return ratelimit.Acquire(c, entity.FieldsMap, q.Form)
+}
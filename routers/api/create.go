@@ -4,15 +4,24 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-bread/components/entity"
 	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/caller"
 	"github.com/go-bread/validators/query"
 	"net/http"
 )
 
 func Create(c *gin.Context) {
 
-	qp, _ := query.Parse(c)
+	qp, err := query.Parse(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 	form := c.Param("form")
-	respData, _ := entity.QueryAndFormatAll(c, entity.FieldsMap, consts.EntityGroupName(form), qp)
+	respData, err := entity.QueryAndFormatAll(caller.FromGin(c), entity.FieldsMap, consts.EntityGroupName(form), qp)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
 	c.JSON(http.StatusOK,respData)
 }
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/components/graphql"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/validators/query"
+)
+
+var (
+	graphQLOnce   sync.Once
+	graphQLSchema *graphql.Schema
+	graphQLErr    error
+)
+
+// GraphQL serves the queries of the schema generated from the entity groups.
+// 每个实体组的根字段与批量查询相同, 分别检查API key的范围及限流
+func GraphQL(c *gin.Context) {
+	graphQLOnce.Do(func() {
+		graphQLSchema, graphQLErr = graphql.Build(entity.FieldsMap)
+	})
+	if graphQLErr != nil {
+		_ = c.Error(e.Wrap(e.ERROR, graphQLErr))
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, query.MaxBodySize))
+	if err != nil {
+		_ = c.Error(e.New(e.INVALID_PARAMS, err.Error()))
+		return
+	}
+	// 变量中的数字保留为json.Number, 按字段类型转换时不丢失精度
+	var req graphql.Request
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&req); err != nil {
+		_ = c.Error(e.New(e.INVALID_PARAMS, err.Error()))
+		return
+	}
+
+	resp, ok := graphql.Execute(c, graphQLSchema, req, batchGuard)
+	if !ok {
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
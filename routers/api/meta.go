@@ -0,0 +1,23 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/validators/query"
+)
+
+// GetMeta describes the fields of an entity group the caller may use
+func GetMeta(c *gin.Context) {
+	form := c.Param("form")
+	meta, err := entity.QueryMeta(caller.FromGin(c), entity.FieldsMap, consts.EntityGroupName(form), query.RequestVersion(c))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, meta)
+}
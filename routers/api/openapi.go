@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/components/openapi"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  *openapi.Document
+)
+
+// OpenAPI serves the document generated from the registered entity groups
+func OpenAPI(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		openAPIDoc = openapi.Build(entity.FieldsMap)
+	})
+
+	c.JSON(http.StatusOK, openAPIDoc)
+}
@@ -2,14 +2,49 @@ package routers
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/go-bread/components/entity"
+	apikeyAuth "github.com/go-bread/middleware/apikey"
+	"github.com/go-bread/middleware/errorhandler"
+	"github.com/go-bread/middleware/jwt"
+	"github.com/go-bread/middleware/ratelimit"
+	"github.com/go-bread/pkg/apikey"
 	"github.com/go-bread/routers/api"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"github.com/swaggo/gin-swagger/swaggerFiles"
 )
 
 func InitRouter() *gin.Engine {
+	entity.InitGroups(entity.FieldsMap)
+
 	r := gin.New()
+	r.Use(errorhandler.ErrorHandler())
+
+	r.GET("openapi.json", api.OpenAPI)
+	r.GET("swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/openapi.json")))
+
+	r.POST("auth/login", ratelimit.Login(), api.Login)
+	r.POST("auth/refresh", api.Refresh)
+
+	// 实体接口及修改密码需要登录, 实体接口也可以使用API key
+	entities := r.Group("/")
+	entities.Use(apikeyAuth.APIKey(jwt.JWT()))
+	{
+		limit := ratelimit.RateLimit(entity.FieldsMap)
+		entities.GET("list/:form", apikeyAuth.Require(apikey.OpList), limit, api.GetList)
+		entities.POST("list/:form", apikeyAuth.Require(apikey.OpList), limit, api.PostList)
+		// create/:form 只查询数据, 与list相同的范围
+		entities.GET("create/:form", apikeyAuth.Require(apikey.OpList), limit, api.Create)
+		entities.GET("meta/:form", apikeyAuth.Require(), api.GetMeta)
+		// 批量查询及GraphQL的每个查询单独检查API key的范围及限流
+		entities.POST("batch", api.Batch)
+		entities.POST("graphql", api.GraphQL)
 
-	r.GET("list/:form",api.GetList)
-	r.GET("create/:form",api.Create)
+		users := entities.Group("/auth", apikeyAuth.UserOnly())
+		users.POST("password", api.ChangePassword)
+		users.POST("keys", api.CreateAPIKey)
+		users.GET("keys", api.ListAPIKeys)
+		users.DELETE("keys/:id", api.RevokeAPIKey)
+	}
 
 	return r
 }
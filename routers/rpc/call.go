@@ -0,0 +1,155 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	apikeyAuth "github.com/go-bread/middleware/apikey"
+	"github.com/go-bread/middleware/jwt"
+	"github.com/go-bread/middleware/ratelimit"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+)
+
+// responseHeaders are returned as the header metadata of a call, in lower case
+var responseHeaders = []string{"Deprecation", "Warning", "Retry-After"}
+
+// call runs run as the caller of the metadata of ctx, the same as the HTTP API:
+// the caller is authenticated, the API key must allow op on the group form and the rate limits of form apply
+func (s *entityServer) call(ctx context.Context, form, op string, run func(c *caller.Caller) error) error {
+	header := requestHeader(ctx)
+	c, err := authenticate(ctx, header)
+	if err == nil {
+		err = apikeyAuth.Check(c.Scope, form, op)
+	}
+	if err == nil {
+		err = ratelimit.Acquire(c, s.fieldsMap, form)
+	}
+	if err == nil {
+		err = safeRun(c, run)
+	}
+
+	if c != nil {
+		var pairs []string
+		for _, k := range responseHeaders {
+			for _, v := range c.Header[k] {
+				pairs = append(pairs, strings.ToLower(k), v)
+			}
+		}
+		if len(pairs) > 0 {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(pairs...))
+		}
+	}
+
+	if err != nil {
+		return toStatus(ctx, header.Get("Accept-Language"), err)
+	}
+	return nil
+}
+
+// requestHeader returns the incoming metadata of ctx as the headers of an HTTP request
+func requestHeader(ctx context.Context) http.Header {
+	header := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for k, vs := range md {
+		// 伪首部, gRPC的保留字段及二进制的值不作为请求头
+		if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || strings.HasSuffix(k, "-bin") {
+			continue
+		}
+		for _, v := range vs {
+			header.Add(k, v)
+		}
+	}
+	return header
+}
+
+// authenticate builds the caller of the API key or the bearer access token of header, the same as the middlewares of the HTTP API
+func authenticate(ctx context.Context, header http.Header) (*caller.Caller, error) {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+
+	if key := header.Get(apikey.Header); key != "" {
+		identity, scope, err := apikeyAuth.Identify(key)
+		if err != nil {
+			return nil, err
+		}
+		return caller.New(identity, scope, ip, header), nil
+	}
+	claims, err := jwt.Authenticate(header.Get("Authorization"))
+	if err != nil {
+		return nil, err
+	}
+	return caller.New(&claims.Identity, nil, ip, header), nil
+}
+
+// safeRun converts a panic of run to an error, the same as errorhandler.ErrorHandler
+func safeRun(c *caller.Caller, run func(c *caller.Caller) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = e.Wrap(e.ERROR, fmt.Errorf("panic: %v", r))
+		}
+	}()
+	return run(c)
+}
+
+// toStatus converts err to a status of the gRPC code of its HTTP status in the language lang,
+// the details carry the code, the message and the field of the error as a google.protobuf.Struct
+func toStatus(ctx context.Context, lang string, err error) error {
+	ee := e.As(err)
+	if ee.Status() >= 500 {
+		method, _ := grpc.Method(ctx)
+		log.Printf("[ERROR] grpc %s: %v", method, ee)
+	}
+	rendered := ee.Render(e.Translator(lang))
+
+	st := status.New(grpcCode(ee.Status()), rendered.Message)
+	detail := map[string]interface{}{"code": rendered.Code, "message": rendered.Message}
+	if rendered.Field != "" {
+		detail["field"] = rendered.Field
+	}
+	if d, err := structpb.NewStruct(detail); err == nil {
+		if withDetails, err := st.WithDetails(d); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusOK:
+		return codes.OK
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusFailedDependency:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/routers/rpc/pb"
+	"github.com/go-bread/validators/query"
+)
+
+// 除等于及in以外的操作符在查询文档中的对象形式, 如 {"gte": 1}
+var operatorKeys = map[pb.Operator]string{
+	pb.Operator_OPERATOR_NE:   "ne",
+	pb.Operator_OPERATOR_GT:   "gt",
+	pb.Operator_OPERATOR_GTE:  "gte",
+	pb.Operator_OPERATOR_LT:   "lt",
+	pb.Operator_OPERATOR_LTE:  "lte",
+	pb.Operator_OPERATOR_LIKE: "like",
+}
+
+// queryParams converts q to the params of the query document of the HTTP API
+func queryParams(c *caller.Caller, v string, q *pb.Query) (query.QParams, error) {
+	if q == nil || len(q.Fields) == 0 {
+		return query.QParams{}, e.New(e.INVALID_PARAMS, "fields is required").WithField("query.fields")
+	}
+	filters, err := conditions(q.Conditions)
+	if err != nil {
+		return query.QParams{}, err
+	}
+
+	var p query.Pagination
+	if q.Page != 0 {
+		p.Page = q.Page
+		p.PageSize = q.PageSize
+		p.Init()
+	}
+
+	var orders query.OrderBy
+	for i, o := range q.Orders {
+		if o.Field == "" {
+			return query.QParams{}, e.New(e.ERROR_INVALID_ORDER).WithField(fmt.Sprintf("query.orders[%d]", i))
+		}
+		d := query.Direction{Desc: o.Desc}
+		switch o.Nulls {
+		case pb.Nulls_NULLS_FIRST:
+			d.Nulls = query.NullsFirst
+		case pb.Nulls_NULLS_LAST:
+			d.Nulls = query.NullsLast
+		}
+		orders.Orders = append(orders.Orders, [2]string{o.Field, d.String()})
+	}
+
+	return query.QParams{
+		QFields:      filters,
+		ReturnFields: q.Fields,
+		Pagination:   p,
+		OrderBy:      orders,
+		Version:      version(c, v),
+		Search:       q.Search,
+	}, nil
+}
+
+// writeParams are the params of the rows to update or delete
+func writeParams(c *caller.Caller, v string, conds []*pb.Condition) (query.QParams, error) {
+	filters, err := conditions(conds)
+	if err != nil {
+		return query.QParams{}, err
+	}
+	return query.QParams{QFields: filters, Version: version(c, v)}, nil
+}
+
+// conditions builds the filters of the query document, e.g. {"class.name": "a", "create_time": {"gte": "2020-01-01"}}
+func conditions(conds []*pb.Condition) (map[string]interface{}, error) {
+	filters := make(map[string]interface{}, len(conds))
+	for i, cond := range conds {
+		if cond.Field == "" {
+			return nil, e.New(e.INVALID_PARAMS, "field is required").WithField(fmt.Sprintf("conditions[%d].field", i))
+		}
+		v := cond.Value.AsInterface()
+		_, isList := v.([]interface{})
+		prev, exists := filters[cond.Field]
+
+		switch cond.Operator {
+		case pb.Operator_OPERATOR_EQ, pb.Operator_OPERATOR_IN:
+			if cond.Operator == pb.Operator_OPERATOR_EQ && isList {
+				return nil, e.Invalid("value.multiple").WithField(cond.Field)
+			}
+			if cond.Operator == pb.Operator_OPERATOR_IN && !isList {
+				return nil, e.Invalid("value.list").WithField(cond.Field)
+			}
+			if exists {
+				return nil, e.Invalid("value.duplicate").WithField(cond.Field)
+			}
+			filters[cond.Field] = v
+		default:
+			key, ok := operatorKeys[cond.Operator]
+			if !ok {
+				return nil, e.Invalid("value.operator", cond.Operator).WithField(cond.Field)
+			}
+			// 同一字段的多个操作符合并为一个对象, 如范围
+			ops, ok := prev.(map[string]interface{})
+			if exists && !ok {
+				return nil, e.Invalid("value.duplicate").WithField(cond.Field)
+			}
+			if !exists {
+				ops = make(map[string]interface{})
+				filters[cond.Field] = ops
+			}
+			if _, dup := ops[key]; dup {
+				return nil, e.Invalid("value.duplicate").WithField(cond.Field)
+			}
+			ops[key] = v
+		}
+	}
+	return filters, nil
+}
+
+// version of the request, or the X-Api-Version metadata
+func version(c *caller.Caller, v string) string {
+	if v != "" {
+		return v
+	}
+	return c.Version
+}
+
+// toStruct converts a row as rendered in JSON by the HTTP API
+func toStruct(row map[string]interface{}) (*structpb.Struct, error) {
+	b, err := json.Marshal(row)
+	if err != nil {
+		return nil, e.Wrap(e.ERROR, err)
+	}
+	s := &structpb.Struct{}
+	if err := protojson.Unmarshal(b, s); err != nil {
+		return nil, e.Wrap(e.ERROR, err)
+	}
+	return s, nil
+}
+
+func toStructs(rows []map[string]interface{}) ([]*structpb.Struct, error) {
+	list := make([]*structpb.Struct, len(rows))
+	for i, row := range rows {
+		s, err := toStruct(row)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = s
+	}
+	return list, nil
+}
@@ -0,0 +1,167 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/pkg/e"
+	"github.com/go-bread/routers/rpc/pb"
+	"github.com/go-bread/validators/query"
+)
+
+func cond(field string, op pb.Operator, v interface{}) *pb.Condition {
+	value, err := structpb.NewValue(v)
+	if err != nil {
+		panic(err)
+	}
+	return &pb.Condition{Field: field, Operator: op, Value: value}
+}
+
+func TestConditions(t *testing.T) {
+	conds := []*pb.Condition{
+		cond("class.name", pb.Operator_OPERATOR_EQ, "a"),
+		cond("id", pb.Operator_OPERATOR_IN, []interface{}{1, "18446744073709551615"}),
+		cond("create_time", pb.Operator_OPERATOR_GTE, "2020-01-01"),
+		cond("create_time", pb.Operator_OPERATOR_LT, "2020-02-01"),
+		cond("name", pb.Operator_OPERATOR_LIKE, "li"),
+	}
+	got, err := conditions(conds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"class.name":  "a",
+		"id":          []interface{}{1.0, "18446744073709551615"},
+		"create_time": map[string]interface{}{"gte": "2020-01-01", "lt": "2020-02-01"},
+		"name":        map[string]interface{}{"like": "li"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%v, want %v", got, want)
+	}
+}
+
+func TestConditionsErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		conds []*pb.Condition
+		key   string
+		field string
+	}{
+		{"no field", []*pb.Condition{cond("", pb.Operator_OPERATOR_EQ, 1)}, "", "conditions[0].field"},
+		{"list of eq", []*pb.Condition{cond("id", pb.Operator_OPERATOR_EQ, []interface{}{1})}, "value.multiple", "id"},
+		{"value of in", []*pb.Condition{cond("id", pb.Operator_OPERATOR_IN, 1)}, "value.list", "id"},
+		{"eq twice", []*pb.Condition{cond("id", pb.Operator_OPERATOR_EQ, 1), cond("id", pb.Operator_OPERATOR_EQ, 2)}, "value.duplicate", "id"},
+		{"eq and gt", []*pb.Condition{cond("id", pb.Operator_OPERATOR_EQ, 1), cond("id", pb.Operator_OPERATOR_GT, 2)}, "value.duplicate", "id"},
+		{"gt twice", []*pb.Condition{cond("id", pb.Operator_OPERATOR_GT, 1), cond("id", pb.Operator_OPERATOR_GT, 2)}, "value.duplicate", "id"},
+		{"unknown operator", []*pb.Condition{cond("id", pb.Operator(99), 1)}, "value.operator", "id"},
+	}
+	for _, c := range cases {
+		_, err := conditions(c.conds)
+		if err == nil {
+			t.Errorf("%s: no error", c.name)
+			continue
+		}
+		if ee := e.As(err); ee.Key != c.key || ee.Field != c.field {
+			t.Errorf("%s: %s at %q, want %s at %q", c.name, ee.Key, ee.Field, c.key, c.field)
+		}
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	c := caller.New(nil, nil, "", http.Header{query.VersionHeader: {"1"}})
+	q := &pb.Query{
+		Fields:   []string{"id", "class"},
+		Orders:   []*pb.Order{{Field: "id", Desc: true, Nulls: pb.Nulls_NULLS_LAST}, {Field: "name"}},
+		Page:     2,
+		PageSize: 1000,
+		Search:   "li",
+	}
+	p, err := queryParams(c, "", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string(p.ReturnFields), q.Fields) || p.Search != "li" || p.Version != "1" {
+		t.Errorf("%+v", p)
+	}
+	if p.Pagination != (query.Pagination{Page: 2, PageSize: query.DefaultPageSize, Offset: query.DefaultPageSize}) {
+		t.Errorf("page %+v", p.Pagination)
+	}
+	if want := [][2]string{{"id", "desc nulls last"}, {"name", "asc"}}; !reflect.DeepEqual(p.Orders, want) {
+		t.Errorf("orders %v, want %v", p.Orders, want)
+	}
+
+	if p, _ := queryParams(c, "2", &pb.Query{Fields: []string{"id"}}); p.Version != "2" || p.Pagination.Page != 0 {
+		t.Errorf("the version of the request %q, page %d", p.Version, p.Pagination.Page)
+	}
+	if _, err := queryParams(c, "", &pb.Query{}); err == nil || e.As(err).Field != "query.fields" {
+		t.Errorf("without fields: %v", err)
+	}
+	if _, err := queryParams(c, "", &pb.Query{Fields: []string{"id"}, Orders: []*pb.Order{{}}}); err == nil || e.As(err).Field != "query.orders[0]" {
+		t.Errorf("order without field: %v", err)
+	}
+}
+
+func TestToStruct(t *testing.T) {
+	s, err := toStruct(map[string]interface{}{"id": uint64(7), "name": nil, "class": map[string]interface{}{"name": "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"id": 7.0, "name": nil, "class": map[string]interface{}{"name": "a"}}
+	if got := s.AsMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("%v, want %v", got, want)
+	}
+	if _, err := toStruct(map[string]interface{}{"c": make(chan int)}); err == nil {
+		t.Error("a row not rendered in JSON converted")
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	cases := []struct {
+		err   error
+		code  codes.Code
+		field string
+	}{
+		{e.Invalid("value.list").WithField("id"), codes.InvalidArgument, "id"},
+		{e.New(e.ERROR_AUTH_TOKEN), codes.Unauthenticated, ""},
+		{e.New(e.ERROR_FORBIDDEN_ROW).WithField("class_id"), codes.PermissionDenied, "class_id"},
+		{e.New(e.ERROR_BATCH_DEPENDENCY, "a"), codes.FailedPrecondition, ""},
+		{e.New(e.ERROR_ROW_QUOTA, 3), codes.ResourceExhausted, ""},
+		{e.New(e.ERROR), codes.Internal, ""},
+	}
+	for _, c := range cases {
+		st := status.Convert(toStatus(context.Background(), "en", c.err))
+		if st.Code() != c.code {
+			t.Errorf("%v: %s, want %s", c.err, st.Code(), c.code)
+			continue
+		}
+		details := st.Details()
+		if len(details) != 1 {
+			t.Errorf("%v: %d details", c.err, len(details))
+			continue
+		}
+		d := details[0].(*structpb.Struct).AsMap()
+		if d["code"] != float64(e.As(c.err).Code) || d["message"] != st.Message() {
+			t.Errorf("%v: details %v", c.err, d)
+		}
+		if f, _ := d["field"].(string); f != c.field {
+			t.Errorf("%v: field %q, want %q", c.err, f, c.field)
+		}
+	}
+}
+
+func TestRequestHeader(t *testing.T) {
+	md := metadata.Pairs("authorization", "Bearer t", "x-api-version", "2", "grpc-timeout", "1S", "trace-bin", "x", ":authority", "h")
+	h := requestHeader(metadata.NewIncomingContext(context.Background(), md))
+	want := http.Header{"Authorization": {"Bearer t"}, "X-Api-Version": {"2"}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("%v, want %v", h, want)
+	}
+}
@@ -0,0 +1,128 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	"github.com/go-bread/components/entity"
+	"github.com/go-bread/components/entity/group"
+	"github.com/go-bread/consts"
+	"github.com/go-bread/pkg/apikey"
+	"github.com/go-bread/pkg/caller"
+	"github.com/go-bread/routers/rpc/pb"
+	"github.com/go-bread/validators/query"
+)
+
+// NewServer returns the gRPC server of the entity service of fieldsMap with server reflection.
+// 每个调用与HTTP接口相同, 经过认证, API key的范围检查及限流
+func NewServer(fieldsMap group.FieldsMap) *grpc.Server {
+	entity.InitGroups(fieldsMap)
+
+	s := grpc.NewServer()
+	pb.RegisterEntityServer(s, &entityServer{fieldsMap: fieldsMap})
+	reflection.Register(s)
+	return s
+}
+
+type entityServer struct {
+	pb.UnimplementedEntityServer
+	fieldsMap group.FieldsMap
+}
+
+func (s *entityServer) List(ctx context.Context, req *pb.ListRequest) (*pb.ListResponse, error) {
+	resp := &pb.ListResponse{}
+	err := s.call(ctx, req.Group, apikey.OpList, func(c *caller.Caller) error {
+		qp, err := queryParams(c, req.Version, req.Query)
+		if err != nil {
+			return err
+		}
+		rows, page, err := entity.QueryAll(c, s.fieldsMap, consts.EntityGroupName(req.Group), qp)
+		if err != nil {
+			return err
+		}
+		if resp.Rows, err = toStructs(rows); err != nil {
+			return err
+		}
+		resp.PageInfo = &pb.PageInfo{Page: page.Page, PageSize: page.PageSize, TotalCount: page.TotalCount}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *entityServer) Get(ctx context.Context, req *pb.GetRequest) (*pb.GetResponse, error) {
+	resp := &pb.GetResponse{}
+	err := s.call(ctx, req.Group, apikey.OpList, func(c *caller.Caller) error {
+		qp, err := queryParams(c, req.Version, req.Query)
+		if err != nil {
+			return err
+		}
+		// 只取第一行
+		qp.Pagination = query.Pagination{Page: 1, PageSize: 1}
+		qp.Pagination.Init()
+		row, err := entity.QueryAndFormatOne(c, s.fieldsMap, consts.EntityGroupName(req.Group), qp)
+		if err != nil || row == nil {
+			return err
+		}
+		resp.Row, err = toStruct(row)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Row == nil {
+		return nil, status.Error(codes.NotFound, "no row matches the query")
+	}
+	return resp, nil
+}
+
+func (s *entityServer) Create(ctx context.Context, req *pb.CreateRequest) (*pb.CreateResponse, error) {
+	resp := &pb.CreateResponse{}
+	err := s.call(ctx, req.Group, apikey.OpCreate, func(c *caller.Caller) error {
+		id, err := entity.Create(c, s.fieldsMap, consts.EntityGroupName(req.Group), version(c, req.Version), req.Values.AsMap())
+		resp.Id = id
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *entityServer) Update(ctx context.Context, req *pb.UpdateRequest) (*pb.UpdateResponse, error) {
+	resp := &pb.UpdateResponse{}
+	err := s.call(ctx, req.Group, apikey.OpUpdate, func(c *caller.Caller) error {
+		qp, err := writeParams(c, req.Version, req.Conditions)
+		if err != nil {
+			return err
+		}
+		resp.Affected, err = entity.Update(c, s.fieldsMap, consts.EntityGroupName(req.Group), qp, req.Values.AsMap())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *entityServer) Delete(ctx context.Context, req *pb.DeleteRequest) (*pb.DeleteResponse, error) {
+	resp := &pb.DeleteResponse{}
+	err := s.call(ctx, req.Group, apikey.OpDelete, func(c *caller.Caller) error {
+		qp, err := writeParams(c, req.Version, req.Conditions)
+		if err != nil {
+			return err
+		}
+		resp.Affected, err = entity.Delete(c, s.fieldsMap, consts.EntityGroupName(req.Group), qp)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
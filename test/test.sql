@@ -14,3 +14,55 @@ Create Table: CREATE TABLE `student` (
     `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+
+Create Table: CREATE TABLE `teacher_class` (
+    `auth_id` int NOT NULL COMMENT '教师的用户id',
+    `class_id` int NOT NULL COMMENT '班级id',
+    PRIMARY KEY (`auth_id`, `class_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+
+Create Table: CREATE TABLE `api_key` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `auth_id` int NOT NULL COMMENT '所属的用户id',
+    `name` varchar(100) NOT NULL DEFAULT '',
+    `prefix` varchar(20) NOT NULL DEFAULT '' COMMENT 'key的开头部分',
+    `key_hash` char(64) NOT NULL COMMENT 'key的sha256',
+    `groups` varchar(1000) NOT NULL DEFAULT '' COMMENT '逗号分隔的实体组',
+    `operations` varchar(100) NOT NULL DEFAULT '' COMMENT '逗号分隔的操作',
+    `created_at` bigint NOT NULL DEFAULT '0',
+    `expires_at` bigint NOT NULL DEFAULT '0',
+    `last_used_at` bigint NOT NULL DEFAULT '0',
+    `revoked_at` bigint NOT NULL DEFAULT '0',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_key_hash` (`key_hash`),
+    KEY `idx_auth_id` (`auth_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+
+Create Table: CREATE TABLE `auth` (
+    `id` int NOT NULL AUTO_INCREMENT,
+    `username` varchar(50) NOT NULL DEFAULT '',
+    `password` varchar(100) NOT NULL DEFAULT '' COMMENT 'bcrypt哈希, 旧数据可能为明文',
+    `roles` varchar(255) NOT NULL DEFAULT '' COMMENT '逗号分隔的角色',
+    `permissions` varchar(1000) NOT NULL DEFAULT '' COMMENT '逗号分隔的权限',
+    `timezone` varchar(64) NOT NULL DEFAULT '',
+    `tenant_id` bigint NOT NULL DEFAULT '0' COMMENT '所属的租户, 0表示不属于任何租户',
+    `failed_attempts` int NOT NULL DEFAULT '0' COMMENT '连续登录失败次数',
+    `locked_until` bigint NOT NULL DEFAULT '0' COMMENT '锁定截止的unix时间',
+    `token_version` int NOT NULL DEFAULT '0' COMMENT '修改密码时递增',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uk_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+
+-- 已有的auth表升级, 也可以执行 bread migrate -apply
+ALTER TABLE `auth` MODIFY `password` varchar(100) NOT NULL DEFAULT '';
+ALTER TABLE `auth` ADD COLUMN `roles` varchar(255) NOT NULL DEFAULT '';
+ALTER TABLE `auth` ADD COLUMN `permissions` varchar(1000) NOT NULL DEFAULT '';
+ALTER TABLE `auth` ADD COLUMN `timezone` varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE `auth` ADD COLUMN `tenant_id` bigint NOT NULL DEFAULT '0';
+ALTER TABLE `auth` ADD COLUMN `failed_attempts` int NOT NULL DEFAULT '0';
+ALTER TABLE `auth` ADD COLUMN `locked_until` bigint NOT NULL DEFAULT '0';
+ALTER TABLE `auth` ADD COLUMN `token_version` int NOT NULL DEFAULT '0';
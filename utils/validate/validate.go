@@ -1,10 +1,10 @@
 package validate
 
 import (
-	"errors"
 	"reflect"
 	"strings"
 
+	"github.com/go-bread/pkg/e"
 	"github.com/go-playground/locales/zh"
 
 	zhTranslations "gopkg.in/go-playground/validator.v9/translations/zh"
@@ -13,6 +13,14 @@ import (
 	"gopkg.in/go-playground/validator.v9"
 )
 
+// 有多语言消息的tag, 其余的tag使用validator的中文翻译
+var tagMessages = map[string]struct{}{
+	"required": {},
+	"len":      {},
+	"min":      {},
+	"max":      {},
+}
+
 var (
 	uni      *ut.UniversalTranslator
 	trans    ut.Translator
@@ -44,8 +52,11 @@ func StructParam(val interface{}) error {
 		if errNew, ok := err.(*validator.InvalidValidationError); ok {
 			panic(errNew)
 		}
-		for _, e := range err.(validator.ValidationErrors) {
-			return errors.New(e.Translate(trans))
+		for _, fe := range err.(validator.ValidationErrors) {
+			if _, ok := tagMessages[fe.Tag()]; ok {
+				return e.NewKey(e.INVALID_PARAMS, "validate."+fe.Tag(), fe.Field(), fe.Param()).WithField(fe.Field())
+			}
+			return e.New(e.INVALID_PARAMS, fe.Translate(trans)).WithField(fe.Field())
 		}
 		return err
 	}
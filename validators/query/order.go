@@ -0,0 +1,64 @@
+package query
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	Asc  = "asc"
+	Desc = "desc"
+
+	NullsFirst = "first"
+	NullsLast  = "last"
+)
+
+// Directions lists the accepted directions of _order_by in their canonical form
+var Directions = []string{
+	Asc, Desc,
+	Asc + " nulls " + NullsFirst, Asc + " nulls " + NullsLast,
+	Desc + " nulls " + NullsFirst, Desc + " nulls " + NullsLast,
+}
+
+var errDirection = errors.New("invalid direction")
+
+// Direction is a sort direction: asc|desc, optionally followed by nulls first|last
+type Direction struct {
+	Desc  bool
+	Nulls string // 为空时使用数据库默认的顺序
+}
+
+// ParseDirection parses a direction case insensitively, e.g. "DESC nulls last"
+func ParseDirection(s string) (Direction, error) {
+	var d Direction
+	words := strings.Fields(strings.ToLower(s))
+	if len(words) != 1 && len(words) != 3 {
+		return d, errDirection
+	}
+	switch words[0] {
+	case Asc:
+	case Desc:
+		d.Desc = true
+	default:
+		return d, errDirection
+	}
+	if len(words) == 3 {
+		if words[1] != "nulls" || (words[2] != NullsFirst && words[2] != NullsLast) {
+			return d, errDirection
+		}
+		d.Nulls = words[2]
+	}
+	return d, nil
+}
+
+// String is the canonical form of the direction, one of Directions
+func (d Direction) String() string {
+	s := Asc
+	if d.Desc {
+		s = Desc
+	}
+	if d.Nulls != "" {
+		s += " nulls " + d.Nulls
+	}
+	return s
+}
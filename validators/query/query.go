@@ -1,10 +1,15 @@
 package query
 
 import (
+	"bytes"
 	"encoding/json"
-	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"strings"
 
+	"github.com/go-bread/pkg/e"
 	"github.com/go-bread/utils/validate"
 	"github.com/gin-gonic/gin"
 )
@@ -13,13 +18,21 @@ const (
 	DefaultPage     = 1
 	DefaultPageSize = 10
 	MaxPageSize     = 500
+
+	// MaxBodySize limits the query document of the body
+	MaxBodySize = 1 << 20
 )
 
+// VersionHeader selects the version of the entity group, same as the version url parameter
+const VersionHeader = "X-Api-Version"
+
 type QParams struct {
 	QFields
 	ReturnFields
 	Pagination
 	OrderBy
+	Version string
+	Search  string // _search 全文搜索的内容
 }
 
 type Parameters struct {
@@ -37,6 +50,7 @@ type Pagination struct {
 	PageSize   uint32 `json:"page_size"`
 	TotalCount uint32 `json:"total_number"`
 	Offset     uint32 `json:"-"`
+	MaxRows    uint32 `json:"-"` // 查询返回的最大行数, 包括没有分页时, 为0时不限制
 }
 
 type OrderBy struct {
@@ -45,18 +59,54 @@ type OrderBy struct {
 
 
 
+// Parse reads the query document of the query url parameter
 func Parse(ctx *gin.Context) (QParams, error) {
-	// build query params
-	var qp QParams
 	q := ctx.Query("query")
 	if q == "" {
-		return qp, errors.New("query field is required")
+		return QParams{}, e.New(e.ERROR_QUERY_REQUIRED)
+	}
+	return ParseDocument([]byte(q), RequestVersion(ctx))
+}
+
+// ParseBody reads the query document of the JSON body, for the filters too long for the url
+func ParseBody(ctx *gin.Context) (QParams, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, MaxBodySize))
+	if err != nil {
+		return QParams{}, e.New(e.INVALID_PARAMS, err.Error())
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return QParams{}, e.NewKey(e.ERROR_QUERY_REQUIRED, "query.body")
+	}
+	return ParseDocument(body, RequestVersion(ctx))
+}
+
+// ParseDocument parses a query document of version, e.g. {"fields":["id"],"class_id":1,"_page":1}
+func ParseDocument(q []byte, version string) (QParams, error) {
+	var qp QParams
+
+	// 数字保留为json.Number, 按字段类型转换时不丢失精度
+	var m map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(q))
+	d.UseNumber()
+	err := d.Decode(&m)
+	if err != nil {
+		return qp, e.New(e.INVALID_PARAMS, err.Error()).WithField("query")
+	}
+	// 文档之后只能有空白, 如 {"fields":["id"]}{"x":1} 无效
+	if _, err := d.Token(); err != io.EOF {
+		return qp, e.New(e.INVALID_PARAMS, "unexpected data after the query document").WithField("query")
+	}
+	if m == nil {
+		return qp, e.Invalid("value.object", "null").WithField("query")
 	}
 
 	var parameters Parameters
-	err := json.Unmarshal([]byte(q), &parameters)
+	err = json.Unmarshal(q, &parameters)
 	if err != nil {
-		return qp, err
+		if te, ok := err.(*json.UnmarshalTypeError); ok && te.Field != "" {
+			return qp, e.New(e.INVALID_PARAMS, te.Error()).WithField(te.Field)
+		}
+		return qp, e.New(e.INVALID_PARAMS, err.Error()).WithField("query")
 	}
 
 	err = validate.StructParam(parameters)
@@ -72,42 +122,57 @@ func Parse(ctx *gin.Context) (QParams, error) {
 		p.Init()
 	}
 
-	var m map[string]interface{}
-	err = json.Unmarshal([]byte(q), &m)
-	if err != nil {
-		return qp, errors.New("query field is invalid")
-	}
-
 	var orders OrderBy
-	if len(orders.Orders) > 0 {
-		err = json.Unmarshal([]byte(q), &orders)
+	if _, ok := m["_order_by"]; ok {
+		err = json.Unmarshal(q, &orders)
 		if err != nil {
-			return qp, errors.New("order by is invalid")
+			return qp, e.New(e.ERROR_INVALID_ORDER).WithField("_order_by")
 		}
 		err = validate.StructParam(orders)
 		if err != nil {
-			return qp, err
+			return qp, e.New(e.ERROR_INVALID_ORDER).WithField("_order_by")
 		}
 		for i, v := range orders.Orders {
-			if strings.ToLower(v[1]) != "asc" && strings.ToLower(v[1]) != "desc" {
-				return qp, errors.New("order by is invalid")
+			d, err := ParseDirection(v[1])
+			if err != nil {
+				return qp, e.New(e.ERROR_INVALID_ORDER).WithField(fmt.Sprintf("_order_by[%d]", i))
 			}
-			orders.Orders[i] = [2]string{v[0], strings.ToLower(v[1])}
+			orders.Orders[i] = [2]string{v[0], d.String()}
+		}
+	}
+
+	var search string
+	if v, ok := m["_search"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return qp, e.Invalid("value.string", e.TypeName(v)).WithField("_search")
 		}
+		search = strings.TrimSpace(s)
 	}
 
 	delete(m, "fields")
-	delete(m, "page")
-	delete(m, "page_size")
-	delete(m, "order_by")
+	delete(m, "_page")
+	delete(m, "_page_size")
+	delete(m, "_order_by")
+	delete(m, "_search")
 	return QParams{
 		QFields:      m,
 		ReturnFields: parameters.Fields,
 		Pagination:   p,
 		OrderBy:      orders,
+		Version:      version,
+		Search:       search,
 	}, nil
 }
 
+// RequestVersion is the version of the url parameter or the X-Api-Version header
+func RequestVersion(ctx *gin.Context) string {
+	if v := ctx.Query("version"); v != "" {
+		return v
+	}
+	return ctx.GetHeader(VersionHeader)
+}
+
 func (p *Pagination) Init() {
 	if p.Page == 0 {
 		p.Page = DefaultPage
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-bread/pkg/e"
+)
+
+func TestParseDocument(t *testing.T) {
+	cases := []struct {
+		name    string
+		doc     string
+		fields  []string
+		filters map[string]interface{}
+		page    Pagination
+		orders  [][2]string
+		search  string
+	}{
+		{
+			name:    "filters",
+			doc:     `{"fields":["id","class.name"],"class_id":1,"class":{"name":"one"}}`,
+			fields:  []string{"id", "class.name"},
+			filters: map[string]interface{}{"class_id": json.Number("1"), "class": map[string]interface{}{"name": "one"}},
+		},
+		{
+			name:    "page",
+			doc:     `{"fields":["id"],"_page":2,"_page_size":20}`,
+			fields:  []string{"id"},
+			filters: map[string]interface{}{},
+			page:    Pagination{Page: 2, PageSize: 20, Offset: 20},
+		},
+		{
+			name:    "order and search",
+			doc:     "{\"fields\":[\"id\"],\"_order_by\":[[\"id\",\"DESC\"]],\"_search\":\" al \"}\n",
+			fields:  []string{"id"},
+			filters: map[string]interface{}{},
+			orders:  [][2]string{{"id", "desc"}},
+			search:  "al",
+		},
+	}
+	for _, c := range cases {
+		qp, err := ParseDocument([]byte(c.doc), "2")
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual([]string(qp.ReturnFields), c.fields) {
+			t.Errorf("%s: fields %v, want %v", c.name, qp.ReturnFields, c.fields)
+		}
+		if !reflect.DeepEqual(map[string]interface{}(qp.QFields), c.filters) {
+			t.Errorf("%s: filters %v, want %v", c.name, qp.QFields, c.filters)
+		}
+		if qp.Pagination != c.page {
+			t.Errorf("%s: page %+v, want %+v", c.name, qp.Pagination, c.page)
+		}
+		if !reflect.DeepEqual(qp.Orders, c.orders) {
+			t.Errorf("%s: orders %v, want %v", c.name, qp.Orders, c.orders)
+		}
+		if qp.Search != c.search || qp.Version != "2" {
+			t.Errorf("%s: search %q version %q", c.name, qp.Search, qp.Version)
+		}
+	}
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		doc   string
+		field string
+	}{
+		{"syntax", `{"fields":`, "query"},
+		{"trailing object", `{"fields":["id"]}{"class_id":1}`, "query"},
+		{"trailing garbage", `{"fields":["id"]} x`, "query"},
+		{"array", `[{"fields":["id"]}]`, "query"},
+		{"null", `null`, "query"},
+		{"fields type", `{"fields":"id"}`, "fields"},
+		{"page type", `{"fields":["id"],"_page":"1"}`, "_page"},
+		{"order", `{"fields":["id"],"_order_by":[["id","up"]]}`, "_order_by[0]"},
+		{"search type", `{"fields":["id"],"_search":1}`, "_search"},
+	}
+	for _, c := range cases {
+		_, err := ParseDocument([]byte(c.doc), "")
+		if err == nil {
+			t.Errorf("%s: no error", c.name)
+			continue
+		}
+		if f := e.As(err).Field; f != c.field {
+			t.Errorf("%s: field %q, want %q (%v)", c.name, f, c.field, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package rule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Args are the key=value arguments of a spec
+type Args struct {
+	values map[string]string
+	used   map[string]bool
+}
+
+func (a Args) String(key string) (string, bool) {
+	v, ok := a.values[key]
+	if ok {
+		a.used[key] = true
+	}
+	return v, ok
+}
+
+func (a Args) Int(key string) (int64, bool, error) {
+	s, ok := a.String(key)
+	if !ok {
+		return 0, false, nil
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("argument %s must be an integer", key)
+	}
+	return i, true, nil
+}
+
+func (a Args) Float(key string) (float64, bool, error) {
+	s, ok := a.String(key)
+	if !ok {
+		return 0, false, nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("argument %s must be a number", key)
+	}
+	return f, true, nil
+}
+
+func (a Args) unused() error {
+	var keys []string
+	for k := range a.values {
+		if !a.used[k] {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("unknown argument %s", strings.Join(keys, ", "))
+}
+
+// bounds reads the optional min and max arguments
+func (a Args) bounds() (min, max *float64, err error) {
+	if f, ok, err := a.Float("min"); err != nil {
+		return nil, nil, err
+	} else if ok {
+		min = &f
+	}
+	if f, ok, err := a.Float("max"); err != nil {
+		return nil, nil, err
+	} else if ok {
+		max = &f
+	}
+	if min != nil && max != nil && *min > *max {
+		return nil, nil, fmt.Errorf("min %v is greater than max %v", *min, *max)
+	}
+	return min, max, nil
+}
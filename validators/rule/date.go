@@ -0,0 +1,108 @@
+package rule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-bread/components/database/condition"
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/e"
+)
+
+const (
+	DateLayout     = "2006-01-02"
+	DatetimeLayout = "2006-01-02 15:04:05"
+)
+
+var dateOperators = []string{"=", ">", ">=", "<", "<="}
+
+// date,min=2020-01-01,max=2030-12-31
+// 单个日期匹配整天, [开始, 结束] 匹配包含两端的整天
+func newDate(args Args) (validatorIface.Validator, error) {
+	return newTimeRule(args, DateLayout, func(loc *time.Location, table, field string, t term) []validatorIface.Condition {
+		d, _ := parseTime(DateLayout, t.value, loc)
+		next := d.AddDate(0, 0, 1)
+		switch t.op {
+		case condition.OpEqual:
+			return []validatorIface.Condition{
+				condition.NewQueryParam(table, field, ">=", d),
+				condition.NewQueryParam(table, field, "<", next),
+			}
+		case ">":
+			return []validatorIface.Condition{condition.NewQueryParam(table, field, ">=", next)}
+		case "<=":
+			return []validatorIface.Condition{condition.NewQueryParam(table, field, "<", next)}
+		default:
+			return []validatorIface.Condition{condition.NewQueryParam(table, field, t.op, d)}
+		}
+	})
+}
+
+// datetime,min=2020-01-01 00:00:00
+func newDatetime(args Args) (validatorIface.Validator, error) {
+	return newTimeRule(args, DatetimeLayout, func(loc *time.Location, table, field string, t term) []validatorIface.Condition {
+		d, _ := parseTime(DatetimeLayout, t.value, loc)
+		return []validatorIface.Condition{condition.NewQueryParam(table, field, t.op, d)}
+	})
+}
+
+// newTimeRule builds the rule in time.Local, InLocation rebuilds it in the timezone of the request:
+// the values, min and max are wall clock times of the caller
+func newTimeRule(args Args, layout string, expand func(loc *time.Location, table, field string, t term) []validatorIface.Condition) (*rule, error) {
+	var min, max string
+	for key, p := range map[string]*string{"min": &min, "max": &max} {
+		s, ok := args.String(key)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(layout, s); err != nil {
+			return nil, fmt.Errorf("argument %s must be formatted as %s", key, layout)
+		}
+		*p = s
+	}
+
+	var in func(loc *time.Location) *rule
+	in = func(loc *time.Location) *rule {
+		return &rule{
+			operators: dateOperators,
+			ranged:    true,
+			check: func(v interface{}) error {
+				t, err := parseTime(layout, v, loc)
+				if err != nil {
+					return err
+				}
+				if min != "" {
+					if m, _ := time.ParseInLocation(layout, min, loc); t.Before(m) {
+						return e.Invalid("value.before", min)
+					}
+				}
+				if max != "" {
+					if m, _ := time.ParseInLocation(layout, max, loc); t.After(m) {
+						return e.Invalid("value.after", max)
+					}
+				}
+				return nil
+			},
+			expand: func(table, field string, t term) []validatorIface.Condition {
+				return expand(loc, table, field, t)
+			},
+			inLocation: in,
+		}
+	}
+	return in(time.Local), nil
+}
+
+func parseTime(layout string, v interface{}, loc *time.Location) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		d, err := time.ParseInLocation(layout, t, loc)
+		if err != nil {
+			return time.Time{}, e.Invalid("value.time_format", t, layout)
+		}
+		return d, nil
+	default:
+		return time.Time{}, e.Invalid("value.time", e.TypeName(v))
+	}
+}
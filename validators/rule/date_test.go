@@ -0,0 +1,71 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	validatorIface "github.com/go-bread/iface/validator"
+)
+
+func inLocation(spec string, loc *time.Location) validatorIface.Validator {
+	return MustParse(spec).(validatorIface.Localizer).InLocation(loc)
+}
+
+func TestDateConditions(t *testing.T) {
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, shanghai)
+	next := day.AddDate(0, 0, 1)
+
+	type cond struct {
+		op    string
+		value time.Time
+	}
+	cases := []struct {
+		name  string
+		spec  string
+		value interface{}
+		want  []cond
+	}{
+		{"day", "date", "2020-01-02", []cond{{">=", day}, {"<", next}}},
+		{"after", "date", map[string]interface{}{"gt": "2020-01-02"}, []cond{{">=", next}}},
+		{"until", "date", map[string]interface{}{"lte": "2020-01-02"}, []cond{{"<", next}}},
+		{"range", "date", []interface{}{"2020-01-02", "2020-01-02"}, []cond{{">=", day}, {"<", next}}},
+		{"datetime", "datetime", "2020-01-02 00:00:00", []cond{{"=", day}}},
+	}
+	for _, c := range cases {
+		v := inLocation(c.spec, shanghai)
+		if err := v.Validate(c.value); err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		conds := v.TransferCondition("t", "d", c.value, nil)
+		if len(conds) != len(c.want) {
+			t.Errorf("%s: %d conditions, want %d", c.name, len(conds), len(c.want))
+			continue
+		}
+		for i, w := range c.want {
+			got := conds[i]
+			if got.Operator() != w.op || !got.ConditionValue()[0].(time.Time).Equal(w.value) {
+				t.Errorf("%s: condition %d is %s %v, want %s %v", c.name, i, got.Operator(), got.ConditionValue(), w.op, w.value)
+			}
+		}
+	}
+}
+
+func TestDateBoundsInLocation(t *testing.T) {
+	// 2020-01-01 00:30 UTC+8 为 2019-12-31 16:30 UTC
+	shanghai := time.FixedZone("UTC+8", 8*3600)
+	min := "datetime,min=2020-01-01 00:00:00"
+	if err := inLocation(min, shanghai).Validate("2020-01-01 00:30:00"); err != nil {
+		t.Errorf("bound in the location of the request: %v", err)
+	}
+	if err := inLocation(min, time.UTC).Validate("2019-12-31 23:00:00"); err == nil {
+		t.Error("a time before min passed")
+	}
+	if err := inLocation(min, shanghai).Validate(time.Date(2019, 12, 31, 16, 30, 0, 0, time.UTC)); err != nil {
+		t.Errorf("coerced time: %v", err)
+	}
+	if v := MustParse("int").(validatorIface.Localizer).InLocation(shanghai); v != MustParse("int") {
+		t.Error("a rule without times was rebuilt")
+	}
+}
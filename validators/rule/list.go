@@ -0,0 +1,68 @@
+package rule
+
+import (
+	"math"
+
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/e"
+)
+
+// list,min=1,max=50 只接受列表, 限制元素个数
+func newList(args Args) (validatorIface.Validator, error) {
+	min, max, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+	return &sizedRule{
+		rule: rule{
+			operators: []string{"in"},
+			check:     func(interface{}) error { return nil },
+		},
+		min: min,
+		max: max,
+	}, nil
+}
+
+// ids,max=100 单个id或id列表, id为正整数
+func newIds(args Args) (validatorIface.Validator, error) {
+	max, ok, err := args.Int("max")
+	if err != nil {
+		return nil, err
+	}
+	r := &sizedRule{
+		rule: rule{
+			operators: []string{"=", "in"},
+			check: func(v interface{}) error {
+				f, ok := toFloat(v)
+				if !ok || f != math.Trunc(f) || f < 1 {
+					return e.Invalid("value.id")
+				}
+				return nil
+			},
+		},
+	}
+	if ok {
+		m := float64(max)
+		r.max = &m
+	}
+	return r, nil
+}
+
+// sizedRule limits the number of elements of the "in" form
+type sizedRule struct {
+	rule
+	min, max *float64
+}
+
+func (r *sizedRule) Validate(v interface{}) error {
+	if list, ok := toList(v); ok {
+		n := float64(len(list))
+		if r.min != nil && n < *r.min {
+			return e.Invalid("value.min_items", *r.min)
+		}
+		if r.max != nil && n > *r.max {
+			return e.Invalid("value.max_items", *r.max)
+		}
+	}
+	return r.rule.Validate(v)
+}
@@ -0,0 +1,76 @@
+package rule
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/e"
+)
+
+var numberOperators = []string{"=", "in", "!=", ">", ">=", "<", "<="}
+
+// int,min=1,max=100
+func newInt(args Args) (validatorIface.Validator, error) {
+	min, max, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		operators: numberOperators,
+		check: func(v interface{}) error {
+			f, ok := toFloat(v)
+			if !ok || f != math.Trunc(f) {
+				return e.Invalid("value.integer", e.TypeName(v))
+			}
+			return checkBounds(f, min, max)
+		},
+	}, nil
+}
+
+// float,min=0,max=1
+func newFloat(args Args) (validatorIface.Validator, error) {
+	min, max, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		operators: numberOperators,
+		check: func(v interface{}) error {
+			f, ok := toFloat(v)
+			if !ok {
+				return e.Invalid("value.number", e.TypeName(v))
+			}
+			return checkBounds(f, min, max)
+		},
+	}, nil
+}
+
+func checkBounds(f float64, min, max *float64) error {
+	if min != nil && f < *min {
+		return e.Invalid("value.min", *min)
+	}
+	if max != nil && f > *max {
+		return e.Invalid("value.max", *max)
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	default:
+		return 0, false
+	}
+}
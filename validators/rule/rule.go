@@ -0,0 +1,279 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-bread/components/database/condition"
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/e"
+)
+
+// Builder creates a validator from the arguments of a spec
+type Builder func(args Args) (validatorIface.Validator, error)
+
+var (
+	builders = map[string]Builder{}
+	cache    sync.Map
+)
+
+func init() {
+	Register("int", newInt)
+	Register("float", newFloat)
+	Register("string", newString)
+	Register("regex", newRegex)
+	Register("enum", newEnum)
+	Register("list", newList)
+	Register("ids", newIds)
+	Register("date", newDate)
+	Register("datetime", newDatetime)
+}
+
+// Register adds a validator usable in specs, it must be called during initialization
+func Register(name string, b Builder) {
+	if _, ok := builders[name]; ok {
+		panic("rule: validator registered twice: " + name)
+	}
+	builders[name] = b
+}
+
+// Parse builds the validator of a spec such as "int,min=1,max=100".
+// The pattern argument of regex consumes the rest of the spec and may contain commas.
+func Parse(spec string) (validatorIface.Validator, error) {
+	if v, ok := cache.Load(spec); ok {
+		return v.(validatorIface.Validator), nil
+	}
+
+	name, args, err := parseSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := builders[name]
+	if !ok {
+		return nil, fmt.Errorf("rule: unknown validator %q", name)
+	}
+	v, err := b(args)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %v", spec, err)
+	}
+	if err := args.unused(); err != nil {
+		return nil, fmt.Errorf("rule %q: %v", spec, err)
+	}
+
+	cache.Store(spec, v)
+	return v, nil
+}
+
+func MustParse(spec string) validatorIface.Validator {
+	v, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func parseSpec(spec string) (string, Args, error) {
+	args := Args{values: make(map[string]string), used: make(map[string]bool)}
+	parts := strings.SplitN(strings.TrimSpace(spec), ",", 2)
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		return "", args, errors.New("rule: empty spec")
+	}
+	if len(parts) == 1 {
+		return name, args, nil
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		i := strings.IndexByte(rest, '=')
+		if i <= 0 {
+			return "", args, fmt.Errorf("rule %q: invalid argument %q", spec, rest)
+		}
+		key := strings.TrimSpace(rest[:i])
+		rest = rest[i+1:]
+
+		var value string
+		if j := strings.IndexByte(rest, ','); j >= 0 && key != "pattern" {
+			value, rest = rest[:j], rest[j+1:]
+		} else {
+			value, rest = rest, ""
+		}
+		args.values[key] = value
+	}
+	return name, args, nil
+}
+
+// term is one operator of a query value
+type term struct {
+	op    string
+	value interface{}
+}
+
+// rule implements the common value forms of validatorIface.Validator:
+// a plain value means "=", an array means "in", an object such as {"gte": 1, "lt": 5} means the operators of its keys.
+type rule struct {
+	operators []string
+	check     func(v interface{}) error // 校验单个值
+	// expand overrides the conditions of a term, e.g. a date matches a whole day
+	expand func(table, field string, t term) []validatorIface.Condition
+	// ranged treats a two elements array as an inclusive [from, to] range instead of "in"
+	ranged bool
+	// inLocation rebuilds the rule for the timezone of a request, nil if the values do not depend on it
+	inLocation func(loc *time.Location) *rule
+}
+
+func (r *rule) Operators() []string {
+	return r.operators
+}
+
+// InLocation implements validatorIface.Localizer
+func (r *rule) InLocation(loc *time.Location) validatorIface.Validator {
+	if r.inLocation == nil || loc == nil {
+		return r
+	}
+	return r.inLocation(loc)
+}
+
+func (r *rule) allows(op string) bool {
+	for _, o := range r.operators {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rule) terms(v interface{}) ([]term, error) {
+	if m, ok := v.(map[string]interface{}); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		terms := make([]term, 0, len(keys))
+		for _, k := range keys {
+			op, ok := condition.OperatorByKey(k)
+			if !ok || !r.allows(op) {
+				return nil, e.Invalid("value.operator", k)
+			}
+			terms = append(terms, term{op, m[k]})
+		}
+		if len(terms) == 0 {
+			return nil, e.Invalid("value.empty_condition")
+		}
+		return terms, nil
+	}
+
+	if list, ok := toList(v); ok {
+		if r.ranged {
+			if len(list) != 2 {
+				return nil, e.Invalid("value.range")
+			}
+			return []term{{">=", list[0]}, {"<=", list[1]}}, nil
+		}
+		if !r.allows(condition.OpIn) {
+			return nil, e.Invalid("value.multiple")
+		}
+		if len(list) == 0 {
+			return nil, e.Invalid("value.empty_list")
+		}
+		return []term{{condition.OpIn, list}}, nil
+	}
+
+	if !r.allows(condition.OpEqual) {
+		return nil, e.Invalid("value.list")
+	}
+	return []term{{condition.OpEqual, v}}, nil
+}
+
+func (r *rule) Validate(v interface{}) error {
+	terms, err := r.terms(v)
+	if err != nil {
+		return err
+	}
+
+	_, object := v.(map[string]interface{})
+	for i, t := range terms {
+		var err error
+		switch t.op {
+		case condition.OpIn:
+			for j, elem := range t.value.([]interface{}) {
+				if err := r.check(elem); err != nil {
+					return e.AsInvalid(err).WithField(fmt.Sprintf("[%d]", j))
+				}
+			}
+		case "like":
+			if _, ok := t.value.(string); !ok {
+				err = e.Invalid("value.like")
+			}
+		default:
+			err = r.check(t.value)
+		}
+		if err == nil {
+			continue
+		}
+		// 出错的位置: {"gte": x} 中的gte, [from, to] 中的下标
+		if object {
+			return e.AsInvalid(err).WithField(condition.OperatorKeys[t.op])
+		}
+		if r.ranged {
+			return e.AsInvalid(err).WithField(fmt.Sprintf("[%d]", i))
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *rule) TransferCondition(table, field string, v interface{}, _ map[string]interface{}) []validatorIface.Condition {
+	terms, err := r.terms(v)
+	if err != nil {
+		return nil
+	}
+
+	conditions := make([]validatorIface.Condition, 0, len(terms))
+	for _, t := range terms {
+		if r.expand != nil {
+			if cs := r.expand(table, field, t); cs != nil {
+				conditions = append(conditions, cs...)
+				continue
+			}
+		}
+		value := t.value
+		if t.op == "like" {
+			value = "%" + escapeLike(value.(string)) + "%"
+		}
+		conditions = append(conditions, condition.NewQueryParam(table, field, t.op, value))
+	}
+	return conditions
+}
+
+var likeReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
+
+func toList(v interface{}) ([]interface{}, bool) {
+	if l, ok := v.([]interface{}); ok {
+		return l, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	// []byte 视为单个值
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	l := make([]interface{}, rv.Len())
+	for i := range l {
+		l[i] = rv.Index(i).Interface()
+	}
+	return l, true
+}
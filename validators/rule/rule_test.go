@@ -0,0 +1,96 @@
+package rule
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-bread/pkg/e"
+)
+
+func TestParseSpec(t *testing.T) {
+	cases := []struct {
+		spec string
+		name string
+		args map[string]string
+	}{
+		{"int", "int", map[string]string{}},
+		{" int , min=1,max=100", "int", map[string]string{"min": "1", "max": "100"}},
+		{"string,max=", "string", map[string]string{"max": ""}},
+		{"regex,pattern=^[a-z]{1,3}$", "regex", map[string]string{"pattern": "^[a-z]{1,3}$"}},
+		{"datetime,min=2020-01-01 00:00:00", "datetime", map[string]string{"min": "2020-01-01 00:00:00"}},
+	}
+	for _, c := range cases {
+		name, args, err := parseSpec(c.spec)
+		if err != nil {
+			t.Errorf("%q: %v", c.spec, err)
+			continue
+		}
+		if name != c.name || !reflect.DeepEqual(args.values, c.args) {
+			t.Errorf("%q: %s %v, want %s %v", c.spec, name, args.values, c.name, c.args)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		spec string
+		msg  string
+	}{
+		{"", "empty spec"},
+		{"nope", "unknown validator"},
+		{"int,1", "invalid argument"},
+		{"int,=1", "invalid argument"},
+		{"int,min=a", "argument min must be a number"},
+		{"int,min=2,max=1", "min 2 is greater than max 1"},
+		{"int,size=1", "unknown argument size"},
+		{"regex", "argument pattern is required"},
+		{"date,min=2020-01-01 00:00:00", "argument min must be formatted as 2006-01-02"},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.spec)
+		if err == nil || !strings.Contains(err.Error(), c.msg) {
+			t.Errorf("%q: got %v, want %q", c.spec, err, c.msg)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		spec  string
+		value interface{}
+		key   string // 错误的消息键, 空为通过
+		field string
+	}{
+		{"int,min=1,max=10", 5.0, "", ""},
+		{"int,min=1,max=10", 11.0, "value.max", ""},
+		{"int,min=1,max=10", 1.5, "value.integer", ""},
+		{"int,min=1,max=10", []interface{}{1.0, 0.0}, "value.min", "[1]"},
+		{"int,min=1,max=10", map[string]interface{}{"gte": 1.0, "lt": 20.0}, "value.max", "lt"},
+		{"int", map[string]interface{}{"like": "1"}, "value.operator", ""},
+		{"int", map[string]interface{}{}, "value.empty_condition", ""},
+		{"string,max=3", map[string]interface{}{"like": "abc"}, "", ""},
+		{"string,max=3", "abcd", "value.max_length", ""},
+		{"regex,pattern=^[a-z]+$", map[string]interface{}{"like": "a"}, "value.operator", ""},
+		{"list,min=1", "a", "value.list", ""},
+		{"ids,max=2", []interface{}{1.0, 2.0, 3.0}, "value.max_items", ""},
+		{"date", []interface{}{"2020-01-01"}, "value.range", ""},
+		{"date", []interface{}{"2020-01-01", "2020-13-01"}, "value.time_format", "[1]"},
+	}
+	for _, c := range cases {
+		err := MustParse(c.spec).Validate(c.value)
+		if c.key == "" {
+			if err != nil {
+				t.Errorf("%s %v: %v", c.spec, c.value, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s %v: no error", c.spec, c.value)
+			continue
+		}
+		if ee := e.As(err); ee.Key != c.key || ee.Field != c.field {
+			t.Errorf("%s %v: %s at %q, want %s at %q", c.spec, c.value, ee.Key, ee.Field, c.key, c.field)
+		}
+	}
+}
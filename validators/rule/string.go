@@ -0,0 +1,91 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	validatorIface "github.com/go-bread/iface/validator"
+	"github.com/go-bread/pkg/e"
+)
+
+// string,min=1,max=20 限制字符数
+func newString(args Args) (validatorIface.Validator, error) {
+	min, max, err := args.bounds()
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		operators: []string{"=", "in", "!=", "like"},
+		check: func(v interface{}) error {
+			s, ok := v.(string)
+			if !ok {
+				return e.Invalid("value.string", e.TypeName(v))
+			}
+			n := float64(utf8.RuneCountInString(s))
+			if min != nil && n < *min {
+				return e.Invalid("value.min_length", *min)
+			}
+			if max != nil && n > *max {
+				return e.Invalid("value.max_length", *max)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// regex,pattern=^[a-z]+$
+func newRegex(args Args) (validatorIface.Validator, error) {
+	pattern, ok := args.String("pattern")
+	if !ok {
+		return nil, errors.New("argument pattern is required")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &rule{
+		operators: []string{"=", "in", "!="},
+		check: func(v interface{}) error {
+			s, ok := v.(string)
+			if !ok {
+				return e.Invalid("value.string", e.TypeName(v))
+			}
+			if !re.MatchString(s) {
+				return e.Invalid("value.pattern")
+			}
+			return nil
+		},
+	}, nil
+}
+
+// enum,values=a|b|c 数字按字面值比较
+func newEnum(args Args) (validatorIface.Validator, error) {
+	values, ok := args.String("values")
+	if !ok || values == "" {
+		return nil, errors.New("argument values is required")
+	}
+	set := make(map[string]struct{})
+	for _, v := range strings.Split(values, "|") {
+		set[v] = struct{}{}
+	}
+	return &rule{
+		operators: []string{"=", "in", "!="},
+		check: func(v interface{}) error {
+			var s string
+			if f, ok := toFloat(v); ok {
+				s = fmt.Sprint(f)
+			} else if str, ok := v.(string); ok {
+				s = str
+			} else {
+				return e.Invalid("value.string_or_number", e.TypeName(v))
+			}
+			if _, ok := set[s]; !ok {
+				return e.Invalid("value.enum", strings.Replace(values, "|", ", ", -1))
+			}
+			return nil
+		},
+	}, nil
+}